@@ -0,0 +1,86 @@
+// This file computes summary statistics over the values in a ValueArray.
+
+package awk
+
+import "sort"
+
+// scalarKeys returns, sorted as strings, the index strings of all scalar
+// elements of a ValueArray.
+func (va *ValueArray) scalarKeys() []string {
+	keys := make([]string, 0, len(va.data))
+	for k, v := range va.data {
+		if v.aval == nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SumValues returns the sum of all values in a ValueArray, converted to
+// numbers as in AWK.  Subarrays are ignored.
+func (va *ValueArray) SumValues() *Value {
+	sum := va.script.NewValue(0)
+	for _, k := range va.scalarKeys() {
+		sum = sum.Add(va.data[k])
+	}
+	return sum
+}
+
+// extremeValue returns the key and value of the element whose value is
+// preferred by a given comparison of numbers.
+func (va *ValueArray) extremeValue(better func(a, b float64) bool) (*Value, *Value) {
+	bestK, bestV := "", (*Value)(nil)
+	for _, k := range va.scalarKeys() {
+		v := va.data[k]
+		if bestV == nil || better(v.Float64(), bestV.Float64()) {
+			bestK, bestV = k, v
+		}
+	}
+	if bestV == nil {
+		return va.script.NewValue(""), va.script.NewValue("")
+	}
+	return va.script.NewValue(bestK), bestV
+}
+
+// MinValue returns the key and value of the element of a ValueArray with the
+// smallest numeric value, which is handy for reporting, say, the least busy
+// host.  Ties are broken in favor of the key that sorts first as a string.
+// Subarrays are ignored.  If the ValueArray contains no scalars, both the key
+// and the value are empty strings.
+func (va *ValueArray) MinValue() (k, v *Value) {
+	return va.extremeValue(func(a, b float64) bool { return a < b })
+}
+
+// MaxValue returns the key and value of the element of a ValueArray with the
+// largest numeric value.  It is otherwise analogous to MinValue.
+func (va *ValueArray) MaxValue() (k, v *Value) {
+	return va.extremeValue(func(a, b float64) bool { return a > b })
+}
+
+// MeanValue returns the arithmetic mean of all values in a ValueArray.
+// Subarrays are ignored.  The mean of an empty ValueArray is determined by
+// the script's division-by-zero policy (see SetZeroDivPolicy) or is zero for
+// a standalone ValueArray.
+func (va *ValueArray) MeanValue() *Value {
+	n := len(va.scalarKeys())
+	if va.script == nil && n == 0 {
+		return va.script.NewValue(0)
+	}
+	return va.script.Ratio(va.SumValues(), va.script.NewValue(n))
+}
+
+// CountIf returns the number of elements of a ValueArray, including
+// subarrays, for which a predicate returns true.  For example, the number of
+// clients that made at least 100 requests is
+//
+//	hits.CountIf(func(k, v *awk.Value) bool { return v.Int() >= 100 })
+func (va *ValueArray) CountIf(pred func(k, v *Value) bool) int {
+	n := 0
+	for k, v := range va.data {
+		if pred(va.script.NewValue(k), v) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,57 @@
+// This file tests summary statistics over ValueArrays.
+
+package awk
+
+import (
+	"math"
+	"testing"
+)
+
+// TestAggregates tests SumValues, MinValue, MaxValue, MeanValue, and CountIf.
+func TestAggregates(t *testing.T) {
+	scr := NewScript()
+	va := scr.NewValueArray().FromMap(map[string]string{
+		"alpha": "10",
+		"beta":  "2.5",
+		"gamma": "40",
+		"delta": "2.5",
+		"eps":   "n/a",
+	})
+	va.Set("sub", scr.NewValueArray().FromMap(map[string]int{"x": 1000}))
+
+	if got := va.SumValues().String(); got != "55" {
+		t.Fatalf("Expected %q but received %q", "55", got)
+	}
+	if k, v := va.MinValue(); k.String() != "eps" || v.String() != "n/a" {
+		t.Fatalf("Expected (eps, n/a) but received (%s, %s)", k, v)
+	}
+	va.Delete("eps")
+	if k, v := va.MinValue(); k.String() != "beta" || v.String() != "2.5" {
+		t.Fatalf("Expected (beta, 2.5) but received (%s, %s)", k, v)
+	}
+	if k, v := va.MaxValue(); k.String() != "gamma" || v.String() != "40" {
+		t.Fatalf("Expected (gamma, 40) but received (%s, %s)", k, v)
+	}
+	if got := va.MeanValue().String(); got != "13.75" {
+		t.Fatalf("Expected %q but received %q", "13.75", got)
+	}
+	if got := va.CountIf(func(k, v *Value) bool { return v.Float64() > 5 }); got != 2 {
+		t.Fatalf("Expected 2 but received %d", got)
+	}
+
+	// Empty arrays should produce well-defined results.
+	empty := scr.NewValueArray()
+	if k, v := empty.MaxValue(); k.String() != "" || v.String() != "" {
+		t.Fatalf("Expected empty strings but received (%q, %q)", k, v)
+	}
+	if got := empty.MeanValue().String(); got != "0" {
+		t.Fatalf("Expected %q but received %q", "0", got)
+	}
+	scr.SetZeroDivPolicy(ZeroDivNaN)
+	if got := empty.MeanValue().Float64(); !math.IsNaN(got) {
+		t.Fatalf("Expected NaN but received %v", got)
+	}
+	if got := NewValueArray().MeanValue().String(); got != "0" {
+		t.Fatalf("Expected %q but received %q", "0", got)
+	}
+}
@@ -0,0 +1,48 @@
+// This file provides deterministic pseudonymization of fields.
+
+package awk
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// pseudonymLen is the number of bytes of HMAC output used to form a
+// pseudonym.
+const pseudonymLen = 8
+
+// pseudonym maps a string to a stable token using HMAC-SHA-256 with a given
+// key.
+func pseudonym(str string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(str))
+	return hex.EncodeToString(mac.Sum(nil)[:pseudonymLen])
+}
+
+// Pseudonymize replaces a field of the current record with a token derived
+// from the field's contents and a secret key using HMAC-SHA-256.  The same
+// contents and key always produce the same token, so pseudonymized data can
+// still be joined and aggregated, but the original contents cannot be
+// recovered without the key.  An empty field is left empty.  If a table was
+// provided by SetPseudonymTable, the mapping from token to original contents
+// is recorded in it.
+func (s *Script) Pseudonymize(field int, key []byte) {
+	orig := s.F(field)
+	if orig.String() == "" {
+		return
+	}
+	tok := pseudonym(orig.String(), key)
+	if s.pseudonyms != nil {
+		s.pseudonyms.Set(tok, orig.String())
+	}
+	s.SetF(field, s.NewValue(tok))
+}
+
+// SetPseudonymTable specifies a ValueArray in which Pseudonymize should
+// record the mapping from each token to the original field contents, making
+// pseudonymization reversible by whoever holds the table.  Passing nil stops
+// recording the mapping.
+func (s *Script) SetPseudonymTable(va *ValueArray) {
+	s.pseudonyms = va
+}
@@ -0,0 +1,207 @@
+// This file provides arithmetic on Values.
+
+package awk
+
+import (
+	"math"
+	"math/big"
+)
+
+// maxExactInt is the largest magnitude of an integer that a float64 can
+// represent exactly.
+const maxExactInt = 1 << 53
+
+// minInt is the most negative int.
+const minInt = -int(^uint(0)>>1) - 1
+
+// maxBigExpBits bounds the size of an exponent to which Pow will raise an
+// arbitrary-precision integer so that a mistaken exponent does not exhaust
+// memory.
+const maxBigExpBits = 24
+
+// operand converts an arithmetic operand to a Value.
+func (v *Value) operand(x interface{}) *Value {
+	if xv, ok := x.(*Value); ok {
+		return xv
+	}
+	return v.script.NewValue(x)
+}
+
+// numeric returns a Value as both an int and a float64 and says whether the
+// Value is integral (and thus whether the int is valid).  A Value created from
+// an integer is returned exactly, regardless of any conversions it has since
+// undergone.
+func (v *Value) numeric() (int, float64, bool) {
+	if v.isInt() {
+		return v.ival, float64(v.ival), true
+	}
+	f := v.Float64()
+	if f == math.Trunc(f) && math.Abs(f) <= maxExactInt {
+		return int(f), f, true
+	}
+	return 0, f, false
+}
+
+// arith applies an arithmetic operation to two Values.  In
+// arbitrary-precision mode (see BigNumbers), the result is computed by
+// bigArith using bigIntOp and bigFloatOp if possible.  Otherwise, if both
+// Values are integral and intOp reports success, the result is an integer.
+// Otherwise, the result is computed by floatOp.
+func (v *Value) arith(x interface{},
+	intOp func(a, b int) (int, bool), floatOp func(a, b float64) float64,
+	bigIntOp func(a, b *big.Int) (*big.Int, bool), bigFloatOp func(a, b *big.Float, prec uint) (*big.Float, bool)) *Value {
+	v2 := v.operand(x)
+	if v.bigMode(v2) {
+		if r := v.bigArith(v2, bigIntOp, bigFloatOp); r != nil {
+			return r
+		}
+	}
+	i1, f1, int1 := v.numeric()
+	i2, f2, int2 := v2.numeric()
+	if int1 && int2 {
+		if r, ok := intOp(i1, i2); ok {
+			return v.script.NewValue(r)
+		}
+	}
+	return v.script.NewValue(floatOp(f1, f2))
+}
+
+// Add returns the sum of a Value and another Value or any type that can be
+// converted to a Value.  Both are converted to numbers as in AWK.  The result
+// is an integer if both operands are integral and the sum does not overflow.
+// See BigNumbers for arithmetic that never overflows.
+func (v *Value) Add(x interface{}) *Value {
+	return v.arith(x,
+		func(a, b int) (int, bool) {
+			r := a + b
+			return r, (r > a) == (b > 0)
+		},
+		func(a, b float64) float64 { return a + b },
+		func(a, b *big.Int) (*big.Int, bool) { return new(big.Int).Add(a, b), true },
+		func(a, b *big.Float, prec uint) (*big.Float, bool) {
+			return new(big.Float).SetPrec(prec).Add(a, b), true
+		})
+}
+
+// Subtract returns the difference of a Value and another Value or any type
+// that can be converted to a Value, following the same rules as Add.  (The
+// name Sub is taken by regular-expression substitution.)
+func (v *Value) Subtract(x interface{}) *Value {
+	return v.arith(x,
+		func(a, b int) (int, bool) {
+			r := a - b
+			return r, (r < a) == (b > 0)
+		},
+		func(a, b float64) float64 { return a - b },
+		func(a, b *big.Int) (*big.Int, bool) { return new(big.Int).Sub(a, b), true },
+		func(a, b *big.Float, prec uint) (*big.Float, bool) {
+			return new(big.Float).SetPrec(prec).Sub(a, b), true
+		})
+}
+
+// Mul returns the product of a Value and another Value or any type that can
+// be converted to a Value, following the same rules as Add.
+func (v *Value) Mul(x interface{}) *Value {
+	return v.arith(x,
+		func(a, b int) (int, bool) {
+			if a == 0 || b == 0 {
+				return 0, true
+			}
+			r := a * b
+			return r, r/b == a && !(a == -1 && b == minInt) && !(b == -1 && a == minInt)
+		},
+		func(a, b float64) float64 { return a * b },
+		func(a, b *big.Int) (*big.Int, bool) { return new(big.Int).Mul(a, b), true },
+		func(a, b *big.Float, prec uint) (*big.Float, bool) {
+			return new(big.Float).SetPrec(prec).Mul(a, b), true
+		})
+}
+
+// Div returns the quotient of a Value and another Value or any type that can
+// be converted to a Value.  The result is an integer if both operands are
+// integral and the division is exact.  Division by zero produces an infinity
+// or NaN, as in floating-point arithmetic.
+func (v *Value) Div(x interface{}) *Value {
+	return v.arith(x,
+		func(a, b int) (int, bool) {
+			if b == 0 || a%b != 0 || (a == minInt && b == -1) {
+				return 0, false
+			}
+			return a / b, true
+		},
+		func(a, b float64) float64 { return a / b },
+		func(a, b *big.Int) (*big.Int, bool) {
+			if b.Sign() == 0 {
+				return nil, false
+			}
+			q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+			return q, r.Sign() == 0
+		},
+		func(a, b *big.Float, prec uint) (*big.Float, bool) {
+			if b.Sign() == 0 {
+				return nil, false
+			}
+			return new(big.Float).SetPrec(prec).Quo(a, b), true
+		})
+}
+
+// Mod returns the remainder of dividing a Value by another Value or any type
+// that can be converted to a Value.  As in AWK, the remainder has the same
+// sign as the dividend, and non-integral operands are permitted.  The result
+// is an integer if both operands are integral.  A remainder modulo zero is
+// NaN.
+func (v *Value) Mod(x interface{}) *Value {
+	return v.arith(x,
+		func(a, b int) (int, bool) {
+			if b == 0 {
+				return 0, false
+			}
+			if b == -1 {
+				return 0, true
+			}
+			return a % b, true
+		},
+		math.Mod,
+		func(a, b *big.Int) (*big.Int, bool) {
+			if b.Sign() == 0 {
+				return nil, false
+			}
+			return new(big.Int).Rem(a, b), true
+		},
+		func(a, b *big.Float, prec uint) (*big.Float, bool) {
+			if b.Sign() == 0 {
+				return nil, false
+			}
+			q, _ := new(big.Float).SetPrec(prec).Quo(a, b).Int(nil)
+			bq := new(big.Float).SetPrec(prec).SetInt(q)
+			return new(big.Float).SetPrec(prec).Sub(a, bq.Mul(bq, b)), true
+		})
+}
+
+// Pow returns a Value raised to the power of another Value or any type that
+// can be converted to a Value.  The result is an integer if both operands are
+// integral, the exponent is nonnegative, and the result can be represented
+// exactly.
+func (v *Value) Pow(x interface{}) *Value {
+	return v.arith(x,
+		func(a, b int) (int, bool) {
+			if b < 0 {
+				return 0, false
+			}
+			r := math.Pow(float64(a), float64(b))
+			if math.Abs(r) > maxExactInt {
+				return 0, false
+			}
+			return int(r), true
+		},
+		math.Pow,
+		func(a, b *big.Int) (*big.Int, bool) {
+			if b.Sign() < 0 || b.BitLen() > maxBigExpBits {
+				return nil, false
+			}
+			return new(big.Int).Exp(a, b, nil), true
+		},
+		func(a, b *big.Float, prec uint) (*big.Float, bool) {
+			return nil, false
+		})
+}
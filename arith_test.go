@@ -0,0 +1,64 @@
+// This file tests arithmetic on Values.
+
+package awk
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+// TestArith tests arithmetic operations on Values.
+func TestArith(t *testing.T) {
+	scr := NewScript()
+	v := func(x interface{}) *Value { return scr.NewValue(x) }
+	tests := []struct {
+		got   *Value // Result of an operation
+		want  string // Expected result as a string
+		isInt bool   // true=result should be an int; false=float64
+	}{
+		{got: v(3).Add(4), want: "7", isInt: true},
+		{got: v("3").Add("4.5"), want: "7.5"},
+		{got: v(" 10 apples").Subtract(3), want: "7", isInt: true},
+		{got: v(6).Mul(v(7)), want: "42", isInt: true},
+		{got: v(7).Div(2), want: "3.5"},
+		{got: v(8).Div("2"), want: "4", isInt: true},
+		{got: v(-7).Mod(3), want: "-1", isInt: true},
+		{got: v(7.5).Mod(2), want: "1.5"},
+		{got: v(2).Pow(10), want: "1024", isInt: true},
+		{got: v(2).Pow(-1), want: "0.5"},
+		{got: v("abc").Add(1), want: "1", isInt: true},
+		{got: v(math.MaxInt64).Add(1), want: "9.22337e+18"},
+		{got: v(1).Div(0), want: "+Inf"},
+	}
+	for i, tc := range tests {
+		if got := tc.got.String(); got != tc.want {
+			t.Fatalf("Test %d: expected %q but received %q", i, tc.want, got)
+		}
+		if tc.isInt != (tc.got.ivalOk && !tc.got.fvalOk) {
+			t.Fatalf("Test %d: expected int-ness of %v", i, tc.isInt)
+		}
+	}
+}
+
+// TestArithAfterConversion tests that integer arithmetic remains exact for
+// large integers even after they have been converted to strings or floats.
+func TestArithAfterConversion(t *testing.T) {
+	scr := NewScript()
+	x := scr.NewValue(1 << 60)
+	_ = x.String()
+	if got, want := x.Add(1).String(), strconv.Itoa(1<<60+1); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	y := scr.NewValue(1<<51 + 1)
+	y.Float64()
+	if got, want := y.Mul(7).String(), strconv.Itoa((1<<51+1)*7); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	z := scr.NewValue(7)
+	z.Float64()
+	_ = z.String()
+	if got, want := scr.NewValue(1<<51+1).Mul(z).String(), strconv.Itoa((1<<51+1)*7); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
@@ -0,0 +1,88 @@
+// This file converts between ValueArrays and Go maps.
+
+package awk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToStringMap returns the contents of a ValueArray as a map from index
+// strings to value strings, suitable for passing to code that knows nothing
+// about Values, such as a text/template or a JSON encoder.  Elements of
+// subarrays are included with their indexes joined by the subscript
+// separator, as if they had been stored with a simulated multidimensional
+// index (see Set).
+func (va *ValueArray) ToStringMap() map[string]string {
+	m := make(map[string]string, len(va.data))
+	sep := va.subSep()
+	va.Walk(func(keys []*Value, v *Value) {
+		m[joinKeys(keys, sep)] = v.String()
+	})
+	return m
+}
+
+// ToFloatMap returns the contents of a ValueArray as a map from index strings
+// to numbers, suitable for passing to, for example, a metrics library.
+// Values are converted as with Value.Float64.  Elements of subarrays are
+// included as in ToStringMap.
+func (va *ValueArray) ToFloatMap() map[string]float64 {
+	m := make(map[string]float64, len(va.data))
+	sep := va.subSep()
+	va.Walk(func(keys []*Value, v *Value) {
+		m[joinKeys(keys, sep)] = v.Float64()
+	})
+	return m
+}
+
+// joinKeys joins a list of indexes with a separator.
+func joinKeys(keys []*Value, sep string) string {
+	strs := make([]string, len(keys))
+	for i, k := range keys {
+		strs[i] = k.String()
+	}
+	return strings.Join(strs, sep)
+}
+
+// FromMap stores every element of a Go map in a ValueArray, replacing any
+// existing elements with the same indexes, and returns the ValueArray to
+// facilitate chaining, as in
+//
+//	rates := s.NewValueArray().FromMap(map[string]float64{"USD": 1, "EUR": 1.08})
+//
+// The map must be a map[string]string, map[string]float64, map[string]int,
+// map[string]*Value, or map[string]interface{}.  In the last case, each value
+// is converted with NewValue, except that a value that is itself one of the
+// preceding map types is converted to a subarray.  FromMap panics if given
+// any other type.
+func (va *ValueArray) FromMap(m interface{}) *ValueArray {
+	switch m := m.(type) {
+	case map[string]string:
+		for k, v := range m {
+			va.Set(k, v)
+		}
+	case map[string]float64:
+		for k, v := range m {
+			va.Set(k, v)
+		}
+	case map[string]int:
+		for k, v := range m {
+			va.Set(k, v)
+		}
+	case map[string]*Value:
+		for k, v := range m {
+			va.Set(k, v)
+		}
+	case map[string]interface{}:
+		for k, v := range m {
+			switch v.(type) {
+			case map[string]string, map[string]float64, map[string]int, map[string]*Value, map[string]interface{}:
+				v = va.script.NewValueArray().FromMap(v)
+			}
+			va.Set(k, v)
+		}
+	default:
+		panic(fmt.Sprintf("ValueArray.FromMap does not accept a %T", m))
+	}
+	return va
+}
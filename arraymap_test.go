@@ -0,0 +1,72 @@
+// This file tests conversions between ValueArrays and Go maps.
+
+package awk
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestToMap tests exporting a ValueArray as a Go map.
+func TestToMap(t *testing.T) {
+	scr := NewScript()
+	scr.SubSep = ":"
+	va := scr.NewValueArray()
+	va.Set("apple", 3)
+	va.Set("pear", "1.5")
+	va.Set("x", "y", "z")
+	sub := scr.NewValueArray()
+	sub.Set("inner", "lemon")
+	va.Set("nested", sub)
+
+	gotS := va.ToStringMap()
+	wantS := map[string]string{"apple": "3", "pear": "1.5", "x:y": "z", "nested:inner": "lemon"}
+	if !reflect.DeepEqual(gotS, wantS) {
+		t.Fatalf("Expected %v but received %v", wantS, gotS)
+	}
+	gotF := va.ToFloatMap()
+	wantF := map[string]float64{"apple": 3, "pear": 1.5, "x:y": 0, "nested:inner": 0}
+	if !reflect.DeepEqual(gotF, wantF) {
+		t.Fatalf("Expected %v but received %v", wantF, gotF)
+	}
+}
+
+// TestFromMap tests loading a ValueArray from a Go map.
+func TestFromMap(t *testing.T) {
+	scr := NewScript()
+	va := scr.NewValueArray().FromMap(map[string]float64{"USD": 1, "EUR": 1.08})
+	if got := va.Get("EUR").Float64(); got != 1.08 {
+		t.Fatalf("Expected %v but received %v", 1.08, got)
+	}
+	va.FromMap(map[string]string{"USD": "one", "GBP": "1.27"})
+	if got := va.Get("USD").String(); got != "one" {
+		t.Fatalf("Expected %q but received %q", "one", got)
+	}
+	if got := len(va.Keys()); got != 3 {
+		t.Fatalf("Expected 3 keys but received %d", got)
+	}
+
+	// Nested maps should become subarrays.
+	va = NewValueArray().FromMap(map[string]interface{}{
+		"n":    7,
+		"sub":  map[string]int{"a": 1},
+		"deep": map[string]interface{}{"b": map[string]string{"c": "d"}},
+	})
+	if got := va.Get("n").Int(); got != 7 {
+		t.Fatalf("Expected 7 but received %d", got)
+	}
+	if got := va.SubArray("sub").Get("a").Int(); got != 1 {
+		t.Fatalf("Expected 1 but received %d", got)
+	}
+	if got := va.SubArray("deep").SubArray("b").Get("c").String(); got != "d" {
+		t.Fatalf("Expected %q but received %q", "d", got)
+	}
+
+	// Unsupported types should be rejected.
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected FromMap to panic on an unsupported type")
+		}
+	}()
+	va.FromMap(map[int]string{1: "one"})
+}
@@ -0,0 +1,70 @@
+// This file lets a ValueArray iterate over its elements in a deterministic
+// order.
+
+package awk
+
+import "sort"
+
+// An ArrayOrder specifies the order in which a ValueArray's Keys and Values
+// methods return its elements.
+type ArrayOrder int
+
+// These are the orders in which a ValueArray can return its elements.
+const (
+	OrderUndefined ArrayOrder = iota // Undefined order, as in AWK (fastest)
+	OrderInsertion                   // Order in which keys were first stored
+	OrderSorted                      // Order of keys, compared as strings (see Script.SetCollator)
+	OrderNatural                     // Order of keys, compared in natural order (see NaturalCompare)
+)
+
+// SetOrder specifies the order in which Keys and Values return a ValueArray's
+// elements.  The default, OrderUndefined, follows Go's map iteration order,
+// which varies from run to run.  OrderInsertion, OrderSorted, and
+// OrderNatural make the order deterministic so that reports and tests
+// produce identical output across runs and Go versions.  With
+// OrderInsertion, a key that is deleted and stored again moves to the end;
+// keys already present when SetOrder is called are ordered as by
+// OrderSorted.  SetOrder returns its receiver to facilitate chaining.
+func (va *ValueArray) SetOrder(o ArrayOrder) *ValueArray {
+	if o == OrderInsertion && va.order != OrderInsertion {
+		va.seqs = make(map[string]int, len(va.data))
+		va.seq = 0
+		va.order = OrderSorted
+		for _, k := range va.orderedKeys() {
+			va.seqs[k] = va.seq
+			va.seq++
+		}
+	}
+	if o != OrderInsertion {
+		va.seqs = nil
+	}
+	va.order = o
+	return va
+}
+
+// orderedKeys returns all index strings in the order specified by SetOrder.
+func (va *ValueArray) orderedKeys() []string {
+	keys := make([]string, 0, len(va.data))
+	for k := range va.data {
+		keys = append(keys, k)
+	}
+	switch va.order {
+	case OrderInsertion:
+		sort.Slice(keys, func(i, j int) bool {
+			return va.seqs[keys[i]] < va.seqs[keys[j]]
+		})
+	case OrderSorted:
+		va.script.sortCollated(keys)
+	case OrderNatural:
+		sortNatural(keys)
+	}
+	return keys
+}
+
+// SetArrayOrder specifies the iteration order (see ValueArray.SetOrder) of
+// all ValueArrays subsequently created by the script's NewValueArray method,
+// including those created by Split and SubArray.  This makes an entire
+// script's output reproducible without configuring each array individually.
+func (s *Script) SetArrayOrder(o ArrayOrder) {
+	s.arrayOrder = o
+}
@@ -0,0 +1,59 @@
+// This file tests deterministic ValueArray iteration orders.
+
+package awk
+
+import (
+	"strings"
+	"testing"
+)
+
+// joinValues concatenates the string forms of a list of Values.
+func joinValues(vs []*Value) string {
+	strs := make([]string, len(vs))
+	for i, v := range vs {
+		strs[i] = v.String()
+	}
+	return strings.Join(strs, " ")
+}
+
+// TestArrayOrder tests insertion-ordered and sorted iteration.
+func TestArrayOrder(t *testing.T) {
+	scr := NewScript()
+	a := scr.NewValueArray()
+	a.Set("b", 1)
+	a.Set("a", 2)
+	a.SetOrder(OrderInsertion)
+	for _, k := range []string{"zeta", "gamma", "alpha", "b"} {
+		a.Set(k, len(k))
+	}
+	a.Delete("a")
+	a.Set("a", 0)
+	if got, want := joinValues(a.Keys()), "b zeta gamma alpha a"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	if got, want := joinValues(a.Values()), "1 4 5 5 0"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// Ensure that snapshots retain the order.
+	snap := a.Snapshot()
+	snap.Set("new", 9)
+	if got, want := joinValues(snap.Keys()), "b zeta gamma alpha a new"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	a.SetOrder(OrderSorted)
+	if got, want := joinValues(a.Keys()), "a alpha b gamma zeta"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
+
+// TestSetArrayOrder tests specifying the order of all of a script's arrays.
+func TestSetArrayOrder(t *testing.T) {
+	scr := NewScript()
+	scr.SetArrayOrder(OrderInsertion)
+	va, _ := scr.Split("pear apple fig", " ")
+	if got, want := joinValues(va.Values()), "pear apple fig"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
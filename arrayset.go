@@ -0,0 +1,75 @@
+// This file provides set operations on the keys of ValueArrays, as in
+// comparing the sets of keys seen in two input streams.
+
+package awk
+
+// copyElement returns a copy of a ValueArray element that is unaffected by
+// subsequent modifications to the original.
+func copyElement(v *Value) *Value {
+	vc := *v
+	if v.aval != nil {
+		vc.aval = v.aval.Snapshot()
+	}
+	return &vc
+}
+
+// emptyCopy returns an empty ValueArray with the same script and iteration
+// order as a given ValueArray.
+func (va *ValueArray) emptyCopy() *ValueArray {
+	return va.script.NewValueArray().SetOrder(va.order)
+}
+
+// Merge copies every element of another ValueArray into a ValueArray.  When
+// both arrays contain the same index, the element stored is the result of
+// calling resolve on the index, the receiver's value, and the other array's
+// value; a nil resolve keeps the other array's value.  For example, counts
+// gathered from two input streams can be combined with
+//
+//	total.Merge(counts, func(k, v1, v2 *awk.Value) *awk.Value {
+//		return v1.Add(v2)
+//	})
+//
+// Merge returns its receiver to facilitate chaining.
+func (va *ValueArray) Merge(other *ValueArray, resolve func(k, v1, v2 *Value) *Value) *ValueArray {
+	for _, k := range other.orderedKeys() {
+		v2 := copyElement(other.data[k])
+		if v1, ok := va.data[k]; ok && resolve != nil {
+			v2 = resolve(va.script.NewValue(k), v1, v2)
+		}
+		va.store(k, v2)
+	}
+	return va
+}
+
+// Union returns a new ValueArray that contains every index that appears in
+// either a ValueArray or another ValueArray.  An index that appears in both
+// takes its value from the receiver.
+func (va *ValueArray) Union(other *ValueArray) *ValueArray {
+	res := va.emptyCopy().Merge(va, nil)
+	return res.Merge(other, func(k, v1, v2 *Value) *Value { return v1 })
+}
+
+// Intersect returns a new ValueArray that contains only the indexes that
+// appear in both a ValueArray and another ValueArray, with values taken from
+// the receiver.
+func (va *ValueArray) Intersect(other *ValueArray) *ValueArray {
+	res := va.emptyCopy()
+	for _, k := range va.orderedKeys() {
+		if _, ok := other.data[k]; ok {
+			res.store(k, copyElement(va.data[k]))
+		}
+	}
+	return res
+}
+
+// Difference returns a new ValueArray that contains only the indexes that
+// appear in a ValueArray but not in another ValueArray, as with comm -23.
+func (va *ValueArray) Difference(other *ValueArray) *ValueArray {
+	res := va.emptyCopy()
+	for _, k := range va.orderedKeys() {
+		if _, ok := other.data[k]; !ok {
+			res.store(k, copyElement(va.data[k]))
+		}
+	}
+	return res
+}
@@ -0,0 +1,67 @@
+// This file tests set operations on ValueArrays.
+
+package awk
+
+import (
+	"testing"
+)
+
+// keysAndValues returns a ValueArray's keys and values, sorted by key, as a
+// single string.
+func keysAndValues(va *ValueArray) string {
+	str := ""
+	for _, k := range va.SortedKeys(nil) {
+		str += k.String() + "=" + va.Get(k).String() + " "
+	}
+	return str
+}
+
+// TestArraySets tests Merge, Union, Intersect, and Difference.
+func TestArraySets(t *testing.T) {
+	scr := NewScript()
+	a := scr.NewValueArray().FromMap(map[string]int{"x": 1, "y": 2, "z": 3})
+	b := scr.NewValueArray().FromMap(map[string]int{"y": 20, "z": 30, "w": 40})
+
+	tests := []struct {
+		name string      // Operation
+		va   *ValueArray // Result of the operation
+		want string      // Expected contents
+	}{
+		{"Union", a.Union(b), "w=40 x=1 y=2 z=3 "},
+		{"Intersect", a.Intersect(b), "y=2 z=3 "},
+		{"Difference", a.Difference(b), "x=1 "},
+		{"Difference", b.Difference(a), "w=40 "},
+	}
+	for _, tc := range tests {
+		if got := keysAndValues(tc.va); got != tc.want {
+			t.Fatalf("%s: expected %q but received %q", tc.name, tc.want, got)
+		}
+	}
+
+	// The operands should be unmodified.
+	if got, want := keysAndValues(a), "x=1 y=2 z=3 "; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// Merge should resolve conflicts with the given function or by
+	// keeping the other array's values.
+	sum := a.Snapshot().Merge(b, func(k, v1, v2 *Value) *Value { return v1.Add(v2) })
+	if got, want := keysAndValues(sum), "w=40 x=1 y=22 z=33 "; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	over := a.Snapshot().Merge(b, nil)
+	if got, want := keysAndValues(over), "w=40 x=1 y=20 z=30 "; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// Merged subarrays should be copies.
+	sub := scr.NewValueArray()
+	sub.Set("k", "v")
+	c := scr.NewValueArray()
+	c.Set("sub", sub)
+	d := scr.NewValueArray().Merge(c, nil)
+	sub.Set("k", "changed")
+	if got := d.SubArray("sub").Get("k").String(); got != "v" {
+		t.Fatalf("Expected %q but received %q", "v", got)
+	}
+}
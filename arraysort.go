@@ -0,0 +1,152 @@
+// This file provides sorted traversal of ValueArrays, like gawk's asort and
+// asorti functions.
+
+package awk
+
+import "sort"
+
+// An ArrayCompare compares two elements of a ValueArray, given their keys and
+// values, and returns a negative number, zero, or a positive number if the
+// first element should sort respectively before, equally with, or after the
+// second.  It is analogous to a gawk user-defined function named by
+// PROCINFO["sorted_in"].
+type ArrayCompare func(k1, v1, k2, v2 *Value) int
+
+// SortByIndexString compares array elements by their keys as strings, like
+// gawk's "@ind_str_asc".  Strings are compared with the collator of the
+// first key's script, if any (see Script.SetCollator).
+func SortByIndexString(k1, v1, k2, v2 *Value) int {
+	return k1.script.collate(k1.String(), k2.String())
+}
+
+// SortByIndexNumber compares array elements by their keys as numbers, like
+// gawk's "@ind_num_asc".  Keys that do not look like numbers are treated as
+// zero.
+func SortByIndexNumber(k1, v1, k2, v2 *Value) int {
+	return compareFloats(k1.Float64(), k2.Float64())
+}
+
+// SortByValueString compares array elements by their values as strings, like
+// gawk's "@val_str_asc".  Subarrays sort after all scalars.  Strings are
+// compared with the collator of the first value's script, if any (see
+// Script.SetCollator).
+func SortByValueString(k1, v1, k2, v2 *Value) int {
+	if c, ok := compareArrays(v1, v2); ok {
+		return c
+	}
+	return v1.script.collate(v1.String(), v2.String())
+}
+
+// SortByValueNumber compares array elements by their values as numbers, like
+// gawk's "@val_num_asc".  Values that do not look like numbers are treated as
+// zero.  Subarrays sort after all scalars.
+func SortByValueNumber(k1, v1, k2, v2 *Value) int {
+	if c, ok := compareArrays(v1, v2); ok {
+		return c
+	}
+	return compareFloats(v1.Float64(), v2.Float64())
+}
+
+// SortByValue compares array elements by their values using AWK's comparison
+// rules (see Value.Cmp), so numbers and numeric strings compare numerically
+// and other values compare as strings, much like gawk's "@val_type_asc".
+// Subarrays sort after all scalars.
+func SortByValue(k1, v1, k2, v2 *Value) int {
+	if c, ok := compareArrays(v1, v2); ok {
+		return c
+	}
+	return v1.Cmp(v2)
+}
+
+// Reverse returns an ArrayCompare that sorts in the opposite order from a
+// given ArrayCompare, like gawk's "_desc" orders.
+func Reverse(cmp ArrayCompare) ArrayCompare {
+	return func(k1, v1, k2, v2 *Value) int {
+		return cmp(k2, v2, k1, v1)
+	}
+}
+
+// compareFloats compares two float64s, returning -1, 0, or +1.
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareArrays compares two Values if at least one of them is a subarray and
+// returns the result and true.  Subarrays sort after scalars and equally with
+// each other.  compareArrays returns false if both Values are scalars.
+func compareArrays(v1, v2 *Value) (int, bool) {
+	a1, a2 := v1.aval != nil, v2.aval != nil
+	switch {
+	case a1 && a2:
+		return 0, true
+	case a1:
+		return 1, true
+	case a2:
+		return -1, true
+	}
+	return 0, false
+}
+
+// sortedKeys returns a ValueArray's index strings sorted with a given
+// ArrayCompare.  Elements that compare equal are ordered by index string so
+// the result is deterministic.
+func (va *ValueArray) sortedKeys(cmp ArrayCompare) []string {
+	keys := make([]string, 0, len(va.data))
+	for k := range va.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]*Value, len(keys))
+	for i, k := range keys {
+		kvs[i] = va.script.NewValue(k)
+	}
+	idx := make([]int, len(keys))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		a, b := idx[i], idx[j]
+		return cmp(kvs[a], va.data[keys[a]], kvs[b], va.data[keys[b]]) < 0
+	})
+	sorted := make([]string, len(keys))
+	for i, n := range idx {
+		sorted[i] = keys[n]
+	}
+	return sorted
+}
+
+// SortedKeys returns all keys in a ValueArray, sorted with a given
+// ArrayCompare, as with gawk's asorti function.  A nil ArrayCompare implies
+// SortByIndexString.
+func (va *ValueArray) SortedKeys(cmp ArrayCompare) []*Value {
+	if cmp == nil {
+		cmp = SortByIndexString
+	}
+	keys := va.sortedKeys(cmp)
+	kvs := make([]*Value, len(keys))
+	for i, k := range keys {
+		kvs[i] = va.script.NewValue(k)
+	}
+	return kvs
+}
+
+// SortedValues returns all values in a ValueArray, sorted with a given
+// ArrayCompare, as with gawk's asort function.  A nil ArrayCompare implies
+// SortByValue.
+func (va *ValueArray) SortedValues(cmp ArrayCompare) []*Value {
+	if cmp == nil {
+		cmp = SortByValue
+	}
+	keys := va.sortedKeys(cmp)
+	vals := make([]*Value, len(keys))
+	for i, k := range keys {
+		vals[i] = va.script.NewValue(va.data[k])
+	}
+	return vals
+}
@@ -0,0 +1,35 @@
+// This file tests sorted traversal of ValueArrays.
+
+package awk
+
+import "testing"
+
+// TestSortedKeysValues tests sorting an array's keys and values with each of
+// the predefined comparators.
+func TestSortedKeysValues(t *testing.T) {
+	scr := NewScript()
+	a := scr.NewValueArray()
+	a.Set("10", "banana")
+	a.Set("9", 25)
+	a.Set("x", "apple")
+	a.Set("100", scr.newInputValue("3"))
+	tests := []struct {
+		got  []*Value
+		want string
+	}{
+		{a.SortedKeys(nil), "10 100 9 x"},
+		{a.SortedKeys(SortByIndexNumber), "x 9 10 100"},
+		{a.SortedKeys(Reverse(SortByIndexNumber)), "100 10 9 x"},
+		{a.SortedValues(nil), "3 25 apple banana"},
+		{a.SortedValues(SortByValueString), "25 3 apple banana"},
+		{a.SortedValues(Reverse(SortByValueNumber)), "25 3 banana apple"},
+		{a.SortedKeys(func(k1, v1, k2, v2 *Value) int {
+			return len(k1.String()) - len(k2.String())
+		}), "9 x 10 100"},
+	}
+	for i, tc := range tests {
+		if got := joinValues(tc.got); got != tc.want {
+			t.Fatalf("Test %d: Expected %q but received %q", i, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// This file helps long-running scripts monitor and control the memory used
+// by their ValueArrays.
+
+package awk
+
+import "unsafe"
+
+// NewValueArraySized creates and returns a standalone ValueArray, not
+// associated with any Script, with space preallocated for about n elements.
+func NewValueArraySized(n int) *ValueArray {
+	var s *Script
+	return s.NewValueArraySized(n)
+}
+
+// NewValueArraySized is like NewValueArray but preallocates space for about n
+// elements.  When the eventual size of an aggregation is known or can be
+// estimated, this avoids the repeated growth of the array as elements are
+// added.
+func (s *Script) NewValueArraySized(n int) *ValueArray {
+	va := s.NewValueArray()
+	if n > 0 {
+		va.data = make(map[string]*Value, n)
+		if va.seqs != nil {
+			va.seqs = make(map[string]int, n)
+		}
+	}
+	return va
+}
+
+// ArrayStats reports the size of a ValueArray.
+type ArrayStats struct {
+	Keys      int   // Number of indexes in the array itself
+	Subarrays int   // Number of subarrays, at all levels of nesting
+	Elements  int   // Number of scalars, at all levels of nesting
+	Bytes     int64 // Approximate number of bytes used, including subarrays
+}
+
+// arrayEntryOverhead is an estimate of the number of bytes a Go map uses for
+// each entry beyond the key's text: the key's string header, the pointer to
+// the Value, and bookkeeping.
+const arrayEntryOverhead = int64(unsafe.Sizeof("") + unsafe.Sizeof((*Value)(nil)) + 8)
+
+// valueSize is the number of bytes a Value occupies, excluding any text or
+// subarray to which it refers.
+const valueSize = int64(unsafe.Sizeof(Value{}))
+
+// Stats returns the number of elements in a ValueArray and an estimate of the
+// memory they occupy.  The estimate accounts for the text of each key and
+// each string value, the Values themselves, and the overhead of the
+// underlying maps, but not for memory shared with other data structures, so
+// it is best used to track an aggregation's growth over time.
+func (va *ValueArray) Stats() ArrayStats {
+	st := ArrayStats{Keys: len(va.data)}
+	for k, v := range va.data {
+		st.Bytes += arrayEntryOverhead + int64(len(k)) + valueSize
+		if v.aval != nil {
+			sub := v.aval.Stats()
+			st.Subarrays += 1 + sub.Subarrays
+			st.Elements += sub.Elements
+			st.Bytes += sub.Bytes
+			continue
+		}
+		st.Elements++
+		if v.svalOk {
+			st.Bytes += int64(len(v.sval))
+		}
+	}
+	if va.seqs != nil {
+		st.Bytes += int64(len(va.seqs)) * (arrayEntryOverhead + int64(unsafe.Sizeof(0)))
+	}
+	if va.index != nil {
+		st.Bytes += int64(len(va.index.keys))*int64(unsafe.Sizeof("")) + int64(len(va.index.nums))*8
+	}
+	return st
+}
@@ -0,0 +1,59 @@
+// This file tests ValueArray memory statistics and capacity hints.
+
+package awk
+
+import (
+	"testing"
+)
+
+// TestArrayStats tests counting elements and estimating memory usage.
+func TestArrayStats(t *testing.T) {
+	scr := NewScript()
+	va := scr.NewValueArraySized(100)
+	if st := va.Stats(); st != (ArrayStats{}) {
+		t.Fatalf("Expected zero statistics but received %+v", st)
+	}
+	va.Set("a", 1)
+	va.Set("b", "hello")
+	sub := scr.NewValueArray()
+	sub.Set("x", "y")
+	sub.Set("z", scr.NewValueArray())
+	va.Set("c", sub)
+	st := va.Stats()
+	if st.Keys != 3 || st.Subarrays != 2 || st.Elements != 3 {
+		t.Fatalf("Expected 3 keys, 2 subarrays, and 3 elements but received %+v", st)
+	}
+	small := st.Bytes
+	if small <= 0 {
+		t.Fatalf("Expected a positive size but received %d", small)
+	}
+	va.Set("b", "a much longer string than before")
+	if st = va.Stats(); st.Bytes != small+int64(len("a much longer string than before")-len("hello")) {
+		t.Fatalf("Expected the size to grow by the difference in string lengths but received %d", st.Bytes-small)
+	}
+
+	// Emptying the array should zero its statistics but leave it usable.
+	va.Delete()
+	if st = va.Stats(); st.Keys != 0 || st.Bytes != 0 {
+		t.Fatalf("Expected an empty array but received %+v", st)
+	}
+	va.Set("again", 1)
+	if got := joinValues(va.Keys()); got != "again" {
+		t.Fatalf("Expected %q but received %q", "again", got)
+	}
+
+	// Insertion order should survive emptying and preallocation.
+	scr.SetArrayOrder(OrderInsertion)
+	va = scr.NewValueArraySized(10)
+	va.Set("z", 1)
+	va.Set("a", 2)
+	va.Delete()
+	va.Set("m", 3)
+	va.Set("b", 4)
+	if got := joinValues(va.Keys()); got != "m b" {
+		t.Fatalf("Expected %q but received %q", "m b", got)
+	}
+	if got := NewValueArraySized(5).Stats().Keys; got != 0 {
+		t.Fatalf("Expected 0 keys but received %d", got)
+	}
+}
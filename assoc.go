@@ -3,21 +3,73 @@
 package awk
 
 import (
+	"sort"
 	"strings"
 )
 
 // A ValueArray maps Values to Values.
 type ValueArray struct {
-	script *Script           // Pointer to the script that produced this value
+	script *Script           // Pointer to the script that produced this value (nil for a standalone ValueArray)
 	data   map[string]*Value // The associative array proper
+	index  *arrayIndex       // Sorted index of keys or nil if not yet computed
+	dflt   func() *Value     // Constructor for the values of missing keys
+	dflIns bool              // true: Get inserts default values; false: it doesn't
+	order  ArrayOrder        // Order in which Keys and Values return elements
+	seqs   map[string]int    // Insertion sequence number of each key (nil unless order is OrderInsertion)
+	seq    int               // Next insertion sequence number
 }
 
-// NewValueArray creates and returns an associative array of Values.
+// An arrayIndex is a sorted list of the keys in a ValueArray.
+type arrayIndex struct {
+	numeric bool      // true: keys are sorted numerically; false: as strings
+	keys    []string  // Sorted keys
+	nums    []float64 // Numeric value of each key (numeric indexes only)
+}
+
+// NewValueArray creates and returns a standalone associative array of Values,
+// not associated with any Script.  A standalone ValueArray separates
+// multidimensional indexes with "\034" until it is associated with a Script
+// by calling Bind.
+func NewValueArray() *ValueArray {
+	var s *Script
+	return s.NewValueArray()
+}
+
+// Bind associates a ValueArray and all of the Values it contains, including
+// those in its subarrays, with a Script so that subsequent operations honor
+// the Script's subscript separator, number conversion format, and case
+// sensitivity.  Indexes that were already stored are not modified.  Bind
+// returns its receiver to facilitate chaining.
+func (va *ValueArray) Bind(s *Script) *ValueArray {
+	va.script = s
+	for _, v := range va.data {
+		v.Bind(s)
+		if v.aval != nil && v.aval.script != s {
+			v.aval.Bind(s)
+		}
+	}
+	return va
+}
+
+// subSep returns the separator to use for simulated multidimensional indexes.
+func (va *ValueArray) subSep() string {
+	if va.script == nil {
+		return "\034"
+	}
+	return va.script.SubSep
+}
+
+// NewValueArray creates and returns an associative array of Values.  The
+// array's iteration order is initially that specified by SetArrayOrder.
 func (s *Script) NewValueArray() *ValueArray {
-	return &ValueArray{
+	va := &ValueArray{
 		script: s,
 		data:   make(map[string]*Value),
 	}
+	if s != nil && s.arrayOrder != OrderUndefined {
+		va.SetOrder(s.arrayOrder)
+	}
+	return va
 }
 
 // Set (index, value) assigns a Value to an index of a ValueArray.  Multiple
@@ -44,7 +96,7 @@ func (va *ValueArray) Set(args ...interface{}) {
 
 	// Handle the most common case: one index and one value.
 	if len(args) == 2 {
-		va.data[argVals[0].String()] = argVals[1]
+		va.store(argVals[0].String(), argVals[1])
 		return
 	}
 
@@ -53,10 +105,23 @@ func (va *ValueArray) Set(args ...interface{}) {
 	for i, v := range argVals[:len(argVals)-1] {
 		idxStrs[i] = v.String()
 	}
-	idx := strings.Join(idxStrs, va.script.SubSep)
+	idx := strings.Join(idxStrs, va.subSep())
 
 	// Associate the final argument with the index string.
-	va.data[idx] = argVals[len(argVals)-1]
+	va.store(idx, argVals[len(argVals)-1])
+}
+
+// store associates a Value with an index string.  It discards the sorted
+// index if the index string was not previously present.
+func (va *ValueArray) store(idx string, v *Value) {
+	if _, found := va.data[idx]; !found {
+		va.index = nil
+		if va.seqs != nil {
+			va.seqs[idx] = va.seq
+			va.seq++
+		}
+	}
+	va.data[idx] = v
 }
 
 // Get returns the Value associated with a given index into a ValueArray.
@@ -64,7 +129,7 @@ func (va *ValueArray) Set(args ...interface{}) {
 // fact, the indexes are concatenated into a single string with intervening
 // Script.SubSep characters.)  The arguments can be provided either as Values
 // or as any types that can be converted to Values.  If the index doesn't
-// appear in the array, a zero value is returned.
+// appear in the array, a zero value is returned (but see SetDefault).
 func (va *ValueArray) Get(args ...interface{}) *Value {
 	// Ensure we were given at least one index.
 	if len(args) < 1 {
@@ -83,9 +148,10 @@ func (va *ValueArray) Get(args ...interface{}) *Value {
 
 	// Handle the most common case: a single index.
 	if len(args) == 1 {
-		vv, found := va.data[argVals[0].String()]
+		idx := argVals[0].String()
+		vv, found := va.data[idx]
 		if !found {
-			return va.script.NewValue("")
+			return va.missing(idx)
 		}
 		return vv
 	}
@@ -95,26 +161,88 @@ func (va *ValueArray) Get(args ...interface{}) *Value {
 	for i, v := range argVals {
 		idxStrs[i] = v.String()
 	}
-	idx := strings.Join(idxStrs, va.script.SubSep)
+	idx := strings.Join(idxStrs, va.subSep())
 
 	// Look up the index in the associative array.
 	vv, found := va.data[idx]
 	if !found {
-		return va.script.NewValue("")
+		return va.missing(idx)
 	}
 	return vv
 }
 
+// Exists says whether a given index appears in a ValueArray, as with AWK's
+// "(index in array)" operator.  Indexes are specified as in Get.  Unlike
+// Get, Exists never adds an element to the array, even if SetDefault was
+// called with insert set to true.
+func (va *ValueArray) Exists(args ...interface{}) bool {
+	if len(args) < 1 {
+		panic("ValueArray.Exists requires at least one index")
+	}
+	_, found := va.data[va.key(args)]
+	return found
+}
+
+// GetOK returns the Value associated with a given index into a ValueArray
+// and true.  If the index doesn't appear in the array, GetOK returns a zero
+// value and false without consulting or invoking any SetDefault factory.
+// Indexes are specified as in Get.
+func (va *ValueArray) GetOK(args ...interface{}) (*Value, bool) {
+	if len(args) < 1 {
+		panic("ValueArray.GetOK requires at least one index")
+	}
+	v, found := va.data[va.key(args)]
+	if !found {
+		return va.script.NewValue(""), false
+	}
+	return v, true
+}
+
+// SetDefault specifies a function that constructs the Value that Get returns
+// when asked for an index that doesn't appear in the array.  If insert is
+// true, Get additionally stores the constructed Value in the array, mimicking
+// AWK's creation of array elements on reference.  Passing a nil factory
+// function restores the default behavior of returning a zero value without
+// modifying the array.
+func (va *ValueArray) SetDefault(factory func() *Value, insert bool) {
+	va.dflt = factory
+	va.dflIns = insert && factory != nil
+}
+
+// missing returns the Value to associate with an index that doesn't appear in
+// the array.
+func (va *ValueArray) missing(idx string) *Value {
+	if va.dflt == nil {
+		return va.script.NewValue("")
+	}
+	v := va.dflt()
+	if v == nil {
+		v = va.script.NewValue("")
+	}
+	if va.dflIns {
+		va.store(idx, v)
+	}
+	return v
+}
+
 // Delete deletes a key and associated value from a ValueArray.  Multiple
 // indexes can be specified to simulate multidimensional arrays.  (In fact, the
 // indexes are concatenated into a single string with intervening Script.SubSep
 // characters.)  The arguments can be provided either as Values or as any types
 // that can be converted to Values.  If no argument is provided, the entire
-// ValueArray is emptied.
+// ValueArray is emptied.  Emptying a ValueArray retains the memory allocated
+// for its elements so that it can be refilled without reallocation.
 func (va *ValueArray) Delete(args ...interface{}) {
-	// If we were given no arguments, delete the entire array.
+	// If we were given no arguments, delete the entire array but keep its
+	// storage.
+	va.index = nil
 	if args == nil {
-		va.data = make(map[string]*Value)
+		for k := range va.data {
+			delete(va.data, k)
+		}
+		for k := range va.seqs {
+			delete(va.seqs, k)
+		}
 		return
 	}
 
@@ -130,7 +258,7 @@ func (va *ValueArray) Delete(args ...interface{}) {
 
 	// Handle the most common case: a single index.
 	if len(args) == 1 {
-		delete(va.data, argVals[0].String())
+		va.remove(argVals[0].String())
 		return
 	}
 
@@ -139,26 +267,345 @@ func (va *ValueArray) Delete(args ...interface{}) {
 	for i, v := range argVals {
 		idxStrs[i] = v.String()
 	}
-	idx := strings.Join(idxStrs, va.script.SubSep)
+	idx := strings.Join(idxStrs, va.subSep())
 
 	// Delete the index from the associative array.
+	va.remove(idx)
+}
+
+// remove deletes an index string and its associated Value.
+func (va *ValueArray) remove(idx string) {
 	delete(va.data, idx)
+	if va.seqs != nil {
+		delete(va.seqs, idx)
+	}
 }
 
-// Keys returns all keys in the associative array in undefined order.
+// Keys returns all keys in the associative array in the order specified by
+// SetOrder (by default, in undefined order).
 func (va *ValueArray) Keys() []*Value {
 	keys := make([]*Value, 0, len(va.data))
-	for kstr := range va.data {
+	for _, kstr := range va.orderedKeys() {
 		keys = append(keys, va.script.NewValue(kstr))
 	}
 	return keys
 }
 
-// Values returns all values in the associative array in undefined order.
+// Values returns all values in the associative array in the order specified
+// by SetOrder (by default, in undefined order).  Values are returned in the
+// same order as the corresponding keys are returned by Keys.
 func (va *ValueArray) Values() []*Value {
 	vals := make([]*Value, 0, len(va.data))
-	for _, v := range va.data {
-		vals = append(vals, va.script.NewValue(v))
+	for _, kstr := range va.orderedKeys() {
+		vals = append(vals, va.script.NewValue(va.data[kstr]))
+	}
+	return vals
+}
+
+// Snapshot returns a copy of a ValueArray that is unaffected by subsequent
+// modifications to the original.  Subarrays are copied recursively.
+func (va *ValueArray) Snapshot() *ValueArray {
+	snap := &ValueArray{
+		script: va.script,
+		data:   make(map[string]*Value, len(va.data)),
+		dflt:   va.dflt,
+		dflIns: va.dflIns,
+		order:  va.order,
+		seq:    va.seq,
+	}
+	if va.seqs != nil {
+		snap.seqs = make(map[string]int, len(va.seqs))
+		for k, n := range va.seqs {
+			snap.seqs[k] = n
+		}
+	}
+	for k, v := range va.data {
+		vc := *v
+		if v.aval != nil {
+			vc.aval = v.aval.Snapshot()
+		}
+		snap.data[k] = &vc
+	}
+	return snap
+}
+
+// An ArrayChange represents a key whose value differs between two ValueArrays.
+type ArrayChange struct {
+	Key *Value // Key common to both arrays
+	Old *Value // Value in the first array
+	New *Value // Value in the second array
+}
+
+// An ArrayDiff describes the differences between two ValueArrays.  Each slice
+// is sorted by key.
+type ArrayDiff struct {
+	Added   []*Value      // Keys that appear only in the second array
+	Removed []*Value      // Keys that appear only in the first array
+	Changed []ArrayChange // Keys whose values differ
+}
+
+// sameElement says whether two ValueArray elements have the same contents.
+// Scalars are compared as strings; subarrays are compared recursively.
+func sameElement(v1, v2 *Value) bool {
+	switch {
+	case v1.aval != nil && v2.aval != nil:
+		return v1.aval.String() == v2.aval.String()
+	case v1.aval != nil || v2.aval != nil:
+		return false
+	default:
+		return v1.String() == v2.String()
+	}
+}
+
+// DiffArrays compares two ValueArrays, typically a Snapshot taken earlier and
+// the current contents of the same array, and reports which keys were added,
+// removed, and changed.
+func DiffArrays(a, b *ValueArray) ArrayDiff {
+	// Sort the keys of both arrays so the output is deterministic.
+	var diff ArrayDiff
+	keys := make([]string, 0, len(a.data)+len(b.data))
+	for k := range a.data {
+		keys = append(keys, k)
+	}
+	for k := range b.data {
+		if _, found := a.data[k]; !found {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	// Classify each key.
+	for _, k := range keys {
+		av, inA := a.data[k]
+		bv, inB := b.data[k]
+		switch {
+		case !inA:
+			diff.Added = append(diff.Added, b.script.NewValue(k))
+		case !inB:
+			diff.Removed = append(diff.Removed, a.script.NewValue(k))
+		case !sameElement(av, bv):
+			diff.Changed = append(diff.Changed, ArrayChange{
+				Key: b.script.NewValue(k),
+				Old: av,
+				New: bv,
+			})
+		}
+	}
+	return diff
+}
+
+// An ArrayEntry is a single key/value pair from a ValueArray.
+type ArrayEntry struct {
+	Key   *Value // Index into the array
+	Value *Value // Value associated with the index
+}
+
+// buildIndex sorts the keys of a ValueArray either numerically or as strings
+// and caches the result until a key is added or deleted.
+func (va *ValueArray) buildIndex(numeric bool) *arrayIndex {
+	if va.index != nil && va.index.numeric == numeric {
+		return va.index
+	}
+	idx := &arrayIndex{
+		numeric: numeric,
+		keys:    make([]string, 0, len(va.data)),
+	}
+	for k := range va.data {
+		idx.keys = append(idx.keys, k)
+	}
+	if numeric {
+		nums := make(map[string]float64, len(idx.keys))
+		for _, k := range idx.keys {
+			nums[k] = va.script.NewValue(k).Float64()
+		}
+		sort.Slice(idx.keys, func(i, j int) bool {
+			ni, nj := nums[idx.keys[i]], nums[idx.keys[j]]
+			if ni != nj {
+				return ni < nj
+			}
+			return idx.keys[i] < idx.keys[j]
+		})
+		idx.nums = make([]float64, len(idx.keys))
+		for i, k := range idx.keys {
+			idx.nums[i] = nums[k]
+		}
+	} else {
+		sort.Strings(idx.keys)
+	}
+	va.index = idx
+	return idx
+}
+
+// GetRange returns, in sorted order, all entries in a ValueArray whose key
+// lies between loKey and hiKey, inclusive.  If numeric is true, keys are
+// compared as numbers; otherwise, they are compared as strings.  Keys can be
+// provided either as Values or as any types that can be converted to Values.
+// GetRange maintains a sorted index of the array's keys, which is rebuilt only
+// after keys are added or deleted.
+func (va *ValueArray) GetRange(loKey, hiKey interface{}, numeric bool) []ArrayEntry {
+	// Convert the endpoints to Values.
+	lo, ok := loKey.(*Value)
+	if !ok {
+		lo = va.script.NewValue(loKey)
+	}
+	hi, ok := hiKey.(*Value)
+	if !ok {
+		hi = va.script.NewValue(hiKey)
+	}
+
+	// Find the first and last matching positions in the index.
+	idx := va.buildIndex(numeric)
+	var first, last int
+	if numeric {
+		loF, hiF := lo.Float64(), hi.Float64()
+		first = sort.SearchFloat64s(idx.nums, loF)
+		last = sort.Search(len(idx.nums), func(i int) bool { return idx.nums[i] > hiF })
+	} else {
+		loS, hiS := lo.String(), hi.String()
+		first = sort.SearchStrings(idx.keys, loS)
+		last = sort.Search(len(idx.keys), func(i int) bool { return idx.keys[i] > hiS })
+	}
+
+	// Return the corresponding entries.
+	if first >= last {
+		return nil
+	}
+	ents := make([]ArrayEntry, 0, last-first)
+	for _, k := range idx.keys[first:last] {
+		ents = append(ents, ArrayEntry{
+			Key:   va.script.NewValue(k),
+			Value: va.data[k],
+		})
+	}
+	return ents
+}
+
+// key converts a list of indexes, provided either as Values or as any types
+// that can be converted to Values, to a single index string.
+func (va *ValueArray) key(args []interface{}) string {
+	idxStrs := make([]string, len(args))
+	for i, arg := range args {
+		v, ok := arg.(*Value)
+		if !ok {
+			v = va.script.NewValue(arg)
+		}
+		idxStrs[i] = v.String()
+	}
+	return strings.Join(idxStrs, va.subSep())
+}
+
+// SplitKey splits a simulated multidimensional index (see Set), provided
+// either as a Value or as any type that can be converted to a Value, into its
+// component indexes.  This is the analog of AWK's idiom
+// "for (k in arr) { split(k, parts, SUBSEP); ... }".  An index that does not
+// contain the subscript separator is returned as a single component.
+func (va *ValueArray) SplitKey(k interface{}) []*Value {
+	kv, ok := k.(*Value)
+	if !ok {
+		kv = va.script.NewValue(k)
+	}
+	parts := strings.Split(kv.String(), va.subSep())
+	vals := make([]*Value, len(parts))
+	for i, p := range parts {
+		vals[i] = va.script.NewValue(p)
 	}
 	return vals
 }
+
+// GetArray returns the subarray associated with a given index into a
+// ValueArray and true.  If the index doesn't appear in the array or is
+// associated with a scalar, GetArray returns nil and false.  Indexes are
+// specified as in Get.
+func (va *ValueArray) GetArray(args ...interface{}) (*ValueArray, bool) {
+	if len(args) < 1 {
+		panic("ValueArray.GetArray requires at least one index")
+	}
+	v, found := va.data[va.key(args)]
+	if !found {
+		return nil, false
+	}
+	return v.Array()
+}
+
+// SubArray returns the subarray associated with a given index into a
+// ValueArray, creating an empty subarray if the index doesn't appear in the
+// array.  This mimics GNU AWK's arrays of arrays, which, unlike simulated
+// multidimensional arrays, can hold elements at heterogeneous depths.
+// Indexes are specified as in Get.  SubArray panics if the index is
+// associated with a scalar.
+func (va *ValueArray) SubArray(args ...interface{}) *ValueArray {
+	// Ensure we were given at least one index.
+	if len(args) < 1 {
+		panic("ValueArray.SubArray requires at least one index")
+	}
+
+	// Return the existing subarray, if any.
+	idx := va.key(args)
+	if v, found := va.data[idx]; found {
+		sub, ok := v.Array()
+		if !ok {
+			panic("ValueArray.SubArray was applied to a scalar element")
+		}
+		return sub
+	}
+
+	// Create and store a new subarray.
+	sub := va.script.NewValueArray()
+	va.store(idx, va.script.NewValue(sub))
+	return sub
+}
+
+// Walk calls a function on each scalar element of a ValueArray, descending
+// recursively into subarrays.  The function is passed the sequence of indexes
+// leading to the element and the element itself.  Elements are visited in
+// order of their indexes, compared as strings.
+func (va *ValueArray) Walk(f func(keys []*Value, v *Value)) {
+	va.walk(nil, f)
+}
+
+// walk is the recursive helper function for Walk.
+func (va *ValueArray) walk(prefix []*Value, f func(keys []*Value, v *Value)) {
+	keys := make([]string, 0, len(va.data))
+	for k := range va.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		path := make([]*Value, len(prefix), len(prefix)+1)
+		copy(path, prefix)
+		path = append(path, va.script.NewValue(k))
+		v := va.data[k]
+		if v.aval != nil {
+			v.aval.walk(path, f)
+		} else {
+			f(path, v)
+		}
+	}
+}
+
+// String serializes a ValueArray, including all of its subarrays, as a
+// string of the form "[key:value key:[key:value ...] ...]", with keys sorted.
+func (va *ValueArray) String() string {
+	keys := make([]string, 0, len(va.data))
+	for k := range va.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(k)
+		sb.WriteByte(':')
+		v := va.data[k]
+		if v.aval != nil {
+			sb.WriteString(v.aval.String())
+		} else {
+			sb.WriteString(v.String())
+		}
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
@@ -3,6 +3,8 @@
 package awk
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -138,3 +140,265 @@ func TestArrayDelete(t *testing.T) {
 		t.Fatalf("Expected 0 but received %d", vsum)
 	}
 }
+
+// TestStandaloneArray tests associative arrays that are not associated with a
+// Script.
+func TestStandaloneArray(t *testing.T) {
+	a := NewValueArray()
+	a.Set("x", "y", 123)
+	if got := a.Get("x", "y").Int(); got != 123 {
+		t.Fatalf("Expected 123 but received %d", got)
+	}
+	if got := a.Get("x\034y").Int(); got != 123 {
+		t.Fatalf("Expected 123 but received %d", got)
+	}
+
+	// Bind the array to a script with a different subscript separator.
+	scr := NewScript()
+	scr.SubSep = ":"
+	a.Bind(scr)
+	a.Set("p", "q", 456)
+	if got := a.Get("p:q").Int(); got != 456 {
+		t.Fatalf("Expected 456 but received %d", got)
+	}
+	if got := a.Get("x", "y").Int(); got != 0 {
+		t.Fatalf("Expected 0 but received %d", got)
+	}
+}
+
+// TestBindSubArray tests that binding an array also binds its subarrays.
+func TestBindSubArray(t *testing.T) {
+	a := NewValueArray()
+	sub := a.SubArray("x")
+	sub.Set("k", 1)
+	deeper := sub.SubArray("y")
+	scr := NewScript()
+	scr.SubSep = ":"
+	a.Bind(scr)
+	sub.Set("p", "q", 2)
+	if got := sub.Get("p:q").Int(); got != 2 {
+		t.Fatalf("Expected 2 but received %d", got)
+	}
+	if sub.script != scr || deeper.script != scr || sub.Get("k").script != scr {
+		t.Fatal("Expected Bind to associate subarrays with the script")
+	}
+}
+
+// TestArrayDiff tests taking a snapshot of an associative array and comparing
+// it to a modified version of the array.
+func TestArrayDiff(t *testing.T) {
+	// Populate an array, take a snapshot, and modify the original.
+	scr := NewScript()
+	a := scr.NewValueArray()
+	for i := 1; i <= 5; i++ {
+		a.Set(i, i*i)
+	}
+	snap := a.Snapshot()
+	a.Delete(2)
+	a.Set(3, "nine")
+	a.Set(4, 16.0)
+	a.Set(6, 36)
+
+	// Ensure the snapshot was not affected.
+	if got := snap.Get(3).Int(); got != 9 {
+		t.Fatalf("Expected 9 but received %d", got)
+	}
+
+	// Compare the snapshot to the modified array.
+	diff := DiffArrays(snap, a)
+	if len(diff.Added) != 1 || diff.Added[0].Int() != 6 {
+		t.Fatalf("Expected added keys [6] but received %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Int() != 2 {
+		t.Fatalf("Expected removed keys [2] but received %v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 {
+		t.Fatalf("Expected 1 changed key but received %d", len(diff.Changed))
+	}
+	ch := diff.Changed[0]
+	if ch.Key.Int() != 3 || ch.Old.Int() != 9 || ch.New.String() != "nine" {
+		t.Fatalf("Expected {3, 9, nine} but received {%v, %v, %v}", ch.Key, ch.Old, ch.New)
+	}
+}
+
+// TestArrayGetRange tests range queries over numeric and string keys.
+func TestArrayGetRange(t *testing.T) {
+	// Bucket some timestamps into an array.
+	scr := NewScript()
+	a := scr.NewValueArray()
+	for _, ts := range []int{1000, 60, 3600, 7, 86400, 120, 999} {
+		a.Set(ts, ts*2)
+	}
+
+	// Query a numeric range.  Note that a string comparison would
+	// produce a different result.
+	ents := a.GetRange(60, 1000, true)
+	want := []int{60, 120, 999, 1000}
+	if len(ents) != len(want) {
+		t.Fatalf("Expected %d entries but received %d", len(want), len(ents))
+	}
+	for i, e := range ents {
+		if e.Key.Int() != want[i] || e.Value.Int() != want[i]*2 {
+			t.Fatalf("Expected {%d, %d} but received {%v, %v}", want[i], want[i]*2, e.Key, e.Value)
+		}
+	}
+
+	// Ensure the index is updated after a key is added.
+	a.Set(500, 1)
+	if n := len(a.GetRange(60, 1000, true)); n != 5 {
+		t.Fatalf("Expected 5 entries but received %d", n)
+	}
+
+	// Query a string range.
+	ents = a.GetRange("1", "3", false)
+	want = []int{1000, 120}
+	if len(ents) != len(want) {
+		t.Fatalf("Expected %d entries but received %d", len(want), len(ents))
+	}
+	for i, e := range ents {
+		if e.Key.Int() != want[i] {
+			t.Fatalf("Expected %d but received %v", want[i], e.Key)
+		}
+	}
+}
+
+// TestArrayDefault tests constructing default values for missing keys.
+func TestArrayDefault(t *testing.T) {
+	// Without insertion, missing keys should remain missing.
+	scr := NewScript()
+	a := scr.NewValueArray()
+	a.SetDefault(func() *Value { return scr.NewValue(-1) }, false)
+	if got := a.Get("x").Int(); got != -1 {
+		t.Fatalf("Expected -1 but received %d", got)
+	}
+	if n := len(a.Keys()); n != 0 {
+		t.Fatalf("Expected 0 keys but received %d", n)
+	}
+
+	// With insertion, missing keys should be created on reference.
+	a.SetDefault(func() *Value { return scr.NewValue(100) }, true)
+	for _, w := range []string{"a", "b", "a", "c", "a"} {
+		a.Set(w, a.Get(w).Int()+1)
+	}
+	if got := a.Get("a").Int(); got != 103 {
+		t.Fatalf("Expected 103 but received %d", got)
+	}
+	a.Get("d")
+	if n := len(a.Keys()); n != 4 {
+		t.Fatalf("Expected 4 keys but received %d", n)
+	}
+
+	// Restore the default behavior.
+	a.SetDefault(nil, true)
+	if got := a.Get("e").String(); got != "" {
+		t.Fatalf("Expected %q but received %q", "", got)
+	}
+	if n := len(a.Keys()); n != 4 {
+		t.Fatalf("Expected 4 keys but received %d", n)
+	}
+}
+
+// TestArrayOfArrays tests storing subarrays within an associative array.
+func TestArrayOfArrays(t *testing.T) {
+	// Create elements at heterogeneous depths.
+	scr := NewScript()
+	a := scr.NewValueArray()
+	a.Set("top", 1)
+	a.SubArray("mid").Set("x", 2)
+	a.SubArray("mid").SubArray("low").Set("y", 3)
+	a.SubArray("mid").Set("z", 4)
+
+	// Test the type-checked accessors.
+	if _, ok := a.GetArray("top"); ok {
+		t.Fatal("Scalar element was incorrectly reported as an array")
+	}
+	if _, ok := a.GetArray("nothing"); ok {
+		t.Fatal("Missing element was incorrectly reported as an array")
+	}
+	mid, ok := a.GetArray("mid")
+	if !ok {
+		t.Fatal("Failed to retrieve a subarray")
+	}
+	if got := mid.Get("z").Int(); got != 4 {
+		t.Fatalf("Expected 4 but received %d", got)
+	}
+	if !a.Get("mid").IsArray() || a.Get("top").IsArray() {
+		t.Fatal("IsArray returned an incorrect result")
+	}
+
+	// Test recursive iteration.
+	var paths []string
+	a.Walk(func(keys []*Value, v *Value) {
+		strs := make([]string, len(keys))
+		for i, k := range keys {
+			strs[i] = k.String()
+		}
+		paths = append(paths, strings.Join(strs, ".")+"="+v.String())
+	})
+	want := "mid.low.y=3 mid.x=2 mid.z=4 top=1"
+	if got := strings.Join(paths, " "); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// Test serialization.
+	want = "[mid:[low:[y:3] x:2 z:4] top:1]"
+	if got := a.String(); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// Ensure that snapshots copy subarrays.
+	snap := a.Snapshot()
+	a.SubArray("mid").Set("x", 5)
+	if got := snap.SubArray("mid").Get("x").Int(); got != 2 {
+		t.Fatalf("Expected 2 but received %d", got)
+	}
+
+	// Ensure that SubArray refuses to treat a scalar as an array.
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("SubArray failed to panic when applied to a scalar")
+		}
+	}()
+	a.SubArray("top")
+}
+
+// TestArrayExists tests membership tests that do not create elements.
+func TestArrayExists(t *testing.T) {
+	scr := NewScript()
+	a := scr.NewValueArray()
+	a.SetDefault(func() *Value { return scr.NewValue(0) }, true)
+	a.Set("x", 1)
+	a.Set("p", "q", "")
+	if !a.Exists("x") || !a.Exists("p", "q") {
+		t.Fatal("Exists failed to find existing indexes")
+	}
+	if a.Exists("y") || a.Exists("p") {
+		t.Fatal("Exists found nonexistent indexes")
+	}
+	if v, ok := a.GetOK("p", "q"); !ok || v.String() != "" {
+		t.Fatalf("Expected an empty element but received %q and %v", v, ok)
+	}
+	if v, ok := a.GetOK("z"); ok || v.String() != "" {
+		t.Fatalf("Expected a zero value but received %q and %v", v, ok)
+	}
+	if n := len(a.Keys()); n != 2 {
+		t.Fatalf("Expected 2 elements but received %d", n)
+	}
+}
+
+// TestArraySplitKey tests decoding simulated multidimensional indexes.
+func TestArraySplitKey(t *testing.T) {
+	scr := NewScript()
+	scr.SubSep = ":"
+	a := scr.NewValueArray()
+	a.Set("x", 2, "y", 10)
+	a.Set("z", 20)
+	var got []string
+	for _, k := range a.SortedKeys(nil) {
+		parts := a.SplitKey(k)
+		got = append(got, fmt.Sprintf("%d[%s]", len(parts), joinValues(parts)))
+	}
+	if s, want := strings.Join(got, " "), "3[x 2 y] 1[z]"; s != want {
+		t.Fatalf("Expected %q but received %q", want, s)
+	}
+}
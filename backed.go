@@ -0,0 +1,469 @@
+// This file defines a variant of ValueArray whose contents are held in
+// external storage rather than in memory, for aggregations over key spaces
+// too large to fit in memory.
+
+package awk
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// An ArrayBackend stores the elements of a BackedValueArray as encoded
+// key/value pairs.  Implementations can wrap an embedded key/value database
+// such as bbolt or Badger.  A BackedValueArray calls an ArrayBackend's
+// methods from only one goroutine at a time.
+type ArrayBackend interface {
+	// Load returns the value stored under a key and true, or false if
+	// the key is not present.
+	Load(key string) ([]byte, bool, error)
+
+	// Store associates a value with a key, replacing any existing
+	// value.
+	Store(key string, val []byte) error
+
+	// Delete removes a key.  Deleting a nonexistent key is not an
+	// error.
+	Delete(key string) error
+
+	// Range calls a function on each key/value pair in an unspecified
+	// order, stopping if the function returns false.
+	Range(f func(key string, val []byte) bool) error
+
+	// Close releases all resources associated with the ArrayBackend.
+	Close() error
+}
+
+// BackedOptions specifies how a BackedValueArray stores its elements.
+type BackedOptions struct {
+	Backend  ArrayBackend // Storage to use (nil for a temporary spill store)
+	Dir      string       // Directory in which to create the spill store ("" for the system default)
+	Buckets  int          // Number of files among which the spill store partitions keys (0 for 256)
+	Resident int          // Maximum number of the spill store's files held in memory (0 for 16)
+}
+
+// A BackedValueArray is an associative array of Values, like a ValueArray,
+// whose elements are kept in an ArrayBackend rather than in memory.  Only
+// scalars can be stored; subarrays are not supported.  Because the backend can
+// fail, a BackedValueArray records the first error it encounters, after which
+// all operations are no-ops; the error can be retrieved with Err.
+type BackedValueArray struct {
+	script *Script      // Pointer to the script that produced this array (nil for a standalone array)
+	store  ArrayBackend // Where elements are stored
+	err    error        // First error encountered
+}
+
+// NewValueArrayBacked creates and returns a standalone BackedValueArray, not
+// associated with any Script.
+func NewValueArrayBacked(opts BackedOptions) (*BackedValueArray, error) {
+	var s *Script
+	return s.NewValueArrayBacked(opts)
+}
+
+// NewValueArrayBacked creates and returns a BackedValueArray.  If opts.Backend
+// is nil, the elements are kept in a spill store: a temporary directory of
+// files among which keys are partitioned by hash value, of which only the
+// opts.Resident most recently used are held in memory.  Memory use is thus
+// roughly Resident/Buckets times that of an equivalent ValueArray, so the
+// number of buckets should grow with the expected number of keys.  The spill
+// store is deleted when the BackedValueArray is closed.
+func (s *Script) NewValueArrayBacked(opts BackedOptions) (*BackedValueArray, error) {
+	store := opts.Backend
+	if store == nil {
+		var err error
+		store, err = newSpillStore(opts.Dir, opts.Buckets, opts.Resident)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &BackedValueArray{script: s, store: store}, nil
+}
+
+// Err returns the first error encountered by a BackedValueArray's backend, if
+// any.
+func (ba *BackedValueArray) Err() error {
+	return ba.err
+}
+
+// Close closes a BackedValueArray's backend, deleting the spill store, if
+// any.  It returns the first error the BackedValueArray encountered, if any.
+func (ba *BackedValueArray) Close() error {
+	if err := ba.store.Close(); ba.err == nil {
+		ba.err = err
+	}
+	return ba.err
+}
+
+// key converts a list of arguments to an index string, as with ValueArray.
+func (ba *BackedValueArray) key(args []interface{}) string {
+	sep := "\034"
+	if ba.script != nil {
+		sep = ba.script.SubSep
+	}
+	strs := make([]string, len(args))
+	for i, a := range args {
+		v, ok := a.(*Value)
+		if !ok {
+			v = ba.script.NewValue(a)
+		}
+		strs[i] = v.String()
+	}
+	return strings.Join(strs, sep)
+}
+
+// encodeValue encodes a scalar Value as a type byte followed by its text.
+// Arbitrary-precision floating-point numbers additionally record their
+// precision.
+func encodeValue(v *Value) ([]byte, error) {
+	switch {
+	case v.aval != nil:
+		return nil, errors.New("A BackedValueArray cannot store a subarray")
+	case v.bint != nil:
+		return []byte("I" + v.bint.String()), nil
+	case v.bflt != nil:
+		prec := strconv.FormatUint(uint64(v.bflt.Prec()), 10)
+		return []byte("F" + prec + ":" + v.bflt.Text('g', -1)), nil
+	case v.number && v.ivalOk && (!v.fvalOk || v.fval == float64(v.ival)):
+		return []byte("i" + strconv.Itoa(v.ival)), nil
+	case v.number:
+		return []byte("f" + strconv.FormatFloat(v.Float64(), 'g', -1, 64)), nil
+	case v.strnum:
+		return []byte("n" + v.String()), nil
+	}
+	return []byte("s" + v.String()), nil
+}
+
+// decodeValue decodes a Value encoded by encodeValue and associates it with a
+// Script.
+func (s *Script) decodeValue(b []byte) (*Value, error) {
+	if len(b) == 0 {
+		return nil, errors.New("An encoded Value is corrupt")
+	}
+	str := string(b[1:])
+	switch b[0] {
+	case 'i':
+		i, err := strconv.Atoi(str)
+		return s.NewValue(i), err
+	case 'f':
+		f, err := strconv.ParseFloat(str, 64)
+		return s.NewValue(f), err
+	case 'I':
+		bi, ok := new(big.Int).SetString(str, 10)
+		if !ok {
+			return nil, errors.New("An encoded Value is corrupt")
+		}
+		return s.NewValue(bi), nil
+	case 'F':
+		strs := strings.SplitN(str, ":", 2)
+		prec, err := strconv.ParseUint(strs[0], 10, 32)
+		if err != nil || len(strs) < 2 {
+			return nil, errors.New("An encoded Value is corrupt")
+		}
+		bf, ok := new(big.Float).SetPrec(uint(prec)).SetString(strs[1])
+		if !ok {
+			return nil, errors.New("An encoded Value is corrupt")
+		}
+		return s.NewValue(bf), nil
+	case 'n':
+		return s.newInputValue(str), nil
+	case 's':
+		return s.NewValue(str), nil
+	}
+	return nil, errors.New("An encoded Value is corrupt")
+}
+
+// fail records an error if no error has yet been recorded.
+func (ba *BackedValueArray) fail(err error) {
+	if ba.err == nil {
+		ba.err = err
+	}
+}
+
+// Set (index, value) assigns a Value to an index of a BackedValueArray, as
+// with ValueArray.Set.
+func (ba *BackedValueArray) Set(args ...interface{}) {
+	if len(args) < 2 {
+		panic("BackedValueArray.Set requires at least one index and one value")
+	}
+	if ba.err != nil {
+		return
+	}
+	v, ok := args[len(args)-1].(*Value)
+	if !ok {
+		v = ba.script.NewValue(args[len(args)-1])
+	}
+	b, err := encodeValue(v)
+	if err != nil {
+		ba.fail(err)
+		return
+	}
+	ba.fail(ba.store.Store(ba.key(args[:len(args)-1]), b))
+}
+
+// GetOK returns the Value associated with a given index into a
+// BackedValueArray and true, or an empty Value and false if the index is
+// not present.  Indexes are specified as in ValueArray.Get.
+func (ba *BackedValueArray) GetOK(args ...interface{}) (*Value, bool) {
+	if len(args) == 0 {
+		panic("BackedValueArray.GetOK requires at least one index")
+	}
+	if ba.err != nil {
+		return ba.script.NewValue(""), false
+	}
+	b, ok, err := ba.store.Load(ba.key(args))
+	if err != nil || !ok {
+		ba.fail(err)
+		return ba.script.NewValue(""), false
+	}
+	v, err := ba.script.decodeValue(b)
+	if err != nil {
+		ba.fail(err)
+		return ba.script.NewValue(""), false
+	}
+	return v, true
+}
+
+// Get returns the Value associated with a given index into a
+// BackedValueArray, or an empty Value if the index is not present.  Unlike
+// ValueArray.Get, Get returns a copy; modifying the Value does not modify
+// the array.  Hence, a counter is incremented with
+//
+//	ba.Set(key, ba.Get(key).Add(1))
+func (ba *BackedValueArray) Get(args ...interface{}) *Value {
+	if len(args) == 0 {
+		panic("BackedValueArray.Get requires at least one index")
+	}
+	v, _ := ba.GetOK(args...)
+	return v
+}
+
+// Exists says whether a given index appears in a BackedValueArray.
+func (ba *BackedValueArray) Exists(args ...interface{}) bool {
+	if len(args) == 0 {
+		panic("BackedValueArray.Exists requires at least one index")
+	}
+	if ba.err != nil {
+		return false
+	}
+	_, ok, err := ba.store.Load(ba.key(args))
+	ba.fail(err)
+	return ok
+}
+
+// Delete deletes a key and associated value from a BackedValueArray.
+// Indexes are specified as in Get.
+func (ba *BackedValueArray) Delete(args ...interface{}) {
+	if len(args) == 0 {
+		panic("BackedValueArray.Delete requires at least one index")
+	}
+	if ba.err != nil {
+		return
+	}
+	ba.fail(ba.store.Delete(ba.key(args)))
+}
+
+// Range calls a function on each key and value in a BackedValueArray, in
+// unspecified order, stopping if the function returns false.  The function
+// must not modify the array.
+func (ba *BackedValueArray) Range(f func(k, v *Value) bool) {
+	if ba.err != nil {
+		return
+	}
+	ba.fail(ba.store.Range(func(key string, b []byte) bool {
+		v, err := ba.script.decodeValue(b)
+		if err != nil {
+			ba.fail(err)
+			return false
+		}
+		return f(ba.script.NewValue(key), v)
+	}))
+}
+
+// Len returns the number of elements in a BackedValueArray, which may require
+// reading the entire backend.
+func (ba *BackedValueArray) Len() int {
+	n := 0
+	ba.Range(func(k, v *Value) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// A spillBucket is one of the files that make up a spillStore.
+type spillBucket struct {
+	data  map[string][]byte // Contents of the bucket (nil if not resident)
+	dirty bool              // true: data differs from the file; false: it doesn't
+	used  int               // Time of most recent use
+}
+
+// A spillStore is an ArrayBackend that partitions keys among files by hash
+// value and keeps only the most recently used files in memory.
+type spillStore struct {
+	dir      string         // Directory holding the files
+	buckets  []*spillBucket // All buckets
+	resident int            // Maximum number of buckets to keep in memory
+	inMem    int            // Number of buckets currently in memory
+	clock    int            // Counter for recording use
+}
+
+// newSpillStore creates a spillStore in a new temporary directory.
+func newSpillStore(dir string, nBuckets, resident int) (*spillStore, error) {
+	if nBuckets <= 0 {
+		nBuckets = 256
+	}
+	if resident <= 0 {
+		resident = 16
+	}
+	tmp, err := ioutil.TempDir(dir, "awk-array-")
+	if err != nil {
+		return nil, err
+	}
+	ss := &spillStore{
+		dir:      tmp,
+		buckets:  make([]*spillBucket, nBuckets),
+		resident: resident,
+	}
+	for i := range ss.buckets {
+		ss.buckets[i] = &spillBucket{}
+	}
+	return ss, nil
+}
+
+// fileName returns the name of the file that holds a given bucket.
+func (ss *spillStore) fileName(i int) string {
+	return filepath.Join(ss.dir, fmt.Sprintf("bucket-%05d", i))
+}
+
+// evict writes the least recently used resident bucket to disk, if dirty,
+// and removes it from memory.
+func (ss *spillStore) evict() error {
+	lru := -1
+	for i, b := range ss.buckets {
+		if b.data != nil && (lru < 0 || b.used < ss.buckets[lru].used) {
+			lru = i
+		}
+	}
+	b := ss.buckets[lru]
+	if b.dirty {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(b.data); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(ss.fileName(lru), buf.Bytes(), 0600); err != nil {
+			return err
+		}
+	}
+	b.data = nil
+	b.dirty = false
+	ss.inMem--
+	return nil
+}
+
+// bucket returns a resident bucket by number, reading it from disk if
+// necessary.
+func (ss *spillStore) bucket(i int) (*spillBucket, error) {
+	b := ss.buckets[i]
+	ss.clock++
+	b.used = ss.clock
+	if b.data != nil {
+		return b, nil
+	}
+	if ss.inMem >= ss.resident {
+		if err := ss.evict(); err != nil {
+			return nil, err
+		}
+	}
+	b.data = make(map[string][]byte)
+	f, err := os.Open(ss.fileName(i))
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		b.data = nil
+		return nil, err
+	default:
+		err = gob.NewDecoder(f).Decode(&b.data)
+		f.Close()
+		if err != nil {
+			b.data = nil
+			return nil, err
+		}
+	}
+	ss.inMem++
+	return b, nil
+}
+
+// bucketFor returns the resident bucket that holds a given key.
+func (ss *spillStore) bucketFor(key string) (*spillBucket, error) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return ss.bucket(int(h.Sum32() % uint32(len(ss.buckets))))
+}
+
+// Load returns the value stored under a key.
+func (ss *spillStore) Load(key string) ([]byte, bool, error) {
+	b, err := ss.bucketFor(key)
+	if err != nil {
+		return nil, false, err
+	}
+	val, ok := b.data[key]
+	return val, ok, nil
+}
+
+// Store associates a value with a key.
+func (ss *spillStore) Store(key string, val []byte) error {
+	b, err := ss.bucketFor(key)
+	if err != nil {
+		return err
+	}
+	b.data[key] = val
+	b.dirty = true
+	return nil
+}
+
+// Delete removes a key.
+func (ss *spillStore) Delete(key string) error {
+	b, err := ss.bucketFor(key)
+	if err != nil {
+		return err
+	}
+	if _, ok := b.data[key]; ok {
+		delete(b.data, key)
+		b.dirty = true
+	}
+	return nil
+}
+
+// Range calls a function on each key/value pair, one bucket at a time.
+func (ss *spillStore) Range(f func(key string, val []byte) bool) error {
+	for i := range ss.buckets {
+		b, err := ss.bucket(i)
+		if err != nil {
+			return err
+		}
+		for k, v := range b.data {
+			if !f(k, v) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Close deletes the spill store's directory.
+func (ss *spillStore) Close() error {
+	for _, b := range ss.buckets {
+		b.data = nil
+	}
+	ss.inMem = 0
+	return os.RemoveAll(ss.dir)
+}
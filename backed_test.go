@@ -0,0 +1,126 @@
+// This file tests ValueArrays backed by external storage.
+
+package awk
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"testing"
+)
+
+// TestBackedValueArray tests storing elements in a spill store that holds
+// only a few buckets in memory.
+func TestBackedValueArray(t *testing.T) {
+	scr := NewScript()
+	ba, err := scr.NewValueArrayBacked(BackedOptions{Buckets: 8, Resident: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := ba.store.(*spillStore).dir
+
+	// Count keys repeatedly so that buckets are evicted and reloaded.
+	for i := 0; i < 3000; i++ {
+		k := fmt.Sprintf("key%d", i%500)
+		ba.Set(k, ba.Get(k).Add(1))
+	}
+	ba.Set("pi", 3.25)
+	ba.Set("name", "x", "y")
+	ba.Set("str", scr.newInputValue("007"))
+	if got := ba.Len(); got != 503 {
+		t.Fatalf("Expected 503 elements but received %d", got)
+	}
+	for i := 0; i < 500; i++ {
+		if got := ba.Get(fmt.Sprintf("key%d", i)).Int(); got != 6 {
+			t.Fatalf("Expected 6 for key%d but received %d", i, got)
+		}
+	}
+	if got := ba.Get("pi").Float64(); got != 3.25 {
+		t.Fatalf("Expected 3.25 but received %v", got)
+	}
+	if got := ba.Get("name", "x").String(); got != "y" {
+		t.Fatalf("Expected %q but received %q", "y", got)
+	}
+	if v := ba.Get("str"); v.String() != "007" || !v.IsStrNum() {
+		t.Fatalf("Expected strnum %q but received %q", "007", v)
+	}
+
+	// Deletion should work and be reflected by Range.
+	ba.Delete("key7")
+	if ba.Exists("key7") || !ba.Exists("key8") {
+		t.Fatal("Expected key7 to be deleted and key8 to remain")
+	}
+	sum := 0
+	ba.Range(func(k, v *Value) bool {
+		if k.String() != "pi" && k.String() != "str" {
+			sum += v.Int()
+		}
+		return true
+	})
+	if sum != 499*6 {
+		t.Fatalf("Expected a sum of %d but received %d", 499*6, sum)
+	}
+
+	// Arbitrary-precision numbers should retain their precision.
+	bi, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	bf, _ := new(big.Float).SetPrec(200).SetString("1.0000000000000000000000000001")
+	ba.Set("bigint", bi)
+	ba.Set("bigfloat", bf)
+	if got := ba.Get("bigint").BigInt(); got == nil || got.Cmp(bi) != 0 {
+		t.Fatalf("Expected %v but received %v", bi, got)
+	}
+	if got := ba.Get("bigfloat").BigFloat(); got == nil || got.Cmp(bf) != 0 || got.Prec() != 200 {
+		t.Fatalf("Expected %v but received %v", bf, got)
+	}
+
+	// Subarrays should be rejected.
+	ba.Set("sub", scr.NewValueArray())
+	if ba.Err() == nil {
+		t.Fatal("Expected an error for storing a subarray")
+	}
+
+	// Closing should remove the spill store.
+	ba.Close()
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Fatalf("Expected %s to be deleted", tmp)
+	}
+}
+
+// mapBackend is an ArrayBackend that stores data in a map.
+type mapBackend map[string][]byte
+
+func (mb mapBackend) Load(k string) ([]byte, bool, error) { v, ok := mb[k]; return v, ok, nil }
+func (mb mapBackend) Store(k string, v []byte) error      { mb[k] = v; return nil }
+func (mb mapBackend) Delete(k string) error               { delete(mb, k); return nil }
+func (mb mapBackend) Close() error                        { return nil }
+func (mb mapBackend) Range(f func(string, []byte) bool) error {
+	for k, v := range mb {
+		if !f(k, v) {
+			break
+		}
+	}
+	return nil
+}
+
+// TestArrayBackend tests using a user-provided ArrayBackend.
+func TestArrayBackend(t *testing.T) {
+	mb := make(mapBackend)
+	ba, err := NewValueArrayBacked(BackedOptions{Backend: mb})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ba.Set("a", 1)
+	ba.Set("b", "two")
+	if got := string(mb["b"]); got != "stwo" {
+		t.Fatalf("Expected %q but received %q", "stwo", got)
+	}
+	if got := ba.Get("a").Add(ba.Get("a")).Int(); got != 2 {
+		t.Fatalf("Expected 2 but received %d", got)
+	}
+	if v, ok := ba.GetOK("c"); ok || v.String() != "" {
+		t.Fatalf("Expected a missing element but received %q", v)
+	}
+	if err := ba.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,189 @@
+// This file implements arbitrary-precision arithmetic on Values.
+
+package awk
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// defaultBigPrec is the precision, in bits, of non-integral
+// arbitrary-precision numbers in Values not associated with a script that
+// called BigNumbers.
+const defaultBigPrec = 128
+
+// BigNumbers specifies whether Value arithmetic (Add, Subtract, Mul, Div,
+// Mod, Pow) should be performed with arbitrary precision, like gawk -M.  A
+// nonzero argument enables arbitrary-precision mode, in which integers are
+// exact no matter how large they grow and non-integral numbers carry prec
+// bits of mantissa.  This prevents, for example, silent loss of precision when
+// summing 64-bit identifiers.  BigNumbers(0), the default, disables
+// arbitrary-precision mode, although arithmetic on Values created from a
+// *big.Int or *big.Float is always performed with arbitrary precision.
+func (s *Script) BigNumbers(prec uint) {
+	s.bigPrec = prec
+}
+
+// bigPrecision returns the precision to use for a Value's non-integral
+// arbitrary-precision numbers.
+func (v *Value) bigPrecision() uint {
+	if v.script != nil && v.script.bigPrec > 0 {
+		return v.script.bigPrec
+	}
+	return defaultBigPrec
+}
+
+// isBig says whether a Value was created from an arbitrary-precision number.
+func (v *Value) isBig() bool {
+	return v.bint != nil || v.bflt != nil
+}
+
+// bigMode says whether arithmetic involving a Value and another Value should
+// be performed with arbitrary precision.
+func (v *Value) bigMode(v2 *Value) bool {
+	return (v.script != nil && v.script.bigPrec > 0) || v.isBig() || v2.isBig()
+}
+
+// bigNumber returns a Value as an arbitrary-precision integer if it is
+// integral or as an arbitrary-precision floating-point number otherwise.
+// Strings are converted from their leading number, as in Float64.  Both
+// return values are nil if the Value is NaN.
+func (v *Value) bigNumber() (*big.Int, *big.Float) {
+	prec := v.bigPrecision()
+	var bf *big.Float
+	switch {
+	case v.bint != nil:
+		return v.bint, nil
+	case v.bflt != nil:
+		bf = v.bflt
+	case v.number || !v.svalOk:
+		if v.ivalOk && (!v.fvalOk || float64(v.ival) == v.fval) {
+			return big.NewInt(int64(v.ival)), nil
+		}
+		f := v.Float64()
+		if f != f {
+			return nil, nil
+		}
+		bf = new(big.Float).SetPrec(prec).SetFloat64(f)
+	default:
+		strs := matchFloat.FindStringSubmatch(v.sval)
+		if len(strs) < 2 {
+			return new(big.Int), nil
+		}
+		if !strings.ContainsAny(strs[1], ".eE") {
+			bi, _ := new(big.Int).SetString(strs[1], 10)
+			return bi, nil
+		}
+		var err error
+		bf, _, err = big.ParseFloat(strs[1], 10, prec, big.ToNearestEven)
+		if err != nil {
+			return new(big.Int), nil
+		}
+	}
+	if bf.IsInt() {
+		bi, _ := bf.Int(nil)
+		return bi, nil
+	}
+	return nil, bf
+}
+
+// BigInt converts a Value to an arbitrary-precision integer, truncating any
+// fractional part.  Unlike Int, BigInt never overflows.  An infinity or NaN
+// converts to zero.
+func (v *Value) BigInt() *big.Int {
+	bi, bf := v.bigNumber()
+	switch {
+	case bi != nil:
+		return new(big.Int).Set(bi)
+	case bf != nil && !bf.IsInf():
+		bi, _ = bf.Int(nil)
+		return bi
+	}
+	return new(big.Int)
+}
+
+// BigFloat converts a Value to an arbitrary-precision floating-point number
+// with the precision specified by BigNumbers (or 128 bits if none was
+// specified).  Because a big.Float cannot represent NaN, BigFloat returns nil
+// for a NaN Value.
+func (v *Value) BigFloat() *big.Float {
+	bi, bf := v.bigNumber()
+	switch {
+	case bi != nil:
+		return new(big.Float).SetPrec(v.bigPrecision()).SetInt(bi)
+	case bf != nil:
+		return new(big.Float).Copy(bf)
+	}
+	return nil
+}
+
+// bigString formats a Value created from a *big.Float.  As in gawk -M,
+// integral values are output in full, and other values are formatted
+// according to ConvFmt.
+func (v *Value) bigString() string {
+	if v.bflt.IsInt() {
+		return v.bflt.Text('f', 0)
+	}
+	cf := convFmt
+	if v.script != nil {
+		cf = v.script.ConvFmt
+	}
+	return fmt.Sprintf(cf, v.bflt)
+}
+
+// bigArith applies an arbitrary-precision arithmetic operation to two Values.
+// If both Values are integral and intOp reports success, the result is an
+// integer.  Otherwise, if floatOp reports success, the result is a
+// floating-point number.  bigArith returns nil if neither operation succeeds
+// or if either operand is an infinity or NaN, in which case the caller should
+// fall back to float64 arithmetic.
+func (v *Value) bigArith(v2 *Value, intOp func(a, b *big.Int) (*big.Int, bool), floatOp func(a, b *big.Float, prec uint) (*big.Float, bool)) *Value {
+	// Convert both operands to arbitrary-precision numbers.
+	bi1, bf1 := v.bigNumber()
+	bi2, bf2 := v2.bigNumber()
+	if (bi1 == nil && bf1 == nil) || (bi2 == nil && bf2 == nil) {
+		return nil
+	}
+	if (bf1 != nil && bf1.IsInf()) || (bf2 != nil && bf2.IsInf()) {
+		return nil
+	}
+
+	// Try integer arithmetic then floating-point arithmetic.
+	if bi1 != nil && bi2 != nil {
+		if r, ok := intOp(bi1, bi2); ok {
+			return v.script.NewValue(r)
+		}
+	}
+	prec := v.bigPrecision()
+	if bi1 != nil {
+		bf1 = new(big.Float).SetPrec(prec).SetInt(bi1)
+	}
+	if bi2 != nil {
+		bf2 = new(big.Float).SetPrec(prec).SetInt(bi2)
+	}
+	if r, ok := floatOp(bf1, bf2, prec); ok {
+		return v.script.NewValue(r)
+	}
+	return nil
+}
+
+// bigCmp compares two Values numerically with arbitrary precision.  It
+// returns false if either Value is NaN.
+func (v *Value) bigCmp(v2 *Value) (int, bool) {
+	bi1, bf1 := v.bigNumber()
+	bi2, bf2 := v2.bigNumber()
+	if (bi1 == nil && bf1 == nil) || (bi2 == nil && bf2 == nil) {
+		return 0, false
+	}
+	if bi1 != nil && bi2 != nil {
+		return bi1.Cmp(bi2), true
+	}
+	if bi1 != nil {
+		bf1 = new(big.Float).SetInt(bi1)
+	}
+	if bi2 != nil {
+		bf2 = new(big.Float).SetInt(bi2)
+	}
+	return bf1.Cmp(bf2), true
+}
@@ -0,0 +1,80 @@
+// This file tests arbitrary-precision arithmetic.
+
+package awk
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// TestBigNumbersSum tests that summing 64-bit identifiers is exact in
+// arbitrary-precision mode.
+func TestBigNumbersSum(t *testing.T) {
+	scr := NewScript()
+	scr.BigNumbers(256)
+	scr.Begin = func(s *Script) { s.State = s.NewValue(0) }
+	scr.AppendStmt(nil, func(s *Script) { s.State = s.State.(*Value).Add(s.F(1)) })
+	input := "9223372036854775807\n9223372036854775807\n3\n"
+	if err := scr.Run(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	sum := scr.State.(*Value)
+	want := "18446744073709551617"
+	if sum.String() != want {
+		t.Fatalf("Expected %q but received %q", want, sum.String())
+	}
+	if sum.BigInt().String() != want {
+		t.Fatalf("Expected %q but received %q", want, sum.BigInt())
+	}
+	if sum.Cmp(scr.NewValue(want)) != 0 {
+		t.Fatalf("Expected %s to compare equal to %q", sum, want)
+	}
+}
+
+// TestBigNumbersOps tests the arithmetic operations in arbitrary-precision
+// mode.
+func TestBigNumbersOps(t *testing.T) {
+	scr := NewScript()
+	scr.BigNumbers(128)
+	two := scr.NewValue(2)
+	for _, c := range []struct {
+		got  *Value
+		want string
+	}{
+		{two.Pow(100), "1267650600228229401496703205376"},
+		{two.Pow(100).Subtract(1).Mod(1000), "375"},
+		{two.Pow(100).Div(two.Pow(98)), "4"},
+		{scr.NewValue(1).Div(3), "0.333333"},
+		{scr.NewValue("0.1").Add("0.2").Mul(10), "3"},
+		{scr.NewValue(7.5).Mod(2), "1.5"},
+		{scr.NewValue(1).Div(0), "+Inf"},
+		{scr.NewValue(big.NewInt(5)).Mul("4"), "20"},
+	} {
+		if c.got.String() != c.want {
+			t.Fatalf("Expected %q but received %q", c.want, c.got)
+		}
+	}
+}
+
+// TestBigAccessors tests BigInt and BigFloat on ordinary Values.
+func TestBigAccessors(t *testing.T) {
+	scr := NewScript()
+	if bi := scr.NewValue("123456789012345678901234567890xyz").BigInt(); bi.String() != "123456789012345678901234567890" {
+		t.Fatalf("Expected %q but received %q", "123456789012345678901234567890", bi)
+	}
+	if bi := scr.NewValue(-3.75).BigInt(); bi.Int64() != -3 {
+		t.Fatalf("Expected -3 but received %s", bi)
+	}
+	if bf := scr.NewValue("2.5").BigFloat(); bf.Text('g', 10) != "2.5" {
+		t.Fatalf("Expected %q but received %q", "2.5", bf.Text('g', 10))
+	}
+	if s := scr.NewValue(big.NewFloat(1.25)).String(); s != "1.25" {
+		t.Fatalf("Expected %q but received %q", "1.25", s)
+	}
+
+	// Without BigNumbers, ordinary arithmetic is unchanged.
+	if s := scr.NewValue(1).Div(3).String(); s != "0.333333" {
+		t.Fatalf("Expected %q but received %q", "0.333333", s)
+	}
+}
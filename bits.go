@@ -0,0 +1,74 @@
+// This file implements gawk's bit-manipulation functions on Values.
+
+package awk
+
+// maxComplBits is the number of bits that Compl complements.  As in gawk,
+// this is the number of bits in a float64's mantissa, so the result of
+// complementing a small number is exactly representable.
+const maxComplBits = 53
+
+// bits truncates a Value to an integer, as gawk does for its bit-manipulation
+// functions, and returns its two's-complement representation.
+func (v *Value) bits() uint64 {
+	return uint64(int64(v.truncInt()))
+}
+
+// bitsValue converts the result of a bit-manipulation function to a Value.
+// Results that do not fit in an int are represented as float64s.
+func (v *Value) bitsValue(u uint64) *Value {
+	if u > uint64(^uint(0)>>1) {
+		return v.script.NewValue(float64(u))
+	}
+	return v.script.NewValue(int(u))
+}
+
+// bitOp applies a bitwise operation to a Value and any number of other
+// Values or types that can be converted to Values.
+func (v *Value) bitOp(xs []interface{}, op func(a, b uint64) uint64) *Value {
+	r := v.bits()
+	for _, x := range xs {
+		r = op(r, v.operand(x).bits())
+	}
+	return v.bitsValue(r)
+}
+
+// And returns the bitwise AND of a Value and one or more other Values or
+// types that can be converted to Values, as with gawk's and function.  All
+// operands are first truncated to integers.
+func (v *Value) And(xs ...interface{}) *Value {
+	return v.bitOp(xs, func(a, b uint64) uint64 { return a & b })
+}
+
+// Or returns the bitwise OR of a Value and one or more other Values or types
+// that can be converted to Values, as with gawk's or function.  All operands
+// are first truncated to integers.
+func (v *Value) Or(xs ...interface{}) *Value {
+	return v.bitOp(xs, func(a, b uint64) uint64 { return a | b })
+}
+
+// Xor returns the bitwise exclusive OR of a Value and one or more other
+// Values or types that can be converted to Values, as with gawk's xor
+// function.  All operands are first truncated to integers.
+func (v *Value) Xor(xs ...interface{}) *Value {
+	return v.bitOp(xs, func(a, b uint64) uint64 { return a ^ b })
+}
+
+// Lshift returns a Value, truncated to an integer, shifted left by a given
+// number of bits, as with gawk's lshift function.
+func (v *Value) Lshift(n interface{}) *Value {
+	return v.bitOp([]interface{}{n}, func(a, b uint64) uint64 { return a << b })
+}
+
+// Rshift returns a Value, truncated to an integer, shifted right by a given
+// number of bits, as with gawk's rshift function.  The shift is logical: zeros
+// are shifted in from the left.
+func (v *Value) Rshift(n interface{}) *Value {
+	return v.bitOp([]interface{}{n}, func(a, b uint64) uint64 { return a >> b })
+}
+
+// Compl returns the bitwise complement of a Value, truncated to an integer,
+// as with gawk's compl function.  As in gawk, only the low-order 53 bits are
+// complemented, so, for example, the complement of 0 is 9007199254740991.
+func (v *Value) Compl() *Value {
+	return v.bitsValue(^v.bits() & (1<<maxComplBits - 1))
+}
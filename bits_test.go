@@ -0,0 +1,31 @@
+// This file tests the bit-manipulation functions.
+
+package awk
+
+import (
+	"testing"
+)
+
+// TestBits tests And, Or, Xor, Lshift, Rshift, and Compl.
+func TestBits(t *testing.T) {
+	scr := NewScript()
+	for _, c := range []struct {
+		got  *Value
+		want string
+	}{
+		{scr.NewValue(12).And(10), "8"},
+		{scr.NewValue(12).And(10, 8.9), "8"},
+		{scr.NewValue("12").Or("3"), "15"},
+		{scr.NewValue(12.7).Xor(10), "6"},
+		{scr.NewValue("1e1").And(15), "10"},
+		{scr.NewValue(1).Lshift(10), "1024"},
+		{scr.NewValue(1024).Rshift("3"), "128"},
+		{scr.NewValue(0).Compl(), "9007199254740991"},
+		{scr.NewValue(0x0f).Compl().And(0xff), "240"},
+		{scr.NewValue("flags").Or(0), "0"},
+	} {
+		if c.got.String() != c.want {
+			t.Fatalf("Expected %q but received %q", c.want, c.got)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// This file lets designated state survive from one run of a script to the
+// next.
+
+package awk
+
+// Carry registers an item (typically a *ValueArray or *Value holding
+// cumulative totals) under a given name so that it survives subsequent calls
+// to Run and RunReaders, and it can later be retrieved with Carried.  This
+// supports accumulating results across separate runs on different inputs,
+// such as the files of a directory processed one at a time:
+//
+//	s.Carry("totals", s.NewValueArray())
+//	for _, f := range files {
+//		// Each run's actions update s.Carried("totals").(*awk.ValueArray).
+//		s.Run(f)
+//	}
+//
+// Each run otherwise starts afresh: NR, FNR, NF, RT, RStart, RLength, and
+// ConvFmt, the current record, and all statistics gathered during the
+// previous run (e.g., RuleStats and Violations) are reinitialized.  The State
+// field is left untouched for backward compatibility, but carried items
+// make explicit which state is meant to persist.  Carrying a nil item
+// removes the name.  Clones and copies of a script (see Clone and Copy) share
+// its carried items, as they share State, but register items independently.
+func (s *Script) Carry(name string, item interface{}) {
+	if item == nil {
+		delete(s.carried, name)
+		return
+	}
+	if s.carried == nil {
+		s.carried = make(map[string]interface{})
+	}
+	s.carried[name] = item
+}
+
+// Carried returns the item registered under a given name by Carry, or nil if
+// no item is registered under that name.
+func (s *Script) Carried(name string) interface{} {
+	return s.carried[name]
+}
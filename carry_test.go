@@ -0,0 +1,53 @@
+// This file tests carrying state from one run to the next.
+
+package awk
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCarry tests that carried items survive across runs while per-run
+// state is reinitialized.
+func TestCarry(t *testing.T) {
+	scr := NewScript()
+	scr.Carry("totals", scr.NewValueArray())
+	scr.AppendStmt(nil, func(s *Script) {
+		totals := s.Carried("totals").(*ValueArray)
+		totals.Set(s.F(1), totals.Get(s.F(1)).Add(s.F(2)))
+		s.F(1).Match("b")
+	})
+	for _, in := range []string{"a 1\nb 2\n", "a 10\nc 5\n"} {
+		if err := scr.Run(strings.NewReader(in)); err != nil {
+			t.Fatal(err)
+		}
+		if scr.NR != 2 {
+			t.Fatalf("Expected NR=2 but received %d", scr.NR)
+		}
+	}
+	totals := scr.Carried("totals").(*ValueArray)
+	if got, want := keysAndValues(totals), "a=11 b=2 c=5 "; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// Per-run match state should not leak into the next run.
+	scr.Run(strings.NewReader("b 0\n"))
+	if scr.RStart != 1 {
+		t.Fatalf("Expected RStart=1 but received %d", scr.RStart)
+	}
+	scr.Run(strings.NewReader(""))
+	if scr.RStart != 0 || scr.RLength != 0 {
+		t.Fatalf("Expected RStart=0 and RLength=0 but received %d and %d", scr.RStart, scr.RLength)
+	}
+
+	// Copies should share items but not the registry.
+	cp := scr.Copy()
+	cp.Carry("extra", 1)
+	cp.Carry("totals", nil)
+	if scr.Carried("extra") != nil || scr.Carried("totals") == nil {
+		t.Fatal("Expected a copy's registrations not to affect the original")
+	}
+	if cp.Carried("totals") != nil || cp.Carried("extra") != 1 {
+		t.Fatal("Expected a copy's registrations to take effect")
+	}
+}
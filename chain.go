@@ -0,0 +1,54 @@
+// This file provides chainable alternatives to AppendStmt.
+
+package awk
+
+// A RuleBuilder holds the pattern of a rule under construction.  Call its Do
+// method to supply the action and append the rule to the script.
+type RuleBuilder struct {
+	script  *Script     // Script to which the rule will be appended
+	pattern PatternFunc // Pattern of the rule
+}
+
+// When begins a rule that applies to records matching a given pattern.  It
+// returns a RuleBuilder whose Do method completes the rule, as in
+// s.When(p).Do(a).  When panics if the pattern is nil; use Always for rules
+// that apply to every record.
+func (s *Script) When(p PatternFunc) *RuleBuilder {
+	if p == nil {
+		panic("Script.When requires a non-nil pattern")
+	}
+	return &RuleBuilder{script: s, pattern: p}
+}
+
+// Do appends a rule made from the RuleBuilder's pattern and a given action to
+// the script and returns the script to facilitate chaining.  Do panics if the
+// action is nil; use Script.Print for rules that output matching records.
+func (rb *RuleBuilder) Do(a ActionFunc) *Script {
+	if a == nil {
+		panic("RuleBuilder.Do requires a non-nil action")
+	}
+	rb.script.AppendStmt(rb.pattern, a)
+	return rb.script
+}
+
+// Always appends a rule that performs a given action on every record and
+// returns the script to facilitate chaining.  Always panics if the action is
+// nil.
+func (s *Script) Always(a ActionFunc) *Script {
+	if a == nil {
+		panic("Script.Always requires a non-nil action")
+	}
+	s.AppendStmt(nil, a)
+	return s
+}
+
+// Print appends a rule that outputs verbatim every record matching a given
+// pattern and returns the script to facilitate chaining.  Print panics if the
+// pattern is nil; use AppendStmt(nil, nil) to output every record.
+func (s *Script) Print(p PatternFunc) *Script {
+	if p == nil {
+		panic("Script.Print requires a non-nil pattern")
+	}
+	s.AppendStmt(p, nil)
+	return s
+}
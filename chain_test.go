@@ -0,0 +1,51 @@
+// This file tests the chainable rule constructors.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestChain tests that When, Do, Always, and Print append rules in order.
+func TestChain(t *testing.T) {
+	scr := NewScript()
+	var out bytes.Buffer
+	scr.Output = &out
+	scr.When(func(s *Script) bool { return s.F(1).Int() > 1 }).
+		Do(func(s *Script) { s.Println("big", s.F(1)) }).
+		Print(Auto("b")).
+		Always(func(s *Script) { s.Println("saw", s.NR) })
+	if err := scr.Run(strings.NewReader("1 a\n2 b\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := "saw 1\nbig 2\n2 b\nsaw 2\n"
+	if out.String() != want {
+		t.Fatalf("Expected %q but received %q", want, out.String())
+	}
+}
+
+// TestChainNil tests that the chainable rule constructors reject nil
+// arguments.
+func TestChainNil(t *testing.T) {
+	scr := NewScript()
+	for name, f := range map[string]func(){
+		"When":   func() { scr.When(nil) },
+		"Do":     func() { scr.When(Auto(1)).Do(nil) },
+		"Always": func() { scr.Always(nil) },
+		"Print":  func() { scr.Print(nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Expected %s(nil) to panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+	if len(scr.rules) != 0 {
+		t.Fatalf("Expected no rules but found %d", len(scr.rules))
+	}
+}
@@ -0,0 +1,125 @@
+// This file lets a script validate its input against declarative constraints.
+
+package awk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A check is a named constraint that every record is expected to satisfy.
+type check struct {
+	desc string      // Description of the constraint
+	cond PatternFunc // Function that returns true if the constraint is satisfied
+}
+
+// A Violation describes a record that failed to satisfy a constraint.
+type Violation struct {
+	Check  string // Description of the constraint that was violated
+	NR     int    // Number of the offending record
+	Record string // Contents of the offending record
+}
+
+// A ValidationReport summarizes the constraint violations encountered during
+// a run of a script.
+type ValidationReport struct {
+	Records    int         // Number of records checked
+	Violations []Violation // All violations in the order they were encountered
+}
+
+// OK says whether all records satisfied all constraints.
+func (vr *ValidationReport) OK() bool {
+	return len(vr.Violations) == 0
+}
+
+// String formats a ValidationReport with one line per violation.
+func (vr *ValidationReport) String() string {
+	var sb strings.Builder
+	for _, v := range vr.Violations {
+		fmt.Fprintf(&sb, "record %d: %s: %s\n", v.NR, v.Check, v.Record)
+	}
+	fmt.Fprintf(&sb, "%d violations in %d records\n", len(vr.Violations), vr.Records)
+	return sb.String()
+}
+
+// Check adds a constraint that every record is expected to satisfy.  The
+// constraint is evaluated on each record immediately after the record is split
+// into fields, before any pattern is evaluated.  Records that violate the
+// constraint are still processed but are noted in the script's
+// ValidationReport, identified by the given description.  It is invalid to
+// call Check from a running script.
+func (s *Script) Check(desc string, cond PatternFunc) {
+	if s.state != notRunning {
+		s.abortScript("Check was called from a running script")
+	}
+	s.checks = append(s.checks, check{desc: desc, cond: cond})
+}
+
+// ValidationReport returns the constraint violations encountered so far by
+// the current (or most recent) run of the script.
+func (s *Script) ValidationReport() *ValidationReport {
+	rpt := &ValidationReport{
+		Records:    s.validated,
+		Violations: make([]Violation, len(s.violations)),
+	}
+	copy(rpt.Violations, s.violations)
+	return rpt
+}
+
+// validate evaluates all constraints on the current record.
+func (s *Script) validate() {
+	if len(s.checks) == 0 {
+		return
+	}
+	s.validated++
+	for _, c := range s.checks {
+		if !c.cond(s) {
+			s.violations = append(s.violations, Violation{
+				Check:  c.desc,
+				NR:     s.NR,
+				Record: s.F(0).String(),
+			})
+		}
+	}
+}
+
+// CompareFields returns a PatternFunc that compares two fields of the current
+// record using a given operator, one of "<", "<=", "==", "!=", ">=", or ">".
+// The fields are compared numerically if both look like numbers and as
+// strings otherwise.  (Hence, dates in ISO 8601 format compare
+// chronologically.)  CompareFields panics if given an invalid operator.
+func CompareFields(i int, op string, j int) PatternFunc {
+	var test func(c int) bool
+	switch op {
+	case "<":
+		test = func(c int) bool { return c < 0 }
+	case "<=":
+		test = func(c int) bool { return c <= 0 }
+	case "==":
+		test = func(c int) bool { return c == 0 }
+	case "!=":
+		test = func(c int) bool { return c != 0 }
+	case ">=":
+		test = func(c int) bool { return c >= 0 }
+	case ">":
+		test = func(c int) bool { return c > 0 }
+	default:
+		panic(fmt.Sprintf("CompareFields does not accept operator %q", op))
+	}
+	return func(s *Script) bool {
+		fi, fj := s.F(i).String(), s.F(j).String()
+		ni, erri := strconv.ParseFloat(strings.TrimSpace(fi), 64)
+		nj, errj := strconv.ParseFloat(strings.TrimSpace(fj), 64)
+		switch {
+		case erri == nil && errj == nil && ni < nj:
+			return test(-1)
+		case erri == nil && errj == nil && ni > nj:
+			return test(1)
+		case erri == nil && errj == nil:
+			return test(0)
+		default:
+			return test(strings.Compare(fi, fj))
+		}
+	}
+}
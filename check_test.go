@@ -0,0 +1,74 @@
+// This file tests input validation.
+
+package awk
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCheck tests validating records against declarative constraints.
+func TestCheck(t *testing.T) {
+	input := `a,2020-01-01,2020-02-01,5
+b,2020-03-01,2020-02-01,7
+c,2020-01-01,2020-01-01,-1
+d,2020-05-01,2020-04-01,10
+`
+	scr := NewScript()
+	scr.Begin = func(s *Script) { s.SetFS(",") }
+	scr.Check("end date after start date", CompareFields(3, ">", 2))
+	scr.Check("nonnegative quantity", func(s *Script) bool { return s.F(4).Int() >= 0 })
+	processed := 0
+	scr.AppendStmt(nil, func(s *Script) { processed++ })
+	err := scr.Run(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate the report.
+	if processed != 4 {
+		t.Fatalf("Expected 4 records to be processed but received %d", processed)
+	}
+	rpt := scr.ValidationReport()
+	if rpt.OK() || rpt.Records != 4 {
+		t.Fatalf("Expected a failed report on 4 records but received %+v", rpt)
+	}
+	want := []Violation{
+		{"end date after start date", 2, "b,2020-03-01,2020-02-01,7"},
+		{"end date after start date", 3, "c,2020-01-01,2020-01-01,-1"},
+		{"nonnegative quantity", 3, "c,2020-01-01,2020-01-01,-1"},
+		{"end date after start date", 4, "d,2020-05-01,2020-04-01,10"},
+	}
+	if len(rpt.Violations) != len(want) {
+		t.Fatalf("Expected %v but received %v", want, rpt.Violations)
+	}
+	for i, v := range rpt.Violations {
+		if v != want[i] {
+			t.Fatalf("Expected %v but received %v", want[i], v)
+		}
+	}
+}
+
+// TestCompareFields tests comparing fields numerically and as strings.
+func TestCompareFields(t *testing.T) {
+	scr := NewScript()
+	scr.splitRecord("10 9 abc abd 10.0")
+	tests := []struct {
+		i    int    // First field
+		op   string // Comparison operator
+		j    int    // Second field
+		want bool   // Expected result
+	}{
+		{1, ">", 2, true},
+		{1, "==", 5, true},
+		{1, "!=", 5, false},
+		{3, "<", 4, true},
+		{3, ">=", 4, false},
+		{2, "<=", 1, true},
+	}
+	for _, tc := range tests {
+		if got := CompareFields(tc.i, tc.op, tc.j)(scr); got != tc.want {
+			t.Fatalf("Expected %v for $%d %s $%d but received %v", tc.want, tc.i, tc.op, tc.j, got)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// This file lets a script compare strings according to a collation order
+// other than that of their bytes.
+
+package awk
+
+import (
+	"sort"
+	"strings"
+)
+
+// SetCollator specifies a function that compares two strings, returning a
+// negative number, zero, or a positive number if the first string should sort
+// respectively before, equally with, or after the second.  The collator is
+// used consistently across the package: by Value.Cmp and Value.StrEqual when
+// comparing strings, by SortByIndexString, SortByValueString, and
+// SortByValue, and by ValueArrays with OrderSorted iteration.  This enables,
+// for example, locale-aware comparisons with
+//
+//	c := collate.New(language.German)
+//	s.SetCollator(c.CompareString)
+//
+// (using golang.org/x/text/collate) or natural ordering with
+//
+//	s.SetCollator(awk.NaturalCompare)
+//
+// SetCollator(nil), the default, compares strings byte by byte, as with
+// strings.Compare.
+func (s *Script) SetCollator(c func(a, b string) int) {
+	s.collator = c
+}
+
+// collate compares two strings using the script's collator, if any.
+func (s *Script) collate(a, b string) int {
+	if s == nil || s.collator == nil {
+		return strings.Compare(a, b)
+	}
+	return s.collator(a, b)
+}
+
+// equalStrings says whether two strings are equal, honoring the Value's case
+// sensitivity and its script's collator.
+func (v *Value) equalStrings(a, b string) bool {
+	if v.ignoreCase() {
+		if strings.EqualFold(a, b) {
+			return true
+		}
+		if v.script == nil || v.script.collator == nil {
+			return false
+		}
+		a, b = strings.ToLower(a), strings.ToLower(b)
+	}
+	if v.script == nil || v.script.collator == nil {
+		return a == b
+	}
+	return v.script.collator(a, b) == 0
+}
+
+// sortCollated sorts a list of strings using a script's collator, if any.
+// Strings the collator considers equal are sorted byte by byte so that the
+// order is deterministic.
+func (s *Script) sortCollated(strs []string) {
+	if s == nil || s.collator == nil {
+		sort.Strings(strs)
+		return
+	}
+	sort.Slice(strs, func(i, j int) bool {
+		if c := s.collator(strs[i], strs[j]); c != 0 {
+			return c < 0
+		}
+		return strs[i] < strs[j]
+	})
+}
@@ -0,0 +1,66 @@
+// This file tests pluggable string collation.
+
+package awk
+
+import (
+	"strings"
+	"testing"
+)
+
+// foldAccents compares strings while treating "é" as "e", as a simple
+// stand-in for a locale-aware collator.
+func foldAccents(a, b string) int {
+	r := strings.NewReplacer("é", "e")
+	return strings.Compare(r.Replace(a), r.Replace(b))
+}
+
+// TestSetCollator tests that a collator is used consistently for
+// comparisons, sorting, and ordered iteration.
+func TestSetCollator(t *testing.T) {
+	scr := NewScript()
+	e1, e2 := scr.NewValue("café"), scr.NewValue("cafe")
+	if e1.StrEqual(e2) || e1.Cmp(e2) == 0 {
+		t.Fatal("Expected byte-wise comparison without a collator")
+	}
+	scr.SetCollator(foldAccents)
+	if !e1.StrEqual(e2) || !e1.StrEqual("cafe") || e1.Cmp(e2) != 0 {
+		t.Fatal("Expected the collator to consider the strings equal")
+	}
+	if got := scr.NewValue("éclair").Cmp("dog"); got <= 0 {
+		t.Fatalf("Expected a positive comparison but received %d", got)
+	}
+	scr.IgnoreCase(true)
+	if !scr.NewValue("CAFÉ").StrEqual("cafe") {
+		t.Fatal("Expected a case-insensitive collated match")
+	}
+	scr.IgnoreCase(false)
+
+	// Sorting and ordered iteration should honor the collator.
+	va := scr.NewValueArray().SetOrder(OrderSorted)
+	for i, k := range []string{"fig", "éclair", "date", "eel"} {
+		va.Set(k, i)
+	}
+	want := "date éclair eel fig"
+	if got := joinValues(va.Keys()); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	if got := joinValues(va.SortedKeys(SortByIndexString)); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	vals := scr.NewValueArray()
+	for i, v := range []string{"fig", "éclair", "date", "eel"} {
+		vals.Set(i, v)
+	}
+	if got := joinValues(vals.SortedValues(SortByValueString)); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	if got := joinValues(vals.SortedValues(nil)); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// A nil collator should restore byte order.
+	scr.SetCollator(nil)
+	if got := joinValues(va.Keys()); got != "date eel fig éclair" {
+		t.Fatalf("Expected %q but received %q", "date eel fig éclair", got)
+	}
+}
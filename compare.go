@@ -0,0 +1,117 @@
+// This file implements AWK-style comparisons of Values.
+
+package awk
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// matchNumStr matches a string that consists entirely of a base-ten
+// floating-point number, optionally surrounded by whitespace.  As in gawk, an
+// infinity or NaN is recognized only when it includes an explicit sign.
+var matchNumStr = regexp.MustCompile(`^\s*(?:[-+]?(?:\d+(?:\.\d*)?|\.\d+)(?:[Ee][-+]?\d+)?|[-+](?i:inf|infinity|nan))\s*$`)
+
+// newInputValue creates a Value from a string read from the input, tagging it
+// as a numeric string if it looks like a number.
+func (s *Script) newInputValue(str string) *Value {
+	v := s.NewValue(str)
+	v.strnum = matchNumStr.MatchString(str)
+	return v
+}
+
+// IsStrNum says whether a Value is a "numeric string": a field, record, or
+// GetLine result whose text looks like a number.  As in AWK, numeric strings
+// compare numerically with numbers and other numeric strings in Cmp and, at
+// CompatPOSIX and above (see Script.SetCompat), in StrEqual.
+func (v *Value) IsStrNum() bool {
+	return v.strnum
+}
+
+// cmpNumber returns a Value as a number and says whether the Value should be
+// compared numerically: either it is a number or it is a numeric string (see
+// IsStrNum).  A string constant compares as a string even if it looks like a
+// number.
+func (v *Value) cmpNumber() (float64, bool) {
+	if !v.number && !v.strnum && v.svalOk {
+		return 0, false
+	}
+	return v.looksNumber()
+}
+
+// looksNumber returns a Value as a number and says whether the Value is
+// either a number or a string that looks like a number, regardless of whether
+// it is a numeric string.
+func (v *Value) looksNumber() (float64, bool) {
+	if v.number || !v.svalOk {
+		return v.Float64(), true
+	}
+	if !matchNumStr.MatchString(v.sval) {
+		return 0, false
+	}
+	f, _ := strconv.ParseFloat(strings.TrimSpace(v.sval), 64)
+	return f, true
+}
+
+// isInt says whether a Value was created from an integer.  The answer does
+// not depend on any conversions the Value has since undergone.
+func (v *Value) isInt() bool {
+	return v.number && !v.float && v.ivalOk
+}
+
+// Cmp compares a Value to another Value or any type that can be converted to
+// a Value and returns -1, 0, or +1 if the first is respectively less than,
+// equal to, or greater than the second.  Following POSIX AWK, the comparison
+// is numeric if both operands are numbers or numeric strings (see IsStrNum)
+// and is a string comparison otherwise.  Hence, the field " 10 " compares
+// equal to the number 10, but the string constants "2" and "10" compare as
+// strings.  If the associated script called IgnoreCase(true) or
+// IgnoreCaseStrings(true), string comparisons are performed in a
+// case-insensitive manner.  String comparisons use the script's collator, if
+// any (see SetCollator).  NaN compares equal to every number.
+func (v *Value) Cmp(v2 interface{}) int {
+	// Compare numerically if possible.
+	x := v.operand(v2)
+	f1, num1 := v.cmpNumber()
+	f2, num2 := x.cmpNumber()
+	if num1 && num2 {
+		if v.isBig() || x.isBig() {
+			if c, ok := v.bigCmp(x); ok {
+				return c
+			}
+		}
+		switch {
+		case v.isInt() && x.isInt():
+			// Compare integers exactly.
+			switch {
+			case v.ival < x.ival:
+				return -1
+			case v.ival > x.ival:
+				return 1
+			}
+			return 0
+		case f1 < f2:
+			return -1
+		case f1 > f2:
+			return 1
+		}
+		return 0
+	}
+
+	// Compare as strings.
+	s1, s2 := v.String(), x.String()
+	if v.ignoreCase() {
+		if strings.EqualFold(s1, s2) {
+			return 0
+		}
+		s1, s2 = strings.ToLower(s1), strings.ToLower(s2)
+	}
+	return v.script.collate(s1, s2)
+}
+
+// Less says whether a Value is less than another Value or any type that can
+// be converted to a Value, using the comparison rules described for Cmp.
+func (v *Value) Less(v2 interface{}) bool {
+	return v.Cmp(v2) < 0
+}
@@ -0,0 +1,168 @@
+// This file tests comparisons of Values.
+
+package awk
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCmp tests that Cmp follows AWK's rules for numeric and string
+// comparisons.
+func TestCmp(t *testing.T) {
+	scr := NewScript()
+	in := scr.newInputValue
+	for _, c := range []struct {
+		a, b interface{}
+		cmp  int
+	}{
+		{2, 10, -1},
+		{"2", "10", 1},
+		{in("2"), in("10"), -1},
+		{in(" 10 "), 10, 0},
+		{" 10 ", 10, -1},
+		{in("1e1"), in("10"), 0},
+		{"1e1", "10", 1},
+		{in("1e3"), 999, 1},
+		{2.5, in("2.50"), 0},
+		{2.5, "2.50", -1},
+		{in("2x"), in("10"), 1},
+		{"abc", "abd", -1},
+		{"abc", 5, 1},
+		{in("+inf"), 1e300, 1},
+		{in("inf"), 1e300, 1},
+		{"", 0, -1},
+	} {
+		a, b := scr.NewValue(c.a), scr.NewValue(c.b)
+		if cmp := a.Cmp(b); cmp != c.cmp {
+			t.Fatalf("Expected %v <=> %v to be %d but received %d", a, b, c.cmp, cmp)
+		}
+		if less := a.Less(c.b); less != (c.cmp < 0) {
+			t.Fatalf("Expected %v < %v to be %v but received %v", a, b, c.cmp < 0, less)
+		}
+	}
+
+	// Cached conversions must not change the outcome.
+	v := in("1e3")
+	v.Int()
+	if v.Cmp(999) != 1 {
+		t.Fatalf("Expected %q to be greater than 999 after conversion to int", "1e3")
+	}
+	f := scr.NewValue(1.5)
+	f.Int()
+	if f.Cmp(1) != 1 {
+		t.Fatalf("Expected 1.5 to be greater than 1 after conversion to int")
+	}
+
+	// Distinct integers beyond the precision of a float64 must compare
+	// exactly, even after conversion to a float64.
+	big1, big2 := scr.NewValue(1<<62), scr.NewValue(1<<62+1)
+	if big1.Cmp(big2) != -1 || big2.Cmp(big1) != 1 {
+		t.Fatalf("Expected %d to be less than %d", 1<<62, 1<<62+1)
+	}
+	big1.Float64()
+	_ = big2.String()
+	if big1.Cmp(big2) != -1 {
+		t.Fatalf("Expected %d to be less than %d after conversion", 1<<62, 1<<62+1)
+	}
+}
+
+// TestCmpIgnoreCase tests that Cmp honors IgnoreCase for string comparisons.
+func TestCmpIgnoreCase(t *testing.T) {
+	scr := NewScript()
+	a, b := scr.NewValue("apple"), scr.NewValue("BANANA")
+	if !b.Less(a) {
+		t.Fatalf("Expected %q < %q in a case-sensitive comparison", "BANANA", "apple")
+	}
+	scr.IgnoreCase(true)
+	if !a.Less(b) {
+		t.Fatalf("Expected %q < %q in a case-insensitive comparison", "apple", "BANANA")
+	}
+	if cmp := a.Cmp("APPLE"); cmp != 0 {
+		t.Fatalf("Expected %q and %q to compare equal but received %d", "apple", "APPLE", cmp)
+	}
+}
+
+// TestStrNum tests that numeric-looking fields are tagged as numeric strings
+// and compare numerically.
+func TestStrNum(t *testing.T) {
+	scr := NewScript()
+	scr.SetCompat(CompatPOSIX)
+	scr.SetFS(",")
+	scr.AppendStmt(nil, func(s *Script) {
+		if !s.F(1).IsStrNum() || !s.F(2).IsStrNum() || s.F(3).IsStrNum() {
+			t.Fatalf("Incorrect numeric-string tags for fields of %q", s.F(0))
+		}
+		if !s.F(1).StrEqual(10) {
+			t.Fatalf("Expected %q to equal 10", s.F(1))
+		}
+		if !s.F(1).StrEqual(s.F(2)) {
+			t.Fatalf("Expected %q to equal %q", s.F(1), s.F(2))
+		}
+		if s.F(1).StrEqual("10") {
+			t.Fatalf("Expected %q not to equal the string %q", s.F(1), "10")
+		}
+		if !s.NewValue(10).StrEqual(s.F(2)) {
+			t.Fatalf("Expected 10 to equal %q", s.F(2))
+		}
+		if s.F(3).StrEqual(10) {
+			t.Fatalf("Expected %q not to equal 10", s.F(3))
+		}
+		if !s.F(2).Less(s.F(4)) {
+			t.Fatalf("Expected %q to be less than %q", s.F(2), s.F(4))
+		}
+	})
+	if err := scr.Run(strings.NewReader(" 10 ,1e1,10x,9e1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if v := scr.NewValue(" 10 "); v.IsStrNum() || v.StrEqual(10) {
+		t.Fatalf("Expected a Go string not to be treated as a numeric string")
+	}
+
+	// At CompatLegacy, StrEqual compares numeric strings as strings.
+	scr = NewScript()
+	scr.SetFS(",")
+	scr.AppendStmt(nil, func(s *Script) {
+		if s.F(1).StrEqual(10) {
+			t.Fatalf("Expected %q not to equal 10 at CompatLegacy", s.F(1))
+		}
+		if !s.F(1).StrEqual(" 10 ") {
+			t.Fatalf("Expected %q to equal the string %q at CompatLegacy", s.F(1), " 10 ")
+		}
+		if s.F(1).Cmp(10) != 0 {
+			t.Fatalf("Expected %q to compare equal to 10", s.F(1))
+		}
+	})
+	if err := scr.Run(strings.NewReader(" 10 ,1e1\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCmpStrEqual tests that Cmp and StrEqual agree on equality for numbers,
+// numeric strings, and string constants.
+func TestCmpStrEqual(t *testing.T) {
+	scr := NewScript()
+	scr.SetCompat(CompatPOSIX)
+	in := scr.newInputValue
+	vals := []*Value{
+		scr.NewValue(10),
+		scr.NewValue(10.5),
+		scr.NewValue("10"),
+		scr.NewValue("10.0"),
+		scr.NewValue(" 10 "),
+		scr.NewValue("abc"),
+		in("10"),
+		in("10.0"),
+		in(" 10 "),
+		in("1e1"),
+		in("10.5"),
+		in("abc"),
+	}
+	for _, a := range vals {
+		for _, b := range vals {
+			if eq, cmp := a.StrEqual(b), a.Cmp(b); eq != (cmp == 0) {
+				t.Fatalf("Expected StrEqual(%q, %q) = %v to agree with Cmp = %d", a, b, eq, cmp)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// This file lets a script reassemble logical records that were wrapped
+// across multiple physical records.
+
+package awk
+
+import "io"
+
+// JoinContinuations specifies that a record with fewer than nf fields is
+// incomplete, having been wrapped across multiple lines (as by some export
+// tools when a row is long).  Before any rule is applied to such a record,
+// the next record is read and appended to it, separated by sep, and the
+// result is split again.  This repeats until the record has at least nf
+// fields, maxMerge records have been appended, or the input is exhausted; a
+// maxMerge of zero or less imposes no limit.  Use a sep of FS (e.g., " " or
+// ",") if rows were wrapped between fields and "" if they were wrapped in the
+// middle of a field.  Each appended record increments NR and FNR, as with
+// GetLine, and RT becomes that of the final record appended.  An nf of zero or
+// less, the default, disables joining.
+func (s *Script) JoinContinuations(nf, maxMerge int, sep string) {
+	s.joinNF = nf
+	s.joinMax = maxMerge
+	s.joinSep = sep
+}
+
+// joinContinuations appends records to the current record, which has already
+// been split, as specified by JoinContinuations.
+func (s *Script) joinContinuations(rec string) error {
+	prov, provKnown := s.prov, s.provKnown
+	defer func() {
+		s.prov, s.provKnown = prov, provKnown
+	}()
+	for n := 0; s.NF < s.joinNF && (s.joinMax <= 0 || n < s.joinMax); n++ {
+		next, err := s.readInput()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.consumeRecord()
+		rec += s.joinSep + next
+		if err := s.splitRecord(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// This file tests reassembling wrapped records.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestJoinContinuations tests joining records that have too few fields.
+func TestJoinContinuations(t *testing.T) {
+	tests := []struct {
+		sep   string // Separator between joined records
+		max   int    // Maximum number of records to append
+		input string // Input text
+		want  string // Expected output
+	}{
+		{",", 0, "1,a,b,c\n2,a\nb,c\n3,a\nb\nc\n4,x,y,z\n", "1 1 a|b|c\n2 3 a|b|c\n3 6 a|b|c\n4 7 x|y|z\n"},
+		{"", 0, "1,a,b,c\n2,a,lo\nng,c\n", "1 1 a|b|c\n2 3 a|long|c\n"},
+		{",", 1, "1,a\nb\nc,d\n", "1 2 a|b\nc 3 d\n"},
+		{",", 0, "1,a\nb\n", "1 2 a|b\n"},
+	}
+	for _, tc := range tests {
+		scr := NewScript()
+		var out bytes.Buffer
+		scr.Output = &out
+		scr.SetFS(",")
+		scr.JoinContinuations(4, tc.max, tc.sep)
+		scr.AppendStmt(nil, func(s *Script) {
+			s.Println(s.F(1), s.NR, strings.Join(s.FStrings()[1:], "|"))
+		})
+		if err := scr.Run(strings.NewReader(tc.input)); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.String(); got != tc.want {
+			t.Fatalf("Expected %q but received %q", tc.want, got)
+		}
+	}
+}
@@ -148,7 +148,7 @@ strings (implying single-character fields)
 
 • control over the number conversion format (CONVFMT)
 
-• automatic enumeration of records (NR) and fields (NR)
+• automatic enumeration of records (NR and FNR) and fields (NF)
 
 • "weak typing"
 
@@ -171,7 +171,8 @@ Examples
 
 A number of examples ported from the POSIX 1003.1 standard document
 (http://pubs.opengroup.org/onlinepubs/9699919799/utilities/awk.html) are
-presented below.
+presented below.  Each reads the sample data returned by NewScriptForExample
+and shows the output it produces.
 
 */
 package awk
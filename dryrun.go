@@ -0,0 +1,67 @@
+// This file lets a script report which rules would match a record without
+// running any actions.
+
+package awk
+
+import (
+	"io/ioutil"
+)
+
+// MatchRecord splits a given record and applies any field rewrites (see
+// Rewrite) as Run would and returns the indices (numbered from 0 in the order
+// the rules were appended) of the rules whose patterns match it.  The rules'
+// names, if any, can be found in the corresponding elements of RuleStats.
+// MatchRecord neither consumes input nor runs any actions, and it leaves the
+// script's current record, NR, and FNR unchanged, which makes it useful for
+// unit-testing rule sets and for diagnosing why a pattern does or does not
+// match.  Patterns are evaluated as if the record were the next one read, and
+// every pattern is evaluated, even those that follow a rule whose action
+// would call Next or Exit.  A pattern that aborts the script is considered
+// not to match.  MatchRecord returns nil if the record cannot be split into
+// fields.
+//
+// Patterns are invoked on a Clone of the script, so they should not depend
+// on state modified by actions other than the State field.  Note that
+// stateful patterns, such as those produced by Range or by Auto with two
+// arguments, advance their state as a result of being evaluated.
+func (s *Script) MatchRecord(line string) []int {
+	// Prepare a clone of the script as if it had just read the record.
+	sc := s.Clone()
+	sc.Output = ioutil.Discard
+	sc.NR = s.NR + 1
+	sc.FNR = s.FNR + 1
+	if err := sc.splitRecord(line); err != nil {
+		return nil
+	}
+	sc.state = inMiddle
+	sc.applyRewrites()
+
+	// Evaluate each pattern in turn.
+	matches := make([]int, 0, len(sc.rules))
+	for i, rule := range sc.rules {
+		if rule.guard != nil && !sc.dryMatch(rule.guard) {
+			continue
+		}
+		if sc.dryMatch(rule.Pattern) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// dryMatch applies a pattern to the current record, treating a pattern that
+// aborts the script or calls Next as not matching.
+func (s *Script) dryMatch(p PatternFunc) (match bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r.(type) {
+			case scriptAborter, recordStopper:
+				match = false
+			default:
+				panic(r)
+			}
+		}
+		s.stop = dontStop
+	}()
+	return p(s)
+}
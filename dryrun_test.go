@@ -0,0 +1,41 @@
+// This file tests matching records without running actions.
+
+package awk
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMatchRecord tests that MatchRecord reports the rules that would fire
+// without running their actions.
+func TestMatchRecord(t *testing.T) {
+	scr := NewScript()
+	ran := false
+	scr.AppendNamedStmt("big", func(s *Script) bool { return s.F(2).Int() > 10 }, func(s *Script) { ran = true })
+	scr.AppendNamedStmt("fruit", Auto("apple|banana"), func(s *Script) { ran = true })
+	scr.AppendNamedStmt("first", Auto(1), nil)
+	scr.AppendStmt(nil, func(s *Script) { ran = true })
+	for _, c := range []struct {
+		line string
+		want []int
+	}{
+		{"apple 20", []int{0, 1, 2, 3}},
+		{"cherry 5", []int{2, 3}},
+		{"banana 3 extra", []int{1, 2, 3}},
+	} {
+		got := scr.MatchRecord(c.line)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("Expected %v for %q but received %v", c.want, c.line, got)
+		}
+	}
+	if ran {
+		t.Fatalf("MatchRecord unexpectedly ran an action")
+	}
+	if scr.NR != 0 || scr.NF != 0 {
+		t.Fatalf("Expected MatchRecord to leave NR and NF at 0 but received %d and %d", scr.NR, scr.NF)
+	}
+	if name := scr.RuleStats()[1].Name; name != "fruit" {
+		t.Fatalf("Expected %q but received %q", "fruit", name)
+	}
+}
@@ -0,0 +1,104 @@
+// This file provides a helper for detecting duplicate keys in the input.
+
+package awk
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// A Duplicate describes a key that appears in more than one record.
+type Duplicate struct {
+	Key   string // Duplicated key
+	Count int    // Total number of records containing the key
+	NRs   []int  // Record numbers of the first few records containing the key
+}
+
+// A dupEntry records what a DupTracker knows about a single key.
+type dupEntry struct {
+	firstNR int        // Record number of the first occurrence
+	dup     *Duplicate // Details of a duplicated key (nil if seen only once)
+}
+
+// A DupTracker detects duplicate keys in a given column of the input.  To
+// bound memory usage, it retains only a 64-bit hash of each key that has been
+// seen exactly once; the key itself is retained only once it is duplicated.
+// (Consequently, two distinct keys with the same hash value are erroneously
+// reported as duplicates.)  A DupTracker accumulates keys across multiple runs
+// of a script until Reset is called.
+type DupTracker struct {
+	field   int                  // Column containing the key
+	maxNRs  int                  // Maximum number of record numbers to retain per key
+	entries map[uint64]*dupEntry // Map from a key's hash to what we know about the key
+	dups    []*Duplicate         // Duplicated keys in order of first duplication
+}
+
+// TrackDuplicates returns a DupTracker that observes the key in the given
+// field of every record that Run reads, before any pattern is evaluated.  For
+// each duplicated key, it retains the record numbers (NR) of at most maxNRs
+// occurrences.  Call the DupTracker's Duplicates method from the End action
+// (or after Run returns) to report the duplicates.
+func (s *Script) TrackDuplicates(field, maxNRs int) *DupTracker {
+	if field < 0 {
+		s.abortScript("TrackDuplicates was passed an invalid field number (%d)", field)
+	}
+	dt := &DupTracker{
+		field:   field,
+		maxNRs:  maxNRs,
+		entries: make(map[uint64]*dupEntry),
+	}
+	s.dupTrackers = append(s.dupTrackers, dt)
+	return dt
+}
+
+// observe incorporates the current record into a DupTracker.
+func (dt *DupTracker) observe(s *Script) {
+	// Hash the key.
+	key := s.F(dt.field).String()
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	hv := h.Sum64()
+
+	// Handle the first occurrence of a key.
+	ent, found := dt.entries[hv]
+	if !found {
+		dt.entries[hv] = &dupEntry{firstNR: s.NR}
+		return
+	}
+
+	// Handle the second and subsequent occurrences of a key.
+	if ent.dup == nil {
+		ent.dup = &Duplicate{Key: key, Count: 1}
+		if dt.maxNRs > 0 {
+			ent.dup.NRs = []int{ent.firstNR}
+		}
+		dt.dups = append(dt.dups, ent.dup)
+	}
+	ent.dup.Count++
+	if len(ent.dup.NRs) < dt.maxNRs {
+		ent.dup.NRs = append(ent.dup.NRs, s.NR)
+	}
+}
+
+// Duplicates returns all keys seen so far that appear in more than one
+// record, sorted by decreasing count and then by key.
+func (dt *DupTracker) Duplicates() []Duplicate {
+	dups := make([]Duplicate, len(dt.dups))
+	for i, d := range dt.dups {
+		dups[i] = *d
+		dups[i].NRs = append([]int(nil), d.NRs...)
+	}
+	sort.SliceStable(dups, func(i, j int) bool {
+		if dups[i].Count != dups[j].Count {
+			return dups[i].Count > dups[j].Count
+		}
+		return dups[i].Key < dups[j].Key
+	})
+	return dups
+}
+
+// Reset discards all keys a DupTracker has seen.
+func (dt *DupTracker) Reset() {
+	dt.entries = make(map[uint64]*dupEntry)
+	dt.dups = nil
+}
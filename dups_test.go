@@ -0,0 +1,55 @@
+// This file tests duplicate-key detection.
+
+package awk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestTrackDuplicates tests detecting duplicate keys in a column.
+func TestTrackDuplicates(t *testing.T) {
+	// Look for duplicates in the second column.
+	input := `1 alpha
+2 bravo
+3 alpha
+4 charlie
+5 bravo
+6 alpha
+7 delta
+8 alpha
+`
+	var dups []Duplicate
+	scr := NewScript()
+	dt := scr.TrackDuplicates(2, 3)
+	scr.AppendStmt(nil, func(s *Script) {})
+	scr.End = func(s *Script) { dups = dt.Duplicates() }
+	err := scr.Run(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate the result.
+	want := "[{alpha 4 [1 3 6]} {bravo 2 [2 5]}]"
+	if got := fmt.Sprint(dups); got != want {
+		t.Fatalf("Expected %s but received %s", want, got)
+	}
+
+	// Ensure that keys accumulate across runs until Reset is called.
+	err = scr.Run(strings.NewReader("1 delta\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(dups); n != 3 {
+		t.Fatalf("Expected 3 duplicated keys but received %d", n)
+	}
+	dt.Reset()
+	err = scr.Run(strings.NewReader("1 delta\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(dups); n != 0 {
+		t.Fatalf("Expected 0 duplicated keys but received %d", n)
+	}
+}
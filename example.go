@@ -0,0 +1,32 @@
+// This file provides canned input for the package's documentation examples.
+
+package awk
+
+import (
+	"io"
+	"strings"
+)
+
+// ExampleInput is a small data set used by the package's documentation
+// examples.  It contains numeric and textual fields, a field with a
+// backslash, a line longer than 72 characters, comma and tab separators, and
+// "start" and "stop" markers so that each of the POSIX AWK examples produces
+// some output.
+const ExampleInput = `1 xyz 7 pie
+2 bread 3 xyz
+2 xyz 12 xyz
+start D7 5 2
+5 a\b 6 fig
+8 G42 1 stop
+stop 4.5
+13 10 20 30 40 50 60 70 80 90 100 110 120 130 140 150 160 170 180 190 200
+21, lemon	lime
+34 fnord 9 end
+`
+
+// NewScriptForExample returns a new Script and an io.Reader that supplies
+// ExampleInput.  It lets examples pass canned input to Run so that their
+// output can be verified by "go test".
+func NewScriptForExample() (*Script, io.Reader) {
+	return NewScript(), strings.NewReader(ExampleInput)
+}
@@ -1,25 +1,37 @@
-// This file presents some examples of awk package usage.
+// This file presents some examples of awk package usage.  Most of the examples
+// read the canned input provided by awk.NewScriptForExample so that "go test"
+// can verify their output.
 
 package awk_test
 
 import (
 	"fmt"
 	"github.com/spakin/awk"
-	"os"
 	"sort"
+	"strings"
 )
 
-var s *awk.Script
-
 // Write to the standard output all input lines for which field 3 is
 // greater than 5 (AWK: $3 > 5).
-func Example_01() {
+func Example_posix01() {
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(func(s *awk.Script) bool { return s.F(3).Int() > 5 }, nil)
+	s.Run(input)
+	// Output:
+	// 1 xyz 7 pie
+	// 2 xyz 12 xyz
+	// 5 a\b 6 fig
+	// 13 10 20 30 40 50 60 70 80 90 100 110 120 130 140 150 160 170 180 190 200
+	// 34 fnord 9 end
 }
 
 // Write every tenth line (AWK: (NR % 10) == 0).
-func Example_02() {
+func Example_posix02() {
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(func(s *awk.Script) bool { return s.NR%10 == 0 }, nil)
+	s.Run(input)
+	// Output:
+	// 34 fnord 9 end
 }
 
 // Write any line with a substring containing a 'G' or 'D', followed by a
@@ -27,58 +39,127 @@ func Example_02() {
 // /(G|D)([[:digit:][:alpha:]]*)/). This example uses character classes digit
 // and alpha to match language-independent digit and alphabetic characters
 // respectively.
-func Example_04() {
+func Example_posix04() {
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(func(s *awk.Script) bool { return s.F(0).Match("(G|D)([[:digit:][:alpha:]]*)") }, nil)
+	s.Run(input)
+	// Output:
+	// start D7 5 2
+	// 8 G42 1 stop
 }
 
 // Write any line in which the second field matches the regular expression
 // "xyz" and the fourth field does not (AWK: $2 ~ /xyz/ && $4 !~ /xyz/).
-func Example_05() {
+func Example_posix05() {
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(func(s *awk.Script) bool {
 		return s.F(2).Match("xyz") && !s.F(4).Match("xyz")
 	}, nil)
+	s.Run(input)
+	// Output:
+	// 1 xyz 7 pie
 }
 
 // Write any line in which the second field contains a backslash (AWK: $2 ~
 // /\\/).
-func Example_06() {
+func Example_posix06() {
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(func(s *awk.Script) bool { return s.F(2).Match(`\\`) }, nil)
+	s.Run(input)
+	// Output:
+	// 5 a\b 6 fig
 }
 
 // Write the second to the last and the last field in each line. Separate the
 // fields by a colon (AWK: {OFS=":"; print $(NF-1), $NF}).
-func Example_08() {
-	s.AppendStmt(nil, func(s *awk.Script) { fmt.Printf("%v:%v\n", s.F(s.NF-1), s.F(s.NF)) })
+func Example_posix08() {
+	s, input := awk.NewScriptForExample()
+	s.AppendStmt(nil, func(s *awk.Script) { s.Printf("%s:%s\n", s.F(s.NF-1), s.F(s.NF)) })
+	s.Run(input)
+	// Output:
+	// 7:pie
+	// 3:xyz
+	// 12:xyz
+	// 5:2
+	// 6:fig
+	// 1:stop
+	// stop:4.5
+	// 190:200
+	// lemon:lime
+	// 9:end
 }
 
 // Write the line number and number of fields in each line (AWK: {print NR ":"
 // NF}). The three strings representing the line number, the colon, and the
 // number of fields are concatenated and that string is written to standard
 // output.
-func Example_09() {
-	s.AppendStmt(nil, func(s *awk.Script) { fmt.Printf("%d:%d\n", s.NR, s.NF) })
+func Example_posix09() {
+	s, input := awk.NewScriptForExample()
+	s.AppendStmt(nil, func(s *awk.Script) { s.Printf("%d:%d\n", s.NR, s.NF) })
+	s.Run(input)
+	// Output:
+	// 1:4
+	// 2:4
+	// 3:4
+	// 4:4
+	// 5:4
+	// 6:4
+	// 7:2
+	// 8:21
+	// 9:3
+	// 10:4
 }
 
 // Write lines longer than 72 characters (AWK: length($0) > 72).
-func Example_10() {
+func Example_posix10() {
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(func(s *awk.Script) bool { return len(s.F(0).String()) > 72 }, nil)
+	s.Run(input)
+	// Output:
+	// 13 10 20 30 40 50 60 70 80 90 100 110 120 130 140 150 160 170 180 190 200
 }
 
 // Write the first two fields in opposite order (AWK: {print $2, $1}).
-func Example_11() {
+func Example_posix11() {
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(nil, func(s *awk.Script) { s.Println(s.F(2), s.F(1)) })
+	s.Run(input)
+	// Output:
+	// xyz 1
+	// bread 2
+	// xyz 2
+	// D7 start
+	// a\b 5
+	// G42 8
+	// 4.5 stop
+	// 10 13
+	// lemon 21,
+	// fnord 34
 }
 
-// Do the same as Example 11, with input fields separated by a comma, space and
-// tab characters, or both (AWK:
+// Do the same as POSIX example 11, with input fields separated by a comma,
+// space and tab characters, or both (AWK:
 //
 //     BEGIN { FS = ",[ \t]*|[ \t]+" }
 //           { print $2, $1 }
 //
 // ).
-func Example_12() {
+func Example_posix12() {
+	s, input := awk.NewScriptForExample()
 	s.Begin = func(s *awk.Script) { s.SetFS(",[ \t]*|[ \t]+") }
 	s.AppendStmt(nil, func(s *awk.Script) { s.Println(s.F(2), s.F(1)) })
+	s.Run(input)
+	// Output:
+	// xyz 1
+	// bread 2
+	// xyz 2
+	// D7 start
+	// a\b 5
+	// G42 8
+	// 4.5 stop
+	// 10 13
+	// lemon 21
+	// fnord 34
 }
 
 // Add up the first column and print the sum and average (AWK:
@@ -87,50 +168,136 @@ func Example_12() {
 //     END {print "sum is", s, "average is", s/NR}
 //
 // ).
-func Example_13() {
+func Example_posix13() {
+	s, input := awk.NewScriptForExample()
 	s.Begin = func(s *awk.Script) { s.State = 0.0 }
 	s.AppendStmt(nil, func(s *awk.Script) { s.State = s.State.(float64) + s.F(1).Float64() })
 	s.End = func(s *awk.Script) {
 		sum := s.State.(float64)
 		s.Println("sum is", sum, "average is", sum/float64(s.NR))
 	}
+	s.Run(input)
+	// Output:
+	// sum is 86 average is 8.6
 }
 
 // Write fields in reverse order, one per line (many lines out for each line
 // in).  AWK: {for (i = NF; i > 0; --i) print $i}.
-func Example_14() {
+func Example_posix14() {
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(nil, func(s *awk.Script) {
 		for i := s.NF; i > 0; i-- {
 			s.Println(s.F(i))
 		}
 	})
+	s.Run(input)
+	// Output:
+	// pie
+	// 7
+	// xyz
+	// 1
+	// xyz
+	// 3
+	// bread
+	// 2
+	// xyz
+	// 12
+	// xyz
+	// 2
+	// 2
+	// 5
+	// D7
+	// start
+	// fig
+	// 6
+	// a\b
+	// 5
+	// stop
+	// 1
+	// G42
+	// 8
+	// 4.5
+	// stop
+	// 200
+	// 190
+	// 180
+	// 170
+	// 160
+	// 150
+	// 140
+	// 130
+	// 120
+	// 110
+	// 100
+	// 90
+	// 80
+	// 70
+	// 60
+	// 50
+	// 40
+	// 30
+	// 20
+	// 10
+	// 13
+	// lime
+	// lemon
+	// 21,
+	// end
+	// 9
+	// fnord
+	// 34
 }
 
 // Write all lines between occurrences of the strings "start" and "stop" (AWK:
 // /start/, /stop/).  This version of the Go code uses awk.Range to combine
 // begin and end functions into a match range.
-func Example_15a() {
+func Example_posix15a() {
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(awk.Range(func(s *awk.Script) bool { return s.F(1).Match("start") },
 		func(s *awk.Script) bool { return s.F(1).Match("stop") }),
 		nil)
+	s.Run(input)
+	// Output:
+	// start D7 5 2
+	// 5 a\b 6 fig
+	// 8 G42 1 stop
+	// stop 4.5
 }
 
 // Write all lines between occurrences of the strings "start" and "stop" (AWK:
 // /start/, /stop/).  This version of the Go code uses awk.Auto to define the
 // begin and end conditions as simple regular-expression matches.
-func Example_15b() {
+func Example_posix15b() {
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(awk.Auto("start", "stop"), nil)
+	s.Run(input)
+	// Output:
+	// start D7 5 2
+	// 5 a\b 6 fig
+	// 8 G42 1 stop
 }
 
 // Write all lines whose first field is different from the previous line's
 // first field (AWK: $1 != prev {print; prev = $1}).
-func Example_16() {
+func Example_posix16() {
+	s, input := awk.NewScriptForExample()
 	s.State = s.NewValue("")
 	s.AppendStmt(func(s *awk.Script) bool { return !s.F(1).StrEqual(s.State) },
 		func(s *awk.Script) {
 			s.Println()
 			s.State = s.F(1)
 		})
+	s.Run(input)
+	// Output:
+	// 1 xyz 7 pie
+	// 2 bread 3 xyz
+	// start D7 5 2
+	// 5 a\b 6 fig
+	// 8 G42 1 stop
+	// stop 4.5
+	// 13 10 20 30 40 50 60 70 80 90 100 110 120 130 140 150 160 170 180 190 200
+	// 21, lemon lime
+	// 34 fnord 9 end
 }
 
 // For all rows of the form "Total: <number>", accumulate <number>.  Once all
@@ -140,15 +307,20 @@ func ExampleScript_AppendStmt() {
 	s.State = 0.0
 	s.AppendStmt(func(s *awk.Script) bool { return s.NF == 2 && s.F(1).StrEqual("Total:") },
 		func(s *awk.Script) { s.State = s.State.(float64) + s.F(2).Float64() })
-	s.End = func(s *awk.Script) { fmt.Printf("The grand total is %.2f\n", s.State.(float64)) }
-	s.Run(os.Stdin)
+	s.End = func(s *awk.Script) { s.Printf("The grand total is %.2f\n", s.State) }
+	s.Run(strings.NewReader("Item: 3\nTotal: 12.50\nItem: 8\nTotal: 30.25\n"))
+	// Output: The grand total is 42.75
 }
 
 // Output each line preceded by its line number.
 func ExampleScript_AppendStmt_nilPattern() {
 	s := awk.NewScript()
-	s.AppendStmt(nil, func(s *awk.Script) { fmt.Printf("%4d %v\n", s.NR, s.F(0)) })
-	s.Run(os.Stdin)
+	s.AppendStmt(nil, func(s *awk.Script) { s.Printf("%4d %s\n", s.NR, s.F(0)) })
+	s.Run(strings.NewReader("Mary had\na little\nlamb\n"))
+	// Output:
+	// 1 Mary had
+	//    2 a little
+	//    3 lamb
 }
 
 // Output only rows in which the first column contains a larger number than the
@@ -156,7 +328,10 @@ func ExampleScript_AppendStmt_nilPattern() {
 func ExampleScript_AppendStmt_nilAction() {
 	s := awk.NewScript()
 	s.AppendStmt(func(s *awk.Script) bool { return s.F(1).Int() > s.F(2).Int() }, nil)
-	s.Run(os.Stdin)
+	s.Run(strings.NewReader("3 5\n9 2\n4 4\n10 7\n"))
+	// Output:
+	// 9 2
+	// 10 7
 }
 
 // Output all input lines that appear between "BEGIN" and "END" inclusive.
@@ -165,11 +340,16 @@ func ExampleRange() {
 	s.AppendStmt(awk.Range(func(s *awk.Script) bool { return s.F(1).StrEqual("BEGIN") },
 		func(s *awk.Script) bool { return s.F(1).StrEqual("END") }),
 		nil)
-	s.Run(os.Stdin)
+	s.Run(strings.NewReader("header\nBEGIN\nfirst\nsecond\nEND\nfooter\n"))
+	// Output:
+	// BEGIN
+	// first
+	// second
+	// END
 }
 
 // Extract the first column of the input into a slice of strings.
-func ExampleBegin() {
+func ExampleScript_Begin() {
 	var data []string
 	s := awk.NewScript()
 	s.Begin = func(s *awk.Script) {
@@ -177,7 +357,10 @@ func ExampleBegin() {
 		data = make([]string, 0)
 	}
 	s.AppendStmt(nil, func(s *awk.Script) { data = append(data, s.F(1).String()) })
-	s.Run(os.Stdin)
+	s.Run(strings.NewReader("Smith,John,42\nJones,Mary,37\nBrown,Ann,29\n"))
+	fmt.Println(data)
+	// Output:
+	// [Smith Jones Brown]
 }
 
 // Output each line with its columns in reverse order.
@@ -192,12 +375,16 @@ func ExampleScript_F() {
 			}
 		}
 	})
-	s.Run(os.Stdin)
+	s.Run(strings.NewReader("one two three\nfour five\n"))
+	// Output:
+	// three two one
+	// five four
 }
 
 // Allocate and populate a 2-D array.  The diagonal is made up of strings while
 // the rest of the array consists of float64 values.
 func ExampleValueArray_Set() {
+	s := awk.NewScript()
 	va := s.NewValueArray()
 	diag := []string{"Dasher", "Dancer", "Prancer", "Vixen", "Comet", "Cupid", "Dunder", "Blixem"}
 	for i := 0; i < 8; i++ {
@@ -209,6 +396,9 @@ func ExampleValueArray_Set() {
 			}
 		}
 	}
+	fmt.Println(va.Get(3, 3), va.Get(3, 4).Float64())
+	// Output:
+	// Vixen 0.4444444444444444
 }
 
 // Sort each line's columns, which are assumed to be floating-point numbers.
@@ -222,20 +412,35 @@ func ExampleScript_FFloat64s() {
 		}
 		fmt.Printf("%.5g\n", nums[len(nums)-1])
 	})
-	s.Run(os.Stdin)
+	s.Run(strings.NewReader("3.5 1.25 2\n-1 10 0.5\n"))
+	// Output:
+	// 1.25 2 3.5
+	// -1 0.5 10
 }
 
 // Delete the fifth line of the input stream but output all other lines.
 func ExampleAuto_int() {
-	s := awk.NewScript()
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(awk.Auto(5), func(s *awk.Script) { s.Next() })
 	s.AppendStmt(nil, nil)
-	s.Run(os.Stdin)
+	s.Run(input)
+	// Output:
+	// 1 xyz 7 pie
+	// 2 bread 3 xyz
+	// 2 xyz 12 xyz
+	// start D7 5 2
+	// 8 G42 1 stop
+	// stop 4.5
+	// 13 10 20 30 40 50 60 70 80 90 100 110 120 130 140 150 160 170 180 190 200
+	// 21, lemon	lime
+	// 34 fnord 9 end
 }
 
 // Output only those lines containing the string, "fnord".
 func ExampleAuto_string() {
-	s := awk.NewScript()
+	s, input := awk.NewScriptForExample()
 	s.AppendStmt(awk.Auto("fnord"), nil)
-	s.Run(os.Stdin)
+	s.Run(input)
+	// Output:
+	// 34 fnord 9 end
 }
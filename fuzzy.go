@@ -0,0 +1,89 @@
+// This file provides approximate string matching based on edit distance.
+
+package awk
+
+import "strings"
+
+// Fuzzy returns a PatternFunc that matches a record if the contents of a
+// given field are within maxDist edits of a target string.  An edit is the
+// insertion, deletion, or substitution of a single character or the
+// transposition of two adjacent characters (i.e., the optimal string
+// alignment variant of the Damerau-Levenshtein distance).  This helps match
+// misspelled hostnames, usernames, and the like, which regular expressions
+// handle poorly.  If the script called IgnoreCase(true) or
+// IgnoreCaseStrings(true), the comparison is case-insensitive.
+func Fuzzy(field int, target string, maxDist int) PatternFunc {
+	tgt := []rune(target)
+	lower := []rune(strings.ToLower(target))
+	return func(s *Script) bool {
+		str := s.F(field).String()
+		t := tgt
+		if s.ignCaseStr {
+			str = strings.ToLower(str)
+			t = lower
+		}
+		return editDistance([]rune(str), t, maxDist) <= maxDist
+	}
+}
+
+// EditDistance returns the number of edits required to turn a Value into a
+// given string, as used by Fuzzy.  If the associated script called
+// IgnoreCase(true) or IgnoreCaseStrings(true), or the Value was returned by
+// IgnoringCase(true), the comparison is case-insensitive.
+func (v *Value) EditDistance(str string) int {
+	a, b := v.String(), str
+	if v.ignoreCase() {
+		a, b = strings.ToLower(a), strings.ToLower(b)
+	}
+	ra, rb := []rune(a), []rune(b)
+	return editDistance(ra, rb, len(ra)+len(rb))
+}
+
+// editDistance computes the optimal-string-alignment distance between two
+// rune slices.  To save time, it gives up and returns max+1 as soon as the
+// distance is known to exceed max.
+func editDistance(a, b []rune, max int) int {
+	if d := len(a) - len(b); max < 0 || d > max || -d > max {
+		return max + 1
+	}
+
+	// Maintain three rows of the dynamic-programming matrix: the current
+	// row and the two before it (for transpositions).
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d := prev[j-1] + cost // Substitution
+			if x := prev[j] + 1; x < d {
+				d = x // Deletion
+			}
+			if x := cur[j-1] + 1; x < d {
+				d = x // Insertion
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				if x := prev2[j-2] + 1; x < d {
+					d = x // Transposition
+				}
+			}
+			cur[j] = d
+			if d < rowMin {
+				rowMin = d
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,52 @@
+// This file tests approximate string matching.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestEditDistance tests computing edit distances.
+func TestEditDistance(t *testing.T) {
+	scr := NewScript()
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"kitten", "sitting", 3},
+		{"webserver", "wbeserver", 1},
+		{"naïve", "naive", 1},
+		{"abc", "", 3},
+		{"ca", "abc", 3},
+	}
+	for _, tc := range tests {
+		if d := scr.NewValue(tc.a).EditDistance(tc.b); d != tc.want {
+			t.Fatalf("Expected distance %d between %q and %q but received %d",
+				tc.want, tc.a, tc.b, d)
+		}
+	}
+	scr.IgnoreCase(true)
+	if d := scr.NewValue("HostA").EditDistance("hosta"); d != 0 {
+		t.Fatalf("Expected distance 0 but received %d", d)
+	}
+}
+
+// TestFuzzy tests matching misspelled field contents.
+func TestFuzzy(t *testing.T) {
+	var out bytes.Buffer
+	scr := NewScript()
+	scr.Output = &out
+	scr.IgnoreCaseStrings(true)
+	scr.AppendStmt(Fuzzy(2, "database01", 2), nil)
+	input := "login database01\nlogin dtaabase01\nlogin DATABSE02\nlogin webserver\nlogin database\n"
+	if err := scr.Run(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	want := "login database01\nlogin dtaabase01\nlogin DATABSE02\nlogin database\n"
+	if got := out.String(); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
@@ -0,0 +1,67 @@
+// This file supports control-break processing: taking action when the value
+// of a key column changes from one record to the next.
+
+package awk
+
+// A groupHook is a function to call when the value of a key field changes.
+type groupHook struct {
+	field int                            // Key field
+	fn    func(s *Script, oldKey *Value) // Function to call on a change
+}
+
+// OnGroupChange registers a function to be called whenever the value of a
+// given key field differs from its value in the preceding record, and once
+// more after the last record (before the End action), which is the classic
+// AWK idiom for printing subtotals of sorted input.  The function is passed
+// the previous key, so it can report and then reset per-group state.  When
+// called because of a change, it runs after the new record has been read and
+// split but before any rule is applied to it, so s.F refers to the first
+// record of the new group.  When called after the last record, s.F still
+// refers to that record.  The function should not call Next.  Keys are
+// compared as strings.  Records rejected by RequireNF are ignored.  For
+// example,
+//
+//	s.OnGroupChange(1, func(s *awk.Script, oldKey *awk.Value) {
+//		s.Println(oldKey, total)
+//		total = 0
+//	})
+//
+// OnGroupChange can be called more than once to watch multiple key fields;
+// the functions are called in the order they were registered.  It is invalid
+// to call OnGroupChange from a running script.
+func (s *Script) OnGroupChange(keyField int, fn func(s *Script, oldKey *Value)) {
+	if s.state != notRunning {
+		s.abortScript("OnGroupChange was called from a running script")
+	}
+	s.groupHooks = append(s.groupHooks[:len(s.groupHooks):len(s.groupHooks)], groupHook{field: keyField, fn: fn})
+}
+
+// checkGroups calls the functions registered by OnGroupChange whose key field
+// changed between the previous record and the current one.
+func (s *Script) checkGroups() {
+	if len(s.groupHooks) == 0 {
+		return
+	}
+	if s.groupKeys == nil {
+		s.groupKeys = make([]*Value, len(s.groupHooks))
+	}
+	for i, h := range s.groupHooks {
+		key := s.NewValue(s.F(h.field))
+		old := s.groupKeys[i]
+		s.groupKeys[i] = key
+		if old != nil && old.String() != key.String() {
+			h.fn(s, old)
+		}
+	}
+}
+
+// flushGroups calls all of the functions registered by OnGroupChange after
+// the last record.
+func (s *Script) flushGroups() {
+	for i, h := range s.groupHooks {
+		if s.groupKeys != nil && s.groupKeys[i] != nil {
+			h.fn(s, s.groupKeys[i])
+		}
+	}
+	s.groupKeys = nil
+}
@@ -0,0 +1,47 @@
+// This file tests control-break processing.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestOnGroupChange tests printing subtotals whenever a key changes.
+func TestOnGroupChange(t *testing.T) {
+	scr := NewScript()
+	var out bytes.Buffer
+	scr.Output = &out
+	total := 0
+	scr.OnGroupChange(1, func(s *Script, oldKey *Value) {
+		s.Println("total", oldKey, total, "before", s.F(2))
+		total = 0
+	})
+	scr.AppendStmt(nil, func(s *Script) {
+		total += s.F(2).Int()
+	})
+	scr.End = func(s *Script) {
+		s.Println("end")
+	}
+	for run := 0; run < 2; run++ {
+		out.Reset()
+		err := scr.Run(strings.NewReader("a 1\na 2\nb 5\nc 1\nc 1\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "total a 3 before 5\ntotal b 5 before 1\ntotal c 2 before 1\nend\n"
+		if got := out.String(); got != want {
+			t.Fatalf("Expected %q but received %q", want, got)
+		}
+	}
+
+	// Empty input should not trigger the function.
+	out.Reset()
+	if err := scr.Run(strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "end\n" {
+		t.Fatalf("Expected %q but received %q", "end\n", got)
+	}
+}
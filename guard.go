@@ -0,0 +1,47 @@
+// This file provides pattern negation and cheap guards that can skip the
+// evaluation of expensive patterns.
+
+package awk
+
+// Unless returns a PatternFunc that matches a record only if a given
+// PatternFunc does not, like AWK's "!" operator applied to a pattern.  A nil
+// PatternFunc matches every record, so Unless(nil) matches none.
+func Unless(p PatternFunc) PatternFunc {
+	if p == nil {
+		p = matchAny
+	}
+	return func(s *Script) bool {
+		return !p(s)
+	}
+}
+
+// Guard attaches a guard to the most recently appended rule.  The guard is
+// evaluated before the rule's pattern and can veto it: if the guard returns
+// false, the rule is skipped without its pattern being evaluated at all.  This
+// lets a cheap test, such as a check of NF or of a field's length, protect an
+// expensive pattern, such as a complex regular expression or a lookup in a
+// large table.  For example,
+//
+//	s.AppendStmt(awk.Auto(`^(GET|POST) /api/v[0-9]+/users/[0-9]+$`), a)
+//	s.Guard(func(s *awk.Script) bool { return s.NF >= 7 })
+//
+// Calling Guard more than once on the same rule requires all of the guards
+// to return true, in the order in which they were attached.  Note that a
+// stateful pattern, such as one produced by Range, does not advance its state
+// on records that its guard vetoes.  The number of vetoed records is reported
+// by RuleStats.  It is invalid to call Guard from a running script or before
+// any rules have been appended.
+func (s *Script) Guard(p PatternFunc) {
+	switch {
+	case s.state != notRunning:
+		s.abortScript("Guard was called from a running script")
+	case len(s.rules) == 0:
+		panic("Guard requires a rule to have been appended")
+	}
+	rule := &s.rules[len(s.rules)-1]
+	if g := rule.guard; g != nil {
+		rule.guard = func(s *Script) bool { return g(s) && p(s) }
+		return
+	}
+	rule.guard = p
+}
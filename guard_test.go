@@ -0,0 +1,62 @@
+// This file tests pattern negation and rule guards.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestUnless tests negating a pattern.
+func TestUnless(t *testing.T) {
+	scr := NewScript()
+	var out bytes.Buffer
+	scr.Output = &out
+	scr.AppendStmt(Unless(Auto("^#")), nil)
+	if err := scr.Run(strings.NewReader("a\n# b\nc\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "a\nc\n"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	scr.state = inMiddle
+	if Unless(nil)(scr) {
+		t.Fatal("Expected Unless(nil) to match nothing")
+	}
+}
+
+// TestGuard tests that guards veto patterns without evaluating them.
+func TestGuard(t *testing.T) {
+	scr := NewScript()
+	var out bytes.Buffer
+	scr.Output = &out
+	evals := 0
+	scr.AppendNamedStmt("expensive", func(s *Script) bool {
+		evals++
+		return s.F(1).Match("^[a-z]+$")
+	}, nil)
+	scr.Guard(func(s *Script) bool { return s.NF >= 2 })
+	scr.Guard(func(s *Script) bool { return len(s.F(1).String()) < 5 })
+	if err := scr.Run(strings.NewReader("abc 1\nxyz\nlonger 2\nQ 3\nok 4\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "abc 1\nok 4\n"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	if evals != 3 {
+		t.Fatalf("Expected 3 pattern evaluations but saw %d", evals)
+	}
+	st := scr.RuleStats()[0]
+	if st.Vetoes != 2 || st.Matches != 2 {
+		t.Fatalf("Expected 2 vetoes and 2 matches but received %d and %d", st.Vetoes, st.Matches)
+	}
+
+	// MatchRecord should honor guards.
+	if got := scr.MatchRecord("abc"); len(got) != 0 {
+		t.Fatalf("Expected no matches but received %v", got)
+	}
+	if got := scr.MatchRecord("abc 5"); len(got) != 1 {
+		t.Fatalf("Expected one match but received %v", got)
+	}
+}
@@ -0,0 +1,78 @@
+// This file provides checksums and hashes of Values and records.
+
+package awk
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/crc32"
+	"strings"
+)
+
+// A Canon specifies how a string is canonicalized before being hashed.
+// Canon values can be combined with bitwise or.
+type Canon int
+
+// The following are the possibilities for a Canon.
+const (
+	CanonTrim     Canon = 1 << iota // Remove leading and trailing whitespace
+	CanonFoldCase                   // Map all letters to lowercase
+	CanonNone     Canon = 0         // Hash strings exactly as is
+)
+
+// SetCanon specifies how Values are canonicalized before being hashed by
+// MD5, SHA256, CRC32, and RecordHash.  The default is CanonNone.
+func (s *Script) SetCanon(c Canon) {
+	s.canon = c
+}
+
+// canonical returns a Value as a string canonicalized according to the
+// associated script's settings.
+func (v *Value) canonical() string {
+	str := v.String()
+	if v.script == nil {
+		return str
+	}
+	if v.script.canon&CanonTrim != 0 {
+		str = strings.TrimSpace(str)
+	}
+	if v.script.canon&CanonFoldCase != 0 {
+		str = strings.ToLower(str)
+	}
+	return str
+}
+
+// MD5 returns the MD5 checksum of a Value, treated as a string, in
+// hexadecimal.
+func (v *Value) MD5() string {
+	sum := md5.Sum([]byte(v.canonical()))
+	return hex.EncodeToString(sum[:])
+}
+
+// SHA256 returns the SHA-256 hash of a Value, treated as a string, in
+// hexadecimal.
+func (v *Value) SHA256() string {
+	sum := sha256.Sum256([]byte(v.canonical()))
+	return hex.EncodeToString(sum[:])
+}
+
+// CRC32 returns the IEEE CRC-32 checksum of a Value, treated as a string.
+// This is convenient for partitioning records into a fixed number of
+// buckets.
+func (v *Value) CRC32() uint32 {
+	return crc32.ChecksumIEEE([]byte(v.canonical()))
+}
+
+// RecordHash returns the SHA-256 hash, in hexadecimal, of all fields of the
+// current record.  Each field is canonicalized individually, and fields are
+// separated by SubSep, so records that differ only in their field separators
+// hash identically.
+func (s *Script) RecordHash() string {
+	strs := make([]string, s.NF)
+	for i := range strs {
+		strs[i] = s.F(i + 1).canonical()
+	}
+	sum := sha256.Sum256([]byte(strings.Join(strs, s.SubSep)))
+	return hex.EncodeToString(sum[:])
+}
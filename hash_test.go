@@ -0,0 +1,51 @@
+// This file tests checksums and hashes.
+
+package awk
+
+import (
+	"testing"
+)
+
+// TestValueHashes tests hashing Values with and without canonicalization.
+func TestValueHashes(t *testing.T) {
+	// Hash a value as is.
+	scr := NewScript()
+	v := scr.NewValue("  Hello ")
+	w := scr.NewValue("hello")
+	if v.MD5() == w.MD5() || v.SHA256() == w.SHA256() || v.CRC32() == w.CRC32() {
+		t.Fatal("Different values incorrectly hashed identically")
+	}
+	if got, want := w.MD5(), "5d41402abc4b2a76b9719d911017c592"; got != want {
+		t.Fatalf("Expected %s but received %s", want, got)
+	}
+	if got, want := w.SHA256(), "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"; got != want {
+		t.Fatalf("Expected %s but received %s", want, got)
+	}
+	if got, want := w.CRC32(), uint32(0x3610a686); got != want {
+		t.Fatalf("Expected %08x but received %08x", want, got)
+	}
+
+	// Hash a value after canonicalizing it.
+	scr.SetCanon(CanonTrim | CanonFoldCase)
+	if v.MD5() != w.MD5() || v.SHA256() != w.SHA256() || v.CRC32() != w.CRC32() {
+		t.Fatal("Equivalent values incorrectly hashed differently")
+	}
+}
+
+// TestRecordHash tests hashing an entire record.
+func TestRecordHash(t *testing.T) {
+	scr := NewScript()
+	scr.splitRecord("a b  c")
+	h1 := scr.RecordHash()
+	scr.SetFS(",")
+	scr.splitRecord("a,b,c")
+	h2 := scr.RecordHash()
+	scr.splitRecord("a,b,d")
+	h3 := scr.RecordHash()
+	if h1 != h2 {
+		t.Fatal("Equivalent records incorrectly hashed differently")
+	}
+	if h2 == h3 {
+		t.Fatal("Different records incorrectly hashed identically")
+	}
+}
@@ -0,0 +1,79 @@
+// This file lets scripts determine what kind of datum a Value holds.
+
+package awk
+
+import "math"
+
+// A Kind indicates what kind of datum a Value holds.
+type Kind int
+
+// These are the kinds of datum a Value can hold.
+const (
+	KindString Kind = iota // A string that does not look like a number
+	KindStrNum             // Input data that looks like a number (see IsStrNum)
+	KindInt                // An integer
+	KindFloat              // A floating-point number
+	KindArray              // A subarray (see ValueArray.SubArray)
+)
+
+// String returns the name of a Kind.
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindStrNum:
+		return "strnum"
+	case KindInt:
+		return "int"
+	case KindFloat:
+		return "float"
+	case KindArray:
+		return "array"
+	}
+	return "unknown"
+}
+
+// Kind returns the kind of datum a Value holds.  A Value created from a Go
+// integer (including a *big.Int) is a KindInt, and a Value created from any
+// other Go number is a KindFloat.  A field, record, or GetLine result whose
+// text looks like a number is a KindStrNum.  Every other string is a
+// KindString.
+func (v *Value) Kind() Kind {
+	switch {
+	case v.aval != nil:
+		return KindArray
+	case v.bint != nil || (v.number && !v.float):
+		return KindInt
+	case v.number:
+		return KindFloat
+	case v.strnum:
+		return KindStrNum
+	}
+	return KindString
+}
+
+// IsNumeric says whether a Value is a number or a string that consists
+// entirely of a number, optionally surrounded by whitespace.  Unlike the
+// best-effort conversions performed by Int and Float64, which treat
+// malformed input as zero, IsNumeric lets a script detect bad input.
+func (v *Value) IsNumeric() bool {
+	if v.aval != nil {
+		return false
+	}
+	_, ok := v.looksNumber()
+	return ok
+}
+
+// IsInt says whether a Value is numeric (see IsNumeric) and its numeric value
+// is an integer.  For example, "42" and "1e3" are integers, but "42.5",
+// "inf", and "forty-two" are not.
+func (v *Value) IsInt() bool {
+	if v.aval != nil {
+		return false
+	}
+	if v.bint != nil || v.isInt() {
+		return true
+	}
+	f, ok := v.looksNumber()
+	return ok && !math.IsInf(f, 0) && f == math.Trunc(f)
+}
@@ -0,0 +1,62 @@
+// This file tests Value type introspection.
+
+package awk
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestKind tests determining the kind of each field of a record.
+func TestKind(t *testing.T) {
+	scr := NewScript()
+	var got []string
+	scr.AppendStmt(nil, func(s *Script) {
+		for i := 1; i <= s.NF; i++ {
+			v := s.F(i)
+			got = append(got, v.Kind().String())
+			if v.IsNumeric() {
+				got = append(got, "numeric")
+			}
+			if v.IsInt() {
+				got = append(got, "int")
+			}
+		}
+	})
+	if err := scr.Run(strings.NewReader("42 4.5 1e3 -inf 12abc\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := "strnum numeric int strnum numeric strnum numeric int strnum numeric string"
+	if s := strings.Join(got, " "); s != want {
+		t.Fatalf("Expected %q but received %q", want, s)
+	}
+}
+
+// TestKindConstructed tests the kinds of Values created by NewValue.
+func TestKindConstructed(t *testing.T) {
+	scr := NewScript()
+	tests := []struct {
+		v       *Value
+		kind    Kind
+		numeric bool
+		isInt   bool
+	}{
+		{scr.NewValue(7), KindInt, true, true},
+		{scr.NewValue(7.0), KindFloat, true, true},
+		{scr.NewValue(7.5), KindFloat, true, false},
+		{scr.NewValue(" 8 "), KindString, true, true},
+		{scr.NewValue(""), KindString, false, false},
+		{scr.NewValue(scr.NewValueArray()), KindArray, false, false},
+	}
+	for i, tc := range tests {
+		if k := tc.v.Kind(); k != tc.kind {
+			t.Fatalf("Test %d: Expected %v but received %v", i, tc.kind, k)
+		}
+		if n := tc.v.IsNumeric(); n != tc.numeric {
+			t.Fatalf("Test %d: Expected IsNumeric() to be %v", i, tc.numeric)
+		}
+		if n := tc.v.IsInt(); n != tc.isInt {
+			t.Fatalf("Test %d: Expected IsInt() to be %v", i, tc.isInt)
+		}
+	}
+}
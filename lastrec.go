@@ -0,0 +1,69 @@
+// This file lets an action determine whether the current record is the last
+// one in the input.
+
+package awk
+
+// A peekedRecord is a record read ahead of the current record, along with
+// the state that reading it would have changed.
+type peekedRecord struct {
+	rec        string          // Text of the record
+	err        error           // Error encountered reading the record (io.EOF at the end of the input)
+	rt         string          // Terminator of the record (RT)
+	fnr        int             // FNR before the record is counted
+	srcIdx     int             // Index of the input stream containing the record
+	srcCounter *countingReader // Byte counter for that input stream
+	prov       Provenance      // Provenance of the record
+	provKnown  bool            // true: prov is valid; false: it isn't
+}
+
+// IsLastRecord says whether the current record is the last record of the
+// entire input, that is, whether no further records remain in the current or
+// any subsequent input stream and none have been queued by Inject.  This lets
+// an action handle its final record specially, for example by omitting a
+// trailing separator or by flushing a group, without deferring the work to
+// the End action.  To answer, IsLastRecord reads the next record ahead of
+// time (at most once per record), so it blocks until that record is
+// available, which matters for interactive input.  The state that describes
+// the current record (e.g., RT, FNR, and CurrentSource) is unaffected.
+// IsLastRecord returns false outside of a record.  An error encountered while
+// reading ahead makes IsLastRecord return true; the error is reported when
+// the script next tries to read a record.
+func (s *Script) IsLastRecord() bool {
+	if s.state != inMiddle || len(s.injected) > 0 {
+		return false
+	}
+	if s.peeked == nil {
+		// Read the next record, then restore the state that describes
+		// the current record.
+		rt, fnr, idx, ctr := s.RT, s.FNR, s.srcIdx, s.srcCounter
+		prov, provKnown := s.prov, s.provKnown
+		rec, err := s.readInput()
+		s.peeked = &peekedRecord{
+			rec:        rec,
+			err:        err,
+			rt:         s.RT,
+			fnr:        s.FNR,
+			srcIdx:     s.srcIdx,
+			srcCounter: s.srcCounter,
+			prov:       s.prov,
+			provKnown:  s.provKnown,
+		}
+		s.RT, s.FNR, s.srcIdx, s.srcCounter = rt, fnr, idx, ctr
+		s.prov, s.provKnown = prov, provKnown
+	}
+	return s.peeked.err != nil
+}
+
+// takePeeked returns the record read ahead by IsLastRecord and makes it
+// current.
+func (s *Script) takePeeked() (string, error) {
+	p := s.peeked
+	s.peeked = nil
+	s.RT = p.rt
+	if p.srcIdx != s.srcIdx {
+		s.FNR = p.fnr
+	}
+	s.srcIdx, s.srcCounter = p.srcIdx, p.srcCounter
+	s.prov, s.provKnown = p.prov, p.provKnown
+	return p.rec, p.err
+}
@@ -0,0 +1,54 @@
+// This file tests detecting the last record of the input.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestIsLastRecord tests IsLastRecord across multiple input streams.
+func TestIsLastRecord(t *testing.T) {
+	scr := NewScript()
+	var out bytes.Buffer
+	scr.Output = &out
+	scr.SetRS(";")
+	scr.AppendStmt(nil, func(s *Script) {
+		last := s.IsLastRecord()
+		src := s.CurrentSource()
+		s.Println(s.F(0), s.NR, s.FNR, src.Index, last, s.RT)
+		if s.IsLastRecord() != last {
+			t.Fatal("Expected IsLastRecord to return the same answer when called twice")
+		}
+	})
+	err := scr.RunReaders(strings.NewReader("a;b;"), strings.NewReader(""), strings.NewReader("c;d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a 1 1 1 false ;\nb 2 2 1 false ;\nc 3 1 3 false ;\nd 4 2 3 true ;\n"
+	if got := out.String(); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// Injected records should count as remaining input.
+	scr = NewScript()
+	out.Reset()
+	scr.Output = &out
+	scr.AppendStmt(nil, func(s *Script) {
+		if s.NR == 1 {
+			s.Inject("extra")
+		}
+		sep := ","
+		if s.IsLastRecord() {
+			sep = "."
+		}
+		s.Println(s.F(0).String() + sep)
+	})
+	if err := scr.Run(strings.NewReader("x\ny\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "x,\nextra,\ny.\n"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
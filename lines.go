@@ -0,0 +1,46 @@
+// This file provides an iterator over the records of an input stream.
+
+package awk
+
+import "io"
+
+// Lines returns an iterator over the records GetLine reads from a given input
+// stream, which may be nil to read from the current input stream.  Iteration
+// stops at the end of the stream or at the first error, which can then be
+// retrieved with LinesErr.  The iterator has the same type as iter.Seq[*Value],
+// so with Go 1.23 or later, an entire auxiliary file can be read (e.g., in the
+// Begin action) with
+//
+//	for v := range s.Lines(f) {
+//		// Do something with v.
+//	}
+//	if err := s.LinesErr(); err != nil {
+//		// Handle the error.
+//	}
+//
+// With earlier versions of Go, the iterator can be called directly with a
+// function that returns true to continue iterating or false to stop.
+func (s *Script) Lines(r io.Reader) func(yield func(*Value) bool) {
+	return func(yield func(*Value) bool) {
+		s.linesErr = nil
+		for {
+			v, err := s.GetLine(r)
+			if err != nil {
+				if err != io.EOF {
+					s.linesErr = err
+				}
+				return
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// LinesErr returns the error, if any, that ended the most recent iteration
+// over an iterator returned by Lines.  Reaching the end of the input stream
+// is not considered an error.
+func (s *Script) LinesErr() error {
+	return s.linesErr
+}
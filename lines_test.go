@@ -0,0 +1,65 @@
+// This file tests iterating over the records of an input stream.
+
+package awk
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestLines tests reading an auxiliary input stream with Lines.
+func TestLines(t *testing.T) {
+	scr := NewScript()
+	var got []string
+	scr.Begin = func(s *Script) {
+		s.Lines(strings.NewReader("one\ntwo\nthree\n"))(func(v *Value) bool {
+			got = append(got, v.String())
+			return true
+		})
+		if err := s.LinesErr(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := scr.Run(strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(got, ","); s != "one,two,three" {
+		t.Fatalf("Expected %q but received %q", "one,two,three", s)
+	}
+}
+
+// TestLinesStop tests stopping an iteration early and capturing errors.
+func TestLinesStop(t *testing.T) {
+	scr := NewScript()
+	r := strings.NewReader("a\nb\nc\n")
+	var got []string
+	first := func(v *Value) bool {
+		got = append(got, v.String())
+		return false
+	}
+	scr.Lines(r)(first)
+	scr.Lines(r)(first)
+	if s := strings.Join(got, ","); s != "a,b" {
+		t.Fatalf("Expected %q but received %q", "a,b", s)
+	}
+
+	errBad := errors.New("bad read")
+	scr.Lines(io.MultiReader(strings.NewReader("x\n"), &errReader{errBad}))(func(v *Value) bool {
+		return true
+	})
+	if err := scr.LinesErr(); err != errBad {
+		t.Fatalf("Expected %v but received %v", errBad, err)
+	}
+}
+
+// An errReader is an io.Reader that always fails.
+type errReader struct {
+	err error // Error to return
+}
+
+// Read returns the errReader's error.
+func (er *errReader) Read(p []byte) (int, error) {
+	return 0, er.err
+}
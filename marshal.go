@@ -0,0 +1,146 @@
+// This file lets Values be encoded and decoded as text and as JSON.
+
+package awk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MarshalText implements encoding.TextMarshaler, letting a Value be encoded
+// by packages such as encoding/json and encoding/xml.  A Value is encoded as
+// its string form except that a Value created from a floating-point number is
+// encoded with as many digits as are needed to represent it exactly rather
+// than with ConvFmt.  MarshalText returns an error for a Value that holds a
+// subarray.
+func (v *Value) MarshalText() ([]byte, error) {
+	switch {
+	case v.aval != nil:
+		return nil, errors.New("An array Value cannot be marshaled as text")
+	case v.svalOk:
+		return []byte(v.sval), nil
+	case v.bflt != nil && v.bint == nil:
+		return []byte(v.bflt.Text('g', -1)), nil
+	case v.fvalOk && !v.ivalOk:
+		return []byte(strconv.FormatFloat(v.fval, 'g', -1, 64)), nil
+	}
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, letting a Value be
+// decoded by packages such as encoding/json, encoding/xml, and flag (via
+// flag.TextVar).  The Value is replaced by one holding the given text.  As
+// with fields read from the input, text that looks like a number is treated
+// as a numeric string (see IsStrNum).  The Value remains associated with the
+// same Script, if any.
+func (v *Value) UnmarshalText(text []byte) error {
+	*v = *v.script.newInputValue(string(text))
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, letting encoding/json encode a
+// Value according to its Kind rather than always as a string.  A KindInt or
+// KindFloat Value is encoded as a JSON number (or as a string if it is
+// infinite or not a number, which JSON cannot represent).  A KindStrNum
+// Value is encoded as a JSON number if its text, ignoring surrounding
+// whitespace, is a valid JSON number and as a string otherwise, so input
+// such as "007" is not altered.  A KindString Value is encoded as a string,
+// and a KindArray Value is encoded as a JSON object mapping each index to
+// its element.
+func (v *Value) MarshalJSON() ([]byte, error) {
+	switch v.Kind() {
+	case KindArray:
+		m := make(map[string]*Value, len(v.aval.data))
+		for k, e := range v.aval.data {
+			m[k] = e
+		}
+		return json.Marshal(m)
+	case KindInt, KindFloat:
+		if f := v.Float64(); math.IsInf(f, 0) || math.IsNaN(f) {
+			break
+		}
+		return v.MarshalText()
+	case KindStrNum:
+		num := strings.TrimSpace(v.String())
+		if json.Valid([]byte(num)) {
+			return []byte(num), nil
+		}
+	}
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, letting encoding/json decode
+// any JSON value into a Value.  A JSON string is decoded as by UnmarshalText.
+// A JSON number is decoded as a numeric string (see IsStrNum) so that its
+// text is preserved exactly.  true and false become 1 and 0, and null becomes
+// an empty string, like an uninitialized AWK variable.  A JSON object becomes
+// a subarray, as does a JSON array, whose elements are indexed from 1 as by
+// Split.  The Value remains associated with the same Script, if any.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	s := v.script
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0:
+		return errors.New("Cannot unmarshal empty JSON data into a Value")
+	case data[0] == '"':
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		return v.UnmarshalText([]byte(str))
+	case data[0] == '{':
+		var m map[string]*Value
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		va := s.NewValueArray()
+		for k, e := range m {
+			va.Set(k, bindJSON(e, s))
+		}
+		*v = *s.NewValue(va)
+	case data[0] == '[':
+		var es []*Value
+		if err := json.Unmarshal(data, &es); err != nil {
+			return err
+		}
+		va := s.NewValueArray()
+		for i, e := range es {
+			va.Set(i+1, bindJSON(e, s))
+		}
+		*v = *s.NewValue(va)
+	case string(data) == "null":
+		*v = *s.NewValue("")
+	case string(data) == "true":
+		*v = *s.NewValue(true)
+	case string(data) == "false":
+		*v = *s.NewValue(false)
+	default:
+		if !json.Valid(data) {
+			return errors.New("Invalid JSON number " + strconv.Quote(string(data)))
+		}
+		*v = *s.newInputValue(string(data))
+	}
+	return nil
+}
+
+// bindJSON associates a Value decoded from a JSON object or array with a
+// Script.  A JSON null within an object or array yields a nil *Value, which
+// bindJSON replaces with an empty string.
+func bindJSON(v *Value, s *Script) *Value {
+	if v == nil {
+		return s.NewValue("")
+	}
+	v.Bind(s)
+	if sub, ok := v.Array(); ok {
+		sub.Bind(s)
+	}
+	return v
+}
@@ -0,0 +1,100 @@
+// This file tests encoding and decoding Values as text.
+
+package awk
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestMarshalText tests round-tripping Values through text.
+func TestMarshalText(t *testing.T) {
+	scr := NewScript()
+	x, y := 0.1, 0.2
+	tests := []struct {
+		v    *Value
+		want string
+	}{
+		{scr.NewValue(42), "42"},
+		{scr.NewValue(x + y), "0.30000000000000004"},
+		{scr.NewValue("hello"), "hello"},
+	}
+	for _, tc := range tests {
+		text, err := tc.v.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(text) != tc.want {
+			t.Fatalf("Expected %q but received %q", tc.want, text)
+		}
+		var v Value
+		if err := v.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		if v.String() != tc.want {
+			t.Fatalf("Expected %q but received %q", tc.want, v.String())
+		}
+	}
+
+	// Unmarshaling into a bound Value retains the binding.
+	v := scr.NewValue("")
+	if err := v.UnmarshalText([]byte("3.5")); err != nil {
+		t.Fatal(err)
+	}
+	if v.script != scr || !v.IsStrNum() {
+		t.Fatalf("Expected a bound numeric string but received %#v", v)
+	}
+
+	// Arrays cannot be marshaled.
+	va := scr.NewValueArray()
+	if _, err := scr.NewValue(va).MarshalText(); err == nil {
+		t.Fatal("Expected an error when marshaling an array")
+	}
+}
+
+// TestMarshalJSON tests round-tripping Values through encoding/json.
+func TestMarshalJSON(t *testing.T) {
+	scr := NewScript()
+	x, y := 0.1, 0.2
+	sub := scr.NewValueArray()
+	sub.Set("k", 1)
+	in := map[string]*Value{
+		"arr":    scr.NewValue(sub),
+		"float":  scr.NewValue(x + y),
+		"inf":    scr.NewValue(math.Inf(1)),
+		"int":    scr.NewValue(42),
+		"str":    scr.NewValue("hello"),
+		"strnum": scr.newInputValue(" 1e3 "),
+		"zeros":  scr.newInputValue("007"),
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"arr":{"k":1},"float":0.30000000000000004,"inf":"+Inf","int":42,"str":"hello","strnum":1e3,"zeros":"007"}`
+	if string(data) != want {
+		t.Fatalf("Expected %q but received %q", want, data)
+	}
+
+	var out *Value
+	data = []byte(`{"a": [1, "two", null, true], "b": {"c": 2.50}}`)
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	out.Bind(scr)
+	arr, ok := out.Array()
+	if !ok {
+		t.Fatalf("Expected an array but received %q", out)
+	}
+	var got []string
+	arr.Walk(func(keys []*Value, v *Value) {
+		got = append(got, fmt.Sprintf("%s/%s=%s:%s", keys[0], keys[1], v, v.Kind()))
+	})
+	want = "a/1=1:strnum a/2=two:string a/3=:string a/4=1:int b/c=2.50:strnum"
+	if s := strings.Join(got, " "); s != want {
+		t.Fatalf("Expected %q but received %q", want, s)
+	}
+}
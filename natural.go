@@ -0,0 +1,105 @@
+// This file provides natural ordering of strings, in which embedded numbers
+// compare numerically, so "web2" sorts before "web10".
+
+package awk
+
+import (
+	"sort"
+	"strings"
+)
+
+// appendNaturalLength appends to a key an encoding of the number of digits
+// in a number.  The encoding consists only of digits and sorts in the same
+// order as the lengths it encodes: 1-8 are encoded as a single digit, and
+// larger lengths n as "9" followed by the encoding of n-9.
+func appendNaturalLength(key []byte, n int) []byte {
+	for n >= 9 {
+		key = append(key, '9')
+		n -= 9
+	}
+	return append(key, byte('0'+n))
+}
+
+// naturalKey returns a string whose byte-wise order is the natural order of
+// the given string.
+func naturalKey(str string) string {
+	key := make([]byte, 0, len(str)+8)
+	for i := 0; i < len(str); {
+		if str[i] < '0' || str[i] > '9' {
+			key = append(key, str[i])
+			i++
+			continue
+		}
+
+		// Encode a run of digits as its length (without leading zeros)
+		// followed by the digits themselves.
+		j := i
+		for j < len(str) && str[j] >= '0' && str[j] <= '9' {
+			j++
+		}
+		digits := strings.TrimLeft(str[i:j], "0")
+		if digits == "" {
+			digits = "0"
+		}
+		key = appendNaturalLength(key, len(digits))
+		key = append(key, digits...)
+		i = j
+	}
+	return string(key)
+}
+
+// NaturalKey returns a sort key for a Value's string form such that
+// comparing keys byte by byte (e.g., with the < operator or as the indexes
+// of a ValueArray with OrderSorted iteration) orders the original strings
+// naturally: runs of decimal digits are compared by numeric value, and all
+// other characters are compared byte by byte.  Hence, "web2" sorts before
+// "web10", and "v1.9.3" sorts before "v1.10.0".  Strings that differ only in
+// the leading zeros of their numbers (e.g., "web01" and "web1") have the same
+// key.
+func (v *Value) NaturalKey() string {
+	return naturalKey(v.String())
+}
+
+// NaturalCompare compares two strings in natural order, as described for
+// Value.NaturalKey, returning a negative number, zero, or a positive number if
+// the first string should sort respectively before, equally with, or after
+// the second.  Strings whose natural keys are equal are compared byte by
+// byte so that only identical strings compare equal.  NaturalCompare can be
+// passed to Script.SetCollator to make natural order the script's default.
+func NaturalCompare(a, b string) int {
+	if c := strings.Compare(naturalKey(a), naturalKey(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// SortByIndexNatural compares array elements by their keys in natural order
+// (see NaturalCompare), like gawk's "@ind_str_asc" but with embedded numbers
+// compared numerically.
+func SortByIndexNatural(k1, v1, k2, v2 *Value) int {
+	return NaturalCompare(k1.String(), k2.String())
+}
+
+// SortByValueNatural compares array elements by their values in natural
+// order (see NaturalCompare).  Subarrays sort after all scalars.
+func SortByValueNatural(k1, v1, k2, v2 *Value) int {
+	if c, ok := compareArrays(v1, v2); ok {
+		return c
+	}
+	return NaturalCompare(v1.String(), v2.String())
+}
+
+// sortNatural sorts a list of strings in natural order.
+func sortNatural(strs []string) {
+	keys := make(map[string]string, len(strs))
+	for _, s := range strs {
+		keys[s] = naturalKey(s)
+	}
+	sort.Slice(strs, func(i, j int) bool {
+		ki, kj := keys[strs[i]], keys[strs[j]]
+		if ki != kj {
+			return ki < kj
+		}
+		return strs[i] < strs[j]
+	})
+}
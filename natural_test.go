@@ -0,0 +1,68 @@
+// This file tests natural ordering of strings.
+
+package awk
+
+import (
+	"sort"
+	"testing"
+)
+
+// TestNaturalCompare tests that embedded numbers compare numerically.
+func TestNaturalCompare(t *testing.T) {
+	want := []string{
+		"", "0", "007", "7", "8", "10", "1000000000", "12345678901234567890", "a",
+		"v1.9.3", "v1.10.0", "web", "web01", "web1", "web2", "web10", "web10a", "web10b", "webx",
+	}
+	strs := []string{
+		"web10", "v1.10.0", "web1", "webx", "7", "12345678901234567890", "web", "10",
+		"web10b", "a", "", "web01", "1000000000", "0", "v1.9.3", "web2", "8", "web10a", "007",
+	}
+	sort.Slice(strs, func(i, j int) bool { return NaturalCompare(strs[i], strs[j]) < 0 })
+	for i := range want {
+		if strs[i] != want[i] {
+			t.Fatalf("Expected %q but received %q", want, strs)
+		}
+	}
+
+	// Keys should sort in the same order, except that leading zeros are
+	// ignored.
+	scr := NewScript()
+	for i := 1; i < len(want); i++ {
+		k1, k2 := scr.NewValue(want[i-1]).NaturalKey(), scr.NewValue(want[i]).NaturalKey()
+		if k1 > k2 || (k1 == k2 && want[i] != "7" && want[i] != "web1") {
+			t.Fatalf("Expected key of %q (%q) to precede that of %q (%q)", want[i-1], k1, want[i], k2)
+		}
+	}
+}
+
+// TestNaturalSort tests sorting ValueArrays in natural order.
+func TestNaturalSort(t *testing.T) {
+	scr := NewScript()
+	va := scr.NewValueArray().SetOrder(OrderNatural)
+	for i, h := range []string{"web10", "web2", "web1", "db3"} {
+		va.Set(h, i)
+	}
+	if got, want := joinValues(va.Keys()), "db3 web1 web2 web10"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	if got, want := joinValues(va.SortedKeys(Reverse(SortByIndexNatural))), "web10 web2 web1 db3"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	hosts := scr.NewValueArray()
+	for i, h := range []string{"web10", "web2", "web1", "db3"} {
+		hosts.Set(i, h)
+	}
+	if got, want := joinValues(hosts.SortedValues(SortByValueNatural)), "db3 web1 web2 web10"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// A natural collator should affect comparisons and OrderSorted.
+	scr.SetCollator(NaturalCompare)
+	if !scr.NewValue("web2").Less("web10") {
+		t.Fatal("Expected web2 to be less than web10")
+	}
+	va.SetOrder(OrderSorted)
+	if got, want := joinValues(va.Keys()), "db3 web1 web2 web10"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
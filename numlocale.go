@@ -0,0 +1,151 @@
+// This file supports lenient conversion of formatted numbers, such as
+// currency amounts, to numeric values.
+
+package awk
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A NumericLocale describes how numbers are formatted in a locale so that
+// strings such as "$1,234.56" and "(45.00)" can be converted to numbers.
+type NumericLocale struct {
+	Currency  []string // Currency symbols or codes to ignore (nil for any Unicode currency symbol)
+	Thousands rune     // Thousands separator (0 for ',')
+	Decimal   rune     // Decimal separator (0 for '.')
+}
+
+// SetNumericLocale enables lenient numeric conversion according to a given
+// NumericLocale.  When lenient conversion is enabled, Value.Float64 and
+// Value.Int (and hence arithmetic on Values) accept strings that contain a
+// currency symbol before or after the number, thousands separators in the
+// integer part, and a locale-specific decimal separator, and treat a number
+// enclosed in parentheses as negative, as is conventional in financial
+// statements.  Hence, "$1,234.56" converts to 1234.56 and "(45.00)" converts
+// to -45 rather than to 0.  With
+//
+//	scr.SetNumericLocale(&awk.NumericLocale{Currency: []string{"€", "EUR"}, Thousands: '.', Decimal: ','})
+//
+// "1.234,56 €" and "EUR -7,5" convert to 1234.56 and -7.5.  Strings that do
+// not fit the locale are converted as usual.  Comparisons and the detection of
+// numeric strings (see Value.IsStrNum) are unaffected.  SetNumericLocale(nil),
+// the default, disables lenient conversion.
+func (s *Script) SetNumericLocale(loc *NumericLocale) {
+	if loc == nil {
+		s.numLocale = nil
+		return
+	}
+	nl := *loc
+	if nl.Currency != nil {
+		nl.Currency = make([]string, len(loc.Currency))
+		copy(nl.Currency, loc.Currency)
+	}
+	if nl.Thousands == 0 {
+		nl.Thousands = ','
+	}
+	if nl.Decimal == 0 {
+		nl.Decimal = '.'
+	}
+	s.numLocale = &nl
+}
+
+// trimSign removes a leading sign from a string and says whether the sign was
+// negative.
+func trimSign(str string) (string, bool) {
+	switch {
+	case strings.HasPrefix(str, "-"):
+		return str[1:], true
+	case strings.HasPrefix(str, "+"):
+		return str[1:], false
+	}
+	return str, false
+}
+
+// trimCurrency removes a currency symbol and any adjacent whitespace from the
+// beginning and the end of a string.
+func (nl *NumericLocale) trimCurrency(str string) string {
+	if nl.Currency == nil {
+		if r, n := utf8.DecodeRuneInString(str); unicode.Is(unicode.Sc, r) {
+			str = str[n:]
+		}
+		if r, n := utf8.DecodeLastRuneInString(str); unicode.Is(unicode.Sc, r) {
+			str = str[:len(str)-n]
+		}
+		return strings.TrimSpace(str)
+	}
+	for _, c := range nl.Currency {
+		if c != "" && strings.HasPrefix(str, c) {
+			str = str[len(c):]
+			break
+		}
+	}
+	for _, c := range nl.Currency {
+		if c != "" && strings.HasSuffix(str, c) {
+			str = str[:len(str)-len(c)]
+			break
+		}
+	}
+	return strings.TrimSpace(str)
+}
+
+// parse converts a string formatted according to a NumericLocale to a
+// float64 and says whether the conversion succeeded.
+func (nl *NumericLocale) parse(str string) (float64, bool) {
+	// Strip parentheses, signs, and currency symbols.
+	str = strings.TrimSpace(str)
+	paren := strings.HasPrefix(str, "(") && strings.HasSuffix(str, ")")
+	if paren {
+		str = strings.TrimSpace(str[1 : len(str)-1])
+	}
+	str, neg := trimSign(str)
+	str = nl.trimCurrency(str)
+	if !neg {
+		str, neg = trimSign(str)
+	}
+	if paren {
+		neg = !neg
+	}
+
+	// Remove thousands separators from the integer part, and replace the
+	// decimal separator with a period.
+	var sb strings.Builder
+	sb.Grow(len(str))
+	if neg {
+		sb.WriteByte('-')
+	}
+	inFrac := false
+	prevDigit := false
+	for i, r := range str {
+		switch {
+		case r == nl.Decimal && !inFrac:
+			sb.WriteByte('.')
+			inFrac = true
+		case r == nl.Thousands && !inFrac:
+			// Require a digit on each side of the separator.
+			next, _ := utf8.DecodeRuneInString(str[i+utf8.RuneLen(r):])
+			if !prevDigit || next < '0' || next > '9' {
+				return 0, false
+			}
+		case r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		default:
+			return 0, false
+		}
+		prevDigit = r >= '0' && r <= '9'
+	}
+	f, err := strconv.ParseFloat(sb.String(), 64)
+	return f, err == nil
+}
+
+// lenientFloat64 converts a string Value to a float64 according to its
+// script's NumericLocale and says whether the conversion succeeded.  It
+// always fails if lenient conversion is not enabled.
+func (v *Value) lenientFloat64() (float64, bool) {
+	if v.script == nil || v.script.numLocale == nil {
+		return 0, false
+	}
+	return v.script.numLocale.parse(v.sval)
+}
@@ -0,0 +1,49 @@
+// This file tests lenient numeric conversion.
+
+package awk
+
+import (
+	"testing"
+)
+
+// TestNumericLocale tests converting formatted numbers according to a
+// NumericLocale.
+func TestNumericLocale(t *testing.T) {
+	tests := []struct {
+		loc  *NumericLocale // Locale to use
+		in   string         // String to convert
+		want float64        // Expected result
+	}{
+		{nil, "$1,234.56", 0},
+		{nil, "(45.00)", 0},
+		{&NumericLocale{}, "$1,234.56", 1234.56},
+		{&NumericLocale{}, "(45.00)", -45},
+		{&NumericLocale{}, "($1,000)", -1000},
+		{&NumericLocale{}, "-£12", -12},
+		{&NumericLocale{}, "$-3.5", -3.5},
+		{&NumericLocale{}, "  99.5 ¥ ", 99.5},
+		{&NumericLocale{}, "1,2,3", 123},
+		{&NumericLocale{}, "1,,2", 1},
+		{&NumericLocale{}, "12abc", 12},
+		{&NumericLocale{Currency: []string{"€", "EUR"}, Thousands: '.', Decimal: ','}, "1.234,56 €", 1234.56},
+		{&NumericLocale{Currency: []string{"€", "EUR"}, Thousands: '.', Decimal: ','}, "EUR -7,5", -7.5},
+		{&NumericLocale{Currency: []string{"€", "EUR"}, Thousands: '.', Decimal: ','}, "$5", 0},
+	}
+	for _, tc := range tests {
+		scr := NewScript()
+		scr.SetNumericLocale(tc.loc)
+		if got := scr.NewValue(tc.in).Float64(); got != tc.want {
+			t.Fatalf("Expected %v for %q but received %v", tc.want, tc.in, got)
+		}
+		if got, want := scr.NewValue(tc.in).Int(), int(tc.want); got != want {
+			t.Fatalf("Expected %d for %q but received %d", want, tc.in, got)
+		}
+	}
+
+	// Arithmetic should respect the locale, too.
+	scr := NewScript()
+	scr.SetNumericLocale(&NumericLocale{})
+	if got := scr.NewValue("$1,000").Add("(250)").String(); got != "750" {
+		t.Fatalf("Expected %q but received %q", "750", got)
+	}
+}
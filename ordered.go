@@ -0,0 +1,167 @@
+// This file provides an output collector that restores the sequential order
+// of records processed in parallel.
+
+package awk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// errOrderedClosed is returned by Put after an OrderedOutput is closed.
+var errOrderedClosed = errors.New("OrderedOutput was used after being closed")
+
+// An OrderedOutput collects output that is produced out of order, such as by
+// several executions of a Program (see Compile) that each process a shard of
+// the input, and writes it to an underlying io.Writer in order of sequence
+// number.  The result is byte-for-byte identical to the output of a single,
+// sequential run.  Memory is bounded by a reordering window: output for a
+// sequence number that is too far ahead of the next one to be written waits
+// until the output in between has been supplied.  An OrderedOutput is safe
+// for concurrent use.
+type OrderedOutput struct {
+	mu      sync.Mutex
+	cond    *sync.Cond     // Signaled whenever next advances or an error occurs
+	w       io.Writer      // Underlying writer
+	window  int            // Maximum number of sequence numbers to buffer
+	next    int            // Next sequence number to write
+	pending map[int][]byte // Output waiting for earlier sequence numbers
+	err     error          // First error encountered
+}
+
+// NewOrderedOutput returns an OrderedOutput that writes to a given io.Writer
+// and that buffers the output of at most window sequence numbers (or 1, if
+// window is smaller).  Sequence numbers start at 1, like NR.
+func NewOrderedOutput(w io.Writer, window int) *OrderedOutput {
+	if window < 1 {
+		window = 1
+	}
+	o := &OrderedOutput{
+		w:       w,
+		window:  window,
+		next:    1,
+		pending: make(map[int][]byte),
+	}
+	o.cond = sync.NewCond(&o.mu)
+	return o
+}
+
+// Put supplies the complete output associated with a given sequence number,
+// which may be empty.  Every sequence number must be supplied exactly once for
+// the output that follows it to be written.  Put blocks while the sequence
+// number is at least window beyond the next one to be written.  It returns an
+// error if the sequence number was already supplied, if a previous write
+// failed, or if the OrderedOutput was closed.
+func (o *OrderedOutput) Put(seq int, data []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Wait until the sequence number falls within the window.
+	for seq >= o.next+o.window && o.err == nil {
+		o.cond.Wait()
+	}
+	if o.err != nil {
+		return o.err
+	}
+	if _, dup := o.pending[seq]; dup || seq < o.next {
+		return fmt.Errorf("Output for sequence number %d was supplied more than once", seq)
+	}
+	o.pending[seq] = append([]byte{}, data...)
+
+	// Write all output that is now in order.
+	defer o.cond.Broadcast()
+	for {
+		d, ok := o.pending[o.next]
+		if !ok {
+			return nil
+		}
+		delete(o.pending, o.next)
+		o.next++
+		if len(d) > 0 {
+			if _, err := o.w.Write(d); err != nil {
+				o.err = err
+				return err
+			}
+		}
+	}
+}
+
+// Close indicates that no more output will be supplied.  It returns an error
+// if any output is still waiting for an earlier sequence number, which
+// indicates that some sequence number was never supplied.  Any calls to Put
+// that are blocked, or that are made later, return an error.
+func (o *OrderedOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	err := o.err
+	if err == nil && len(o.pending) > 0 {
+		err = fmt.Errorf("Output for sequence number %d was never supplied", o.next)
+	}
+	if o.err == nil {
+		o.err = errOrderedClosed
+	}
+	o.cond.Broadcast()
+	if err == errOrderedClosed {
+		return nil
+	}
+	return err
+}
+
+// SetOrderedOutput directs the output produced while processing each record
+// to an OrderedOutput rather than to the Output stream.  A function maps the
+// script's state to the record's sequence number in the complete,
+// sequential input; if the function is nil, NR is used.  For example, if the
+// input is split into contiguous shards, each shard's execution might
+// return an offset plus NR.  Every record produces exactly one sequence
+// number, even if it produces no output or is rejected by RequireNF.  When an
+// action reads additional records with GetLine(nil) or records are joined by
+// JoinContinuations, the record's output is associated with the last record
+// read, and the sequence numbers of the others are supplied with no output.
+// Output from the Begin and End actions is still written to the Output
+// stream.  Passing a nil OrderedOutput restores normal output.  Because each
+// execution must be given its own mapping to sequence numbers, the setting is
+// not carried over by Clone, Copy, or Compile.
+func (s *Script) SetOrderedOutput(o *OrderedOutput, seq func(s *Script) int) {
+	s.ordered = o
+	s.orderedSeq = seq
+}
+
+// orderedSeqNum returns the sequence number of the current record for
+// purposes of ordered output.
+func (s *Script) orderedSeqNum() int {
+	if s.orderedSeq == nil {
+		return s.NR
+	}
+	return s.orderedSeq(s)
+}
+
+// putOrdered supplies a record's output to the script's OrderedOutput.
+func (s *Script) putOrdered(buf *bytes.Buffer) {
+	var data []byte
+	if buf != nil {
+		data = buf.Bytes()
+	}
+	if err := s.ordered.Put(s.orderedSeqNum(), data); err != nil {
+		s.abortScript("%s", err)
+	}
+}
+
+// consumeRecord increments NR and FNR for a record read other than by Run's
+// main loop, such as by GetLine.  To keep OrderedOutput from waiting forever
+// for a sequence number that will never be supplied, it supplies empty output
+// for the current record, whose output will instead be associated with the
+// record just read, or, outside of record processing, for the record just
+// read.
+func (s *Script) consumeRecord() {
+	if s.ordered != nil && s.state == inMiddle {
+		s.putOrdered(nil)
+	}
+	s.NR++
+	s.FNR++
+	if s.ordered != nil && s.state != inMiddle {
+		s.putOrdered(nil)
+	}
+}
@@ -0,0 +1,150 @@
+// This file tests restoring the order of output produced in parallel.
+
+package awk
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestOrderedOutputShards tests that output from several executions running
+// concurrently on interleaved shards of the input matches that of a
+// sequential run.
+func TestOrderedOutputShards(t *testing.T) {
+	// Prepare some input and the expected, sequential output.
+	var lines []string
+	for i := 1; i <= 200; i++ {
+		lines = append(lines, fmt.Sprintf("%d %d", i, i*i))
+		if i%50 == 0 {
+			lines = append(lines, "rejected")
+		}
+	}
+	scr := NewScript()
+	scr.RequireNF(2, NFReject)
+	scr.AppendStmt(func(s *Script) bool { return s.F(1).Int()%3 != 0 },
+		func(s *Script) { s.Println(s.F(2), s.F(1)) })
+	scr.AppendStmt(Auto("7"), nil)
+	var want bytes.Buffer
+	scr.Output = &want
+	if err := scr.Run(strings.NewReader(strings.Join(lines, "\n"))); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run the same program on four shards, with record i going to shard
+	// i mod 4.
+	const nShards = 4
+	var got bytes.Buffer
+	prog := scr.Compile()
+	oo := NewOrderedOutput(&got, 8)
+	var wg sync.WaitGroup
+	errs := make([]error, nShards)
+	for sh := 0; sh < nShards; sh++ {
+		var shard []string
+		for i := sh; i < len(lines); i += nShards {
+			shard = append(shard, lines[i])
+		}
+		ex := prog.NewExecution()
+		sh := sh
+		ex.SetOrderedOutput(oo, func(s *Script) int { return (s.NR-1)*nShards + sh + 1 })
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[sh] = ex.Run(strings.NewReader(strings.Join(shard, "\n")))
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := oo.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		t.Fatalf("Expected %q but received %q", want.String(), got.String())
+	}
+}
+
+// TestOrderedOutputErrors tests that duplicate and missing sequence numbers
+// are reported.
+func TestOrderedOutputErrors(t *testing.T) {
+	var out bytes.Buffer
+	oo := NewOrderedOutput(&out, 4)
+	if err := oo.Put(2, []byte("two\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := oo.Put(2, []byte("again\n")); err == nil {
+		t.Fatalf("Expected an error for a duplicate sequence number")
+	}
+	if err := oo.Put(1, []byte("one\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := oo.Put(4, []byte("four\n")); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "one\ntwo\n" {
+		t.Fatalf("Expected %q but received %q", "one\ntwo\n", out.String())
+	}
+	if err := oo.Close(); err == nil {
+		t.Fatalf("Expected an error for a missing sequence number")
+	}
+	if err := oo.Put(3, nil); err == nil {
+		t.Fatalf("Expected an error after Close")
+	}
+}
+
+// TestOrderedOutputGetLine tests that records consumed by GetLine or
+// JoinContinuations do not leave gaps in the sequence numbers supplied to an
+// OrderedOutput.
+func TestOrderedOutputGetLine(t *testing.T) {
+	var out bytes.Buffer
+	oo := NewOrderedOutput(&out, 2)
+	scr := NewScript()
+	scr.SetOrderedOutput(oo, nil)
+	scr.AppendStmt(func(s *Script) bool { return s.F(1).StrEqual("a") }, func(s *Script) {
+		v, err := s.GetLine(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Println(s.F(1), v)
+	})
+	scr.AppendStmt(Auto("[^ab]"), nil)
+	done := make(chan error)
+	go func() { done <- scr.Run(strings.NewReader("a\nb\nc\nd\ne\nf\n")) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+	if err := oo.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a b\nc\nd\ne\nf\n"; out.String() != want {
+		t.Fatalf("Expected %q but received %q", want, out.String())
+	}
+
+	// Joined records must likewise supply their sequence numbers.
+	out.Reset()
+	oo = NewOrderedOutput(&out, 1)
+	scr = NewScript()
+	scr.SetOrderedOutput(oo, nil)
+	scr.JoinContinuations(2, 0, " ")
+	scr.AppendStmt(nil, nil)
+	if err := scr.Run(strings.NewReader("a\nb\nc d\ne\nf\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := oo.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a b\nc d\ne f\n"; out.String() != want {
+		t.Fatalf("Expected %q but received %q", want, out.String())
+	}
+}
@@ -0,0 +1,71 @@
+// This file provides facilities for keeping each record's output contiguous.
+
+package awk
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// AtomicOutput specifies whether all of the output produced while processing
+// a record should be written to the Output stream with a single Write call
+// after all rules have been applied to the record.  This guarantees that one
+// record's output is not interleaved with other output, such as that of
+// another script running concurrently on the same Output stream (provided the
+// stream's Write method is itself safe for concurrent use; see
+// NewSyncWriter).  Only output written through the Output field while
+// processing a record is affected; output from the Begin and End actions is
+// written directly.  The default is AtomicOutput(false).
+func (s *Script) AtomicOutput(atomic bool) {
+	s.atomicOut = atomic
+}
+
+// bufferRecordOutput redirects the Output stream to a buffer if AtomicOutput
+// is enabled or an OrderedOutput is in use.  It returns a function that
+// restores the Output stream and writes the buffered output to it in a single
+// Write call or passes the buffered output to the OrderedOutput.
+func (s *Script) bufferRecordOutput() func() {
+	if !s.atomicOut && s.ordered == nil {
+		return func() {}
+	}
+	out := s.Output
+	if s.recOut == nil {
+		s.recOut = &bytes.Buffer{}
+	}
+	buf := s.recOut
+	buf.Reset()
+	s.Output = buf
+	return func() {
+		s.Output = out
+		switch {
+		case s.ordered != nil:
+			s.putOrdered(buf)
+		case buf.Len() > 0:
+			out.Write(buf.Bytes())
+		}
+	}
+}
+
+// A syncWriter is an io.Writer that serializes calls to an underlying
+// io.Writer's Write method.
+type syncWriter struct {
+	sync.Mutex
+	w io.Writer // Underlying writer
+}
+
+// Write writes to the underlying io.Writer while holding a lock.
+func (sw *syncWriter) Write(p []byte) (int, error) {
+	sw.Lock()
+	defer sw.Unlock()
+	return sw.w.Write(p)
+}
+
+// NewSyncWriter wraps an io.Writer so that its Write method can safely be
+// called from multiple goroutines, with each call completing before the next
+// begins.  Combined with AtomicOutput, this lets multiple scripts (e.g.,
+// executions of the same Program) share an Output stream without
+// interleaving the output of individual records.
+func NewSyncWriter(w io.Writer) io.Writer {
+	return &syncWriter{w: w}
+}
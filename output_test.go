@@ -0,0 +1,59 @@
+// This file tests keeping each record's output contiguous.
+
+package awk
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestAtomicOutput tests that concurrent executions don't interleave the
+// output of individual records.
+func TestAtomicOutput(t *testing.T) {
+	// Define a script that outputs each record in pieces.
+	scr := NewScript()
+	scr.AtomicOutput(true)
+	scr.AppendStmt(nil, func(s *Script) {
+		for _, c := range s.F(0).String() {
+			fmt.Fprintf(s.Output, "%c", c)
+			runtime.Gosched()
+		}
+	})
+	scr.AppendStmt(nil, func(s *Script) {
+		fmt.Fprintln(s.Output)
+	})
+	prog := scr.Compile()
+
+	// Run many executions concurrently on the same output stream.
+	var out bytes.Buffer
+	w := NewSyncWriter(&out)
+	var wg sync.WaitGroup
+	for _, word := range []string{"aaaaaaaa", "bbbbbbbb", "cccccccc", "dddddddd"} {
+		wg.Add(1)
+		go func(word string) {
+			defer wg.Done()
+			sc := prog.NewExecution()
+			sc.Output = w
+			err := sc.Run(strings.NewReader(strings.Repeat(word+"\n", 50)))
+			if err != nil {
+				t.Error(err)
+			}
+		}(word)
+	}
+	wg.Wait()
+
+	// Ensure that each line is homogeneous.
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 200 {
+		t.Fatalf("Expected 200 lines but received %d", len(lines))
+	}
+	for _, ln := range lines {
+		if len(ln) != 8 || strings.Count(ln, ln[:1]) != 8 {
+			t.Fatalf("Received interleaved output %q", ln)
+		}
+	}
+}
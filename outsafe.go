@@ -0,0 +1,94 @@
+// This file provides options that protect consumers of a script's output
+// from overly long or binary-contaminated records.
+
+package awk
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxOutputRecord limits each record output by Println or the default print
+// action to at most n bytes, not including the output record separator.  A
+// longer record is truncated (on a character boundary) and the given marker,
+// such as "...", is appended in its place, with the marker counting toward
+// the n-byte limit.  If n is too small to hold the marker, the record is
+// truncated to n bytes with no marker.  An n of zero, the default, imposes
+// no limit.  Output from Printf and from writes to the Output stream is not
+// affected.
+func (s *Script) MaxOutputRecord(n int, marker string) {
+	if n < 0 {
+		s.abortScript("MaxOutputRecord was passed a negative length (%d)", n)
+	}
+	s.maxOutRec = n
+	s.truncMark = marker
+}
+
+// EscapeControl specifies whether control characters should be escaped in
+// records output by Println or the default print action.  With
+// EscapeControl(true), the usual C escapes (\n, \r, \b, \f, \v, \a, and \0)
+// replace the corresponding characters, and \xHH replaces any other C0 or C1
+// control character, DEL, or byte that is not part of a valid UTF-8
+// sequence.  Tabs, which commonly separate fields, are output as is, as are
+// backslashes.  Escaping is performed before MaxOutputRecord truncates the
+// record.  The default is EscapeControl(false).
+func (s *Script) EscapeControl(esc bool) {
+	s.escCtl = esc
+}
+
+// safeOutput applies the EscapeControl and MaxOutputRecord settings to the
+// text of an output record.
+func (s *Script) safeOutput(text string) string {
+	if s.escCtl {
+		text = escapeControl(text)
+	}
+	if s.maxOutRec > 0 && len(text) > s.maxOutRec {
+		n := s.maxOutRec
+		mark := s.truncMark
+		if len(mark) > n {
+			mark = ""
+		}
+		n -= len(mark)
+		for n > 0 && !utf8.RuneStart(text[n]) {
+			n--
+		}
+		text = text[:n] + mark
+	}
+	return text
+}
+
+// escapeControl escapes all control characters except tab as well as all
+// invalid UTF-8 bytes in a string.
+func escapeControl(str string) string {
+	var sb strings.Builder
+	for i := 0; i < len(str); {
+		r, sz := utf8.DecodeRuneInString(str[i:])
+		switch {
+		case r == utf8.RuneError && sz == 1:
+			fmt.Fprintf(&sb, `\x%02x`, str[i])
+		case r == '\t':
+			sb.WriteByte('\t')
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\b':
+			sb.WriteString(`\b`)
+		case r == '\f':
+			sb.WriteString(`\f`)
+		case r == '\v':
+			sb.WriteString(`\v`)
+		case r == '\a':
+			sb.WriteString(`\a`)
+		case r == 0:
+			sb.WriteString(`\0`)
+		case r < 0x20 || (r >= 0x7f && r < 0xa0):
+			fmt.Fprintf(&sb, `\x%02x`, r)
+		default:
+			sb.WriteString(str[i : i+sz])
+		}
+		i += sz
+	}
+	return sb.String()
+}
@@ -0,0 +1,49 @@
+// This file tests the output safety options.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestMaxOutputRecord tests truncating long output records.
+func TestMaxOutputRecord(t *testing.T) {
+	var out bytes.Buffer
+	scr := NewScript()
+	scr.Output = &out
+	scr.MaxOutputRecord(8, "...")
+	scr.AppendStmt(func(s *Script) bool { return s.NR == 3 }, func(s *Script) {
+		s.Println(s.F(1), "ünïcødé")
+	})
+	scr.AppendStmt(func(s *Script) bool { return s.NR != 3 }, nil)
+	err := scr.Run(strings.NewReader("short\nsomewhat longer\nab\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "short\nsomew...\nab ü...\n"
+	if got := out.String(); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
+
+// TestEscapeControl tests escaping control characters in output records.
+func TestEscapeControl(t *testing.T) {
+	var out bytes.Buffer
+	scr := NewScript()
+	scr.Output = &out
+	scr.SetFS(",")
+	scr.EscapeControl(true)
+	scr.MaxOutputRecord(12, "~")
+	scr.AppendStmt(nil, func(s *Script) { s.Println() })
+	scr.AppendStmt(nil, nil)
+	err := scr.Run(strings.NewReader("a\tb\x1b[0m,\xff\x00\r\nlong,ok\x7f\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a\tb\\x1b[0m ~\na\tb\\x1b[0m,~\nlong ok\\x7f\nlong,ok\\x7f\n"
+	if got := out.String(); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
@@ -0,0 +1,122 @@
+// This file provides constructors for stateful PatternFunc functions that
+// encapsulate commonly needed per-record carried state.
+
+package awk
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// timeCheck returns a PatternFunc that parses a timestamp from a given field
+// and passes the difference between it and the previous record's timestamp
+// to a test function.  Records whose timestamp can't be parsed never match and
+// do not affect the state.  The state is reset at the start of each run.
+func timeCheck(field int, layout string, test func(gap time.Duration) bool) PatternFunc {
+	var prev time.Time // Previous timestamp
+	havePrev := false  // true=prev is valid; false=no previous timestamp
+	prevNR := 0        // Previous value of NR
+	return func(s *Script) bool {
+		// Forget the previous timestamp when a new run begins.
+		if s.NR <= prevNR {
+			havePrev = false
+		}
+		prevNR = s.NR
+
+		// Parse the current timestamp.
+		t, err := time.Parse(layout, s.F(field).String())
+		if err != nil {
+			return false
+		}
+
+		// Compare it to the previous timestamp.
+		if !havePrev {
+			prev, havePrev = t, true
+			return false
+		}
+		gap := t.Sub(prev)
+		if t.After(prev) {
+			prev = t
+		}
+		return test(gap)
+	}
+}
+
+// TimeGap returns a PatternFunc that parses a timestamp from a given field
+// using a time.Parse layout and matches a record if more than maxGap has
+// elapsed since the latest timestamp seen so far or if the record's timestamp
+// precedes that timestamp (i.e., the record arrived out of order).  Records
+// whose timestamp can't be parsed never match.
+func TimeGap(field int, layout string, maxGap time.Duration) PatternFunc {
+	return timeCheck(field, layout, func(gap time.Duration) bool {
+		return gap > maxGap || gap < 0
+	})
+}
+
+// OutOfOrder returns a PatternFunc that parses a timestamp from a given field
+// using a time.Parse layout and matches a record if its timestamp precedes
+// the latest timestamp seen so far.  Records whose timestamp can't be parsed
+// never match.
+func OutOfOrder(field int, layout string) PatternFunc {
+	return timeCheck(field, layout, func(gap time.Duration) bool {
+		return gap < 0
+	})
+}
+
+// maxPatternKeys bounds the number of keys remembered by the PatternFunc
+// functions returned by FirstSeen and Changed.
+const maxPatternKeys = 1 << 20
+
+// hashString returns a 64-bit hash of a string.
+func hashString(str string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(str))
+	return h.Sum64()
+}
+
+// A boundedMap maps hashes of keys to hashes of values.  It holds at most
+// maxPatternKeys entries, discarding an arbitrary entry when full.
+type boundedMap map[uint64]uint64
+
+// set associates a value hash with a key hash.
+func (bm boundedMap) set(k, v uint64) {
+	if _, found := bm[k]; !found && len(bm) >= maxPatternKeys {
+		for old := range bm {
+			delete(bm, old)
+			break
+		}
+	}
+	bm[k] = v
+}
+
+// FirstSeen returns a PatternFunc that matches a record only if the contents
+// of the given field have not appeared in that field in any previous record.
+// Keys are remembered across runs.  To bound memory usage, only a 64-bit hash
+// of each key is retained, and at most about a million keys are remembered;
+// beyond that, a forgotten key may match again.
+func FirstSeen(keyField int) PatternFunc {
+	seen := make(boundedMap)
+	return func(s *Script) bool {
+		k := hashString(s.F(keyField).String())
+		if _, found := seen[k]; found {
+			return false
+		}
+		seen.set(k, 0)
+		return true
+	}
+}
+
+// Changed returns a PatternFunc that matches a record if the contents of
+// valField differ from those in the most recent previous record with the same
+// contents of keyField.  The first record with a given key does not match.
+// Keys are remembered across runs.  Memory usage is bounded as in FirstSeen.
+func Changed(keyField, valField int) PatternFunc {
+	last := make(boundedMap)
+	return func(s *Script) bool {
+		k := hashString(s.F(keyField).String())
+		v := hashString(s.F(valField).String())
+		prev, found := last[k]
+		last.set(k, v)
+		return found && prev != v
+	}
+}
@@ -0,0 +1,85 @@
+// This file tests stateful pattern constructors.
+
+package awk
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTimeGap tests detecting gaps between and misordering of timestamps.
+func TestTimeGap(t *testing.T) {
+	input := `10:00:00 a
+10:00:30 b
+10:05:00 c
+10:04:00 d
+10:05:10 e
+garbage f
+10:20:00 g
+`
+	var gaps, ooo []string
+	scr := NewScript()
+	scr.AppendStmt(TimeGap(1, "15:04:05", time.Minute), func(s *Script) {
+		gaps = append(gaps, s.F(2).String())
+	})
+	scr.AppendStmt(OutOfOrder(1, "15:04:05"), func(s *Script) {
+		ooo = append(ooo, s.F(2).String())
+	})
+
+	// Run the script twice to ensure that the state is reset between
+	// runs.
+	for i := 0; i < 2; i++ {
+		gaps, ooo = nil, nil
+		err := scr.Run(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(gaps, " "); got != "c d g" {
+			t.Fatalf("Expected %q but received %q", "c d g", got)
+		}
+		if got := strings.Join(ooo, " "); got != "d" {
+			t.Fatalf("Expected %q but received %q", "d", got)
+		}
+	}
+}
+
+// TestFirstSeen tests matching only the first occurrence of each key.
+func TestFirstSeen(t *testing.T) {
+	var got []string
+	scr := NewScript()
+	scr.AppendStmt(FirstSeen(1), func(s *Script) { got = append(got, s.F(2).String()) })
+	err := scr.Run(strings.NewReader("web1 a\ndb1 b\nweb1 c\nweb2 d\ndb1 e\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = scr.Run(strings.NewReader("web2 f\nweb3 g\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(got, " "); s != "a b d g" {
+		t.Fatalf("Expected %q but received %q", "a b d g", s)
+	}
+}
+
+// TestChanged tests matching when a key's value changes.
+func TestChanged(t *testing.T) {
+	var got []string
+	scr := NewScript()
+	scr.AppendStmt(Changed(1, 2), func(s *Script) { got = append(got, s.F(3).String()) })
+	input := `web1 v1 a
+db1 v7 b
+web1 v1 c
+web1 v2 d
+db1 v7 e
+web1 v1 f
+db1 v8 g
+`
+	err := scr.Run(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(got, " "); s != "d f g" {
+		t.Fatalf("Expected %q but received %q", "d f g", s)
+	}
+}
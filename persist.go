@@ -0,0 +1,105 @@
+// This file lets a ValueArray be saved to and loaded from a file so that an
+// aggregation can be carried from one run of a program to another.
+
+package awk
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+// savedArrayVersion is the version number of the format written by Save.
+// Version 2 added arbitrary-precision numbers; Load accepts either version.
+const savedArrayVersion = 2
+
+// A savedArray is the form in which Save writes a ValueArray.
+type savedArray struct {
+	Version  int            // Format version (savedArrayVersion)
+	Elements []savedElement // Elements in the array's iteration order
+}
+
+// A savedElement is a single element of a savedArray.
+type savedElement struct {
+	Key   string         // Index string
+	Value []byte         // Scalar, as encoded by encodeValue (nil for a subarray)
+	Sub   []savedElement // Elements of a subarray
+}
+
+// saveElements converts a ValueArray's elements to savedElements.
+func (va *ValueArray) saveElements() ([]savedElement, error) {
+	keys := va.orderedKeys()
+	elts := make([]savedElement, len(keys))
+	for i, k := range keys {
+		v := va.data[k]
+		elts[i].Key = k
+		if v.aval != nil {
+			sub, err := v.aval.saveElements()
+			if err != nil {
+				return nil, err
+			}
+			elts[i].Sub = sub
+			continue
+		}
+		b, err := encodeValue(v)
+		if err != nil {
+			return nil, err
+		}
+		elts[i].Value = b
+	}
+	return elts, nil
+}
+
+// Save writes the contents of a ValueArray, including all of its subarrays,
+// to an io.Writer in a form that Load can read back, using encoding/gob.
+// Each Value's type (number, arbitrary-precision number, string, or numeric
+// string) and precision are preserved, as is the order in which the elements
+// would be returned by Keys.  Save and Load
+// let an aggregation built by one run be checkpointed and then resumed or
+// combined with a later run's, as in incremental processing of daily logs.
+func (va *ValueArray) Save(w io.Writer) error {
+	elts, err := va.saveElements()
+	if err != nil {
+		return err
+	}
+	return gob.NewEncoder(w).Encode(savedArray{
+		Version:  savedArrayVersion,
+		Elements: elts,
+	})
+}
+
+// loadElements stores savedElements in a ValueArray.
+func (va *ValueArray) loadElements(elts []savedElement) error {
+	for _, e := range elts {
+		if e.Value == nil {
+			sub := va.script.NewValueArray()
+			if err := sub.loadElements(e.Sub); err != nil {
+				return err
+			}
+			va.store(e.Key, va.script.NewValue(sub))
+			continue
+		}
+		v, err := va.script.decodeValue(e.Value)
+		if err != nil {
+			return err
+		}
+		va.store(e.Key, v)
+	}
+	return nil
+}
+
+// Load reads a ValueArray written by Save from an io.Reader and stores its
+// elements in a ValueArray, replacing any existing elements with the same
+// indexes and leaving all others intact.  To combine a saved aggregation with
+// a new one more selectively, Load it into an empty ValueArray, then use
+// Merge.
+func (va *ValueArray) Load(r io.Reader) error {
+	var sa savedArray
+	if err := gob.NewDecoder(r).Decode(&sa); err != nil {
+		return err
+	}
+	if sa.Version < 1 || sa.Version > savedArrayVersion {
+		return fmt.Errorf("Saved ValueArray has unsupported version %d", sa.Version)
+	}
+	return va.loadElements(sa.Elements)
+}
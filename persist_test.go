@@ -0,0 +1,73 @@
+// This file tests saving and loading ValueArrays.
+
+package awk
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// TestSaveLoad tests that a ValueArray survives a round trip through Save
+// and Load.
+func TestSaveLoad(t *testing.T) {
+	scr := NewScript()
+	va := scr.NewValueArray().SetOrder(OrderInsertion)
+	va.Set("zebra", 3)
+	va.Set("apple", 2.5)
+	va.Set("name", "Bob")
+	va.Set("input", scr.newInputValue("042"))
+	bi, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	va.Set("big", bi)
+	va.Set("a", "b", "multi")
+	sub := scr.NewValueArray()
+	sub.Set("inner", 7)
+	sub.Set("empty", scr.NewValueArray())
+	va.Set("sub", sub)
+
+	var buf bytes.Buffer
+	if err := va.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	ld := scr.NewValueArray().SetOrder(OrderInsertion)
+	ld.Set("other", "kept")
+	ld.Set("zebra", "replaced")
+	if err := ld.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Check the contents and types.
+	if v := ld.Get("zebra"); !v.IsInt() || v.Int() != 3 {
+		t.Fatalf("Expected the integer 3 but received %q", v)
+	}
+	if v := ld.Get("apple"); v.Kind() != KindFloat || v.Float64() != 2.5 {
+		t.Fatalf("Expected the float 2.5 but received %q", v)
+	}
+	if v := ld.Get("name"); v.Kind() != KindString {
+		t.Fatalf("Expected a string but received %v", v.Kind())
+	}
+	if v := ld.Get("input"); !v.IsStrNum() || v.String() != "042" {
+		t.Fatalf("Expected the numeric string %q but received %q", "042", v)
+	}
+	if v := ld.Get("big"); v.BigInt().Cmp(bi) != 0 || v.String() != bi.String() {
+		t.Fatalf("Expected %v but received %q", bi, v)
+	}
+	if got := ld.Get("a", "b").String(); got != "multi" {
+		t.Fatalf("Expected %q but received %q", "multi", got)
+	}
+	if got := ld.SubArray("sub").Get("inner").Int(); got != 7 {
+		t.Fatalf("Expected 7 but received %d", got)
+	}
+	if _, ok := ld.SubArray("sub").GetArray("empty"); !ok {
+		t.Fatal("Expected an empty subarray")
+	}
+	if got, want := joinValues(ld.Keys()), "other zebra apple name input big a\034b sub"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// Garbage should be rejected.
+	if err := ld.Load(strings.NewReader("not gob")); err == nil {
+		t.Fatal("Expected an error loading garbage")
+	}
+}
@@ -0,0 +1,63 @@
+// This file provides operations on all elements of a simulated
+// multidimensional ValueArray that share a leading index.
+
+package awk
+
+import (
+	"sort"
+	"strings"
+)
+
+// prefixRange returns the string index of a ValueArray and the range of
+// positions within it of the keys that begin with the given indexes followed
+// by the subscript separator.
+func (va *ValueArray) prefixRange(prefix []interface{}) (*arrayIndex, int, int) {
+	p := va.key(prefix) + va.subSep()
+	idx := va.buildIndex(false)
+	first := sort.SearchStrings(idx.keys, p)
+	last := first
+	for last < len(idx.keys) && strings.HasPrefix(idx.keys[last], p) {
+		last++
+	}
+	return idx, first, last
+}
+
+// DeletePrefix deletes from a simulated multidimensional ValueArray (see Set)
+// every element whose leading indexes equal the given indexes and returns the
+// number of elements deleted.  For example, DeletePrefix(i) deletes all
+// elements (i, j) and (i, j, k) but not the element (i) itself.  This is the
+// multidimensional analog of AWK's "delete arr[i]".  Indexes are specified as
+// in Get.  DeletePrefix locates the elements with a binary search over the
+// array's sorted keys, so repeated calls do not examine every key.
+func (va *ValueArray) DeletePrefix(prefix ...interface{}) int {
+	if len(prefix) < 1 {
+		panic("ValueArray.DeletePrefix requires at least one index")
+	}
+	idx, first, last := va.prefixRange(prefix)
+	for _, k := range idx.keys[first:last] {
+		va.remove(k)
+	}
+	idx.keys = append(idx.keys[:first], idx.keys[last:]...)
+	return last - first
+}
+
+// View returns a ValueArray containing every element of a simulated
+// multidimensional ValueArray whose leading indexes equal the given indexes,
+// with those indexes removed.  For example, if a contains elements ("x", 1),
+// ("x", 2), and ("y", 1), then a.View("x") contains elements 1 and 2.
+// Indexes are specified as in Get.  The view shares its Values with the
+// original array but not its keys: adding or deleting elements in either
+// array does not affect the other, so the view should be treated as a
+// read-only snapshot.
+func (va *ValueArray) View(prefix ...interface{}) *ValueArray {
+	if len(prefix) < 1 {
+		panic("ValueArray.View requires at least one index")
+	}
+	view := va.script.NewValueArray()
+	idx, first, last := va.prefixRange(prefix)
+	n := len(va.key(prefix) + va.subSep())
+	for _, k := range idx.keys[first:last] {
+		view.store(k[n:], va.data[k])
+	}
+	return view
+}
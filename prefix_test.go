@@ -0,0 +1,56 @@
+// This file tests prefix operations on multidimensional arrays.
+
+package awk
+
+import "testing"
+
+// TestDeletePrefix tests deleting all elements with a given leading index.
+func TestDeletePrefix(t *testing.T) {
+	scr := NewScript()
+	a := scr.NewValueArray()
+	a.Set("x", 1, "a")
+	a.Set("x", 2, "b")
+	a.Set("x", 2, 9, "c")
+	a.Set("x", "x itself")
+	a.Set("xy", 1, "d")
+	a.Set("y", 1, "e")
+	if n := a.DeletePrefix("x"); n != 3 {
+		t.Fatalf("Expected 3 deletions but received %d", n)
+	}
+	want := "x xy\0341 y\0341"
+	if got := joinValues(a.SortedKeys(nil)); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	if n := a.DeletePrefix("y", 1); n != 0 {
+		t.Fatalf("Expected 0 deletions but received %d", n)
+	}
+	if n := a.DeletePrefix("y"); n != 1 {
+		t.Fatalf("Expected 1 deletion but received %d", n)
+	}
+	a.Set("y", 2, "f")
+	if got, want := a.View("y").Get(2).String(), "f"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
+
+// TestView tests viewing all elements with a given leading index.
+func TestView(t *testing.T) {
+	scr := NewScript()
+	sales := scr.NewValueArray()
+	sales.Set("east", "jan", 10)
+	sales.Set("east", "feb", 20)
+	sales.Set("east", "mar", "q1", 5)
+	sales.Set("west", "jan", 30)
+	east := sales.View("east")
+	want := "feb jan mar\034q1"
+	if got := joinValues(east.SortedKeys(nil)); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	if got := east.View("mar").Get("q1").Int(); got != 5 {
+		t.Fatalf("Expected 5 but received %d", got)
+	}
+	east.Delete("feb")
+	if !sales.Exists("east", "feb") {
+		t.Fatal("Deleting from a view affected the original array")
+	}
+}
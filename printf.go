@@ -0,0 +1,226 @@
+// This file implements AWK-style formatted output.
+
+package awk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// looksNumeric says whether a Value is a number or a string that consists
+// entirely of a number, as with an AWK "strnum".
+func (v *Value) looksNumeric() bool {
+	if !v.svalOk {
+		return true
+	}
+	_, err := strconv.ParseFloat(strings.TrimSpace(v.sval), 64)
+	return err == nil
+}
+
+// truncInt converts a Value to an int, truncating any fractional part.  Unlike
+// Int, truncInt honors exponents in strings (e.g., "1e3").
+func (v *Value) truncInt() int {
+	if v.ivalOk {
+		return v.ival
+	}
+	return int(v.Float64())
+}
+
+// Sprintf formats its arguments according to a format string using AWK's
+// printf conversions and returns the result as a Value.  Each argument can be
+// provided either as a Value or as any type that can be converted to a Value
+// and is coerced as appropriate for its conversion:
+//
+// • %d and %i format an integer (truncating any fractional part), and %o, %x,
+// %X, and %u format an integer in octal, hexadecimal, or decimal.
+//
+// • %e, %E, %f, %F, %g, and %G format a floating-point number.
+//
+// • %s formats a string.
+//
+// • %c formats a number as the character with that code point and a string
+// as its first character.
+//
+// • %% produces a literal percent sign.
+//
+// Conversions accept the flags "-", "+", " ", "#", and "0", a width, and a
+// precision, as in AWK.  A width or precision of "*" is taken from the next
+// argument.  Missing arguments are treated as empty strings (which are zero
+// when converted to numbers), and unrecognized conversions are output
+// verbatim.
+func (s *Script) Sprintf(format string, args ...interface{}) *Value {
+	// Define a function that returns the next argument as a Value.
+	var raw interface{} // Most recent argument before conversion to a Value
+	nextArg := func() *Value {
+		if len(args) == 0 {
+			raw = ""
+			return s.NewValue("")
+		}
+		raw = args[0]
+		args = args[1:]
+		if v, ok := raw.(*Value); ok {
+			return v
+		}
+		return s.NewValue(raw)
+	}
+
+	// Process the format string one conversion at a time.
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		// Copy non-conversion characters verbatim.
+		if format[i] != '%' {
+			sb.WriteByte(format[i])
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			sb.WriteByte('%')
+			i++
+			continue
+		}
+
+		// Parse the flags, width, and precision into a Go format
+		// specifier.
+		spec := []byte{'%'}
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-+ #0", format[j]) >= 0 {
+			spec = append(spec, format[j])
+			j++
+		}
+		for _, dot := range []bool{false, true} {
+			if dot {
+				if j >= len(format) || format[j] != '.' {
+					break
+				}
+				spec = append(spec, '.')
+				j++
+			}
+			if j < len(format) && format[j] == '*' {
+				// As in AWK, a negative width implies
+				// left justification, and a negative
+				// precision is ignored.
+				n := nextArg().truncInt()
+				switch {
+				case n >= 0:
+					spec = strconv.AppendInt(spec, int64(n), 10)
+				case dot:
+					spec = spec[:len(spec)-1]
+				default:
+					spec = append(spec, '-')
+					spec = strconv.AppendInt(spec, int64(-n), 10)
+				}
+				j++
+				continue
+			}
+			for j < len(format) && format[j] >= '0' && format[j] <= '9' {
+				spec = append(spec, format[j])
+				j++
+			}
+		}
+		if j >= len(format) {
+			sb.WriteString(format[i:])
+			break
+		}
+
+		// Format the next argument according to the conversion
+		// character.
+		switch c := format[j]; c {
+		case 'd', 'i', 'u':
+			sb.WriteString(fmt.Sprintf(string(append(spec, 'd')), nextArg().truncInt()))
+		case 'o', 'x', 'X':
+			sb.WriteString(fmt.Sprintf(string(append(spec, c)), nextArg().truncInt()))
+		case 'e', 'E', 'f', 'F', 'g', 'G':
+			if c == 'F' {
+				c = 'f'
+			}
+			sb.WriteString(fmt.Sprintf(string(append(spec, c)), nextArg().Float64()))
+		case 's':
+			sb.WriteString(fmt.Sprintf(string(append(spec, 's')), nextArg().String()))
+		case 'c':
+			v := nextArg()
+			_, isStr := raw.(string)
+			ch := ""
+			if !isStr && v.looksNumeric() {
+				ch = string(rune(v.truncInt()))
+			} else if r, w := utf8.DecodeRuneInString(v.String()); w > 0 {
+				ch = string(r)
+			}
+			sb.WriteString(fmt.Sprintf(string(append(spec, 's')), ch))
+		default:
+			sb.WriteString(format[i : j+1])
+		}
+		i = j
+	}
+	return s.NewValue(sb.String())
+}
+
+// Printf formats its arguments according to a format string using AWK's
+// printf conversions, as described for Sprintf, and writes the result to the
+// script's Output stream.
+func (s *Script) Printf(format string, args ...interface{}) {
+	fmt.Fprint(s.Output, s.Sprintf(format, args...))
+}
+
+// Format implements fmt.Formatter so that a Value passed to the fmt package's
+// printing functions is converted as appropriate for the verb: %d, %b, %o,
+// %O, %x, %X, %c, and %U format the Value as an integer (truncating any
+// fractional part); %e, %E, %f, %F, %g, and %G format it as a floating-point
+// number; and %s, %q, %v, and all other verbs format it as a string.  As an
+// exception, %x and %X format a Value that does not look like a number as a
+// string, in hexadecimal.  Flags, width, and precision are honored, so, for
+// example, fmt.Printf("%05d", s.F(2)) zero-pads an integral field.
+func (v *Value) Format(f fmt.State, verb rune) {
+	// Reconstruct the format specifier.
+	spec := []byte{'%'}
+	for _, flag := range "+-# 0" {
+		if f.Flag(int(flag)) && !(verb == 'v' && (flag == '+' || flag == '#')) {
+			spec = append(spec, byte(flag))
+		}
+	}
+	if wid, ok := f.Width(); ok {
+		spec = strconv.AppendInt(spec, int64(wid), 10)
+	}
+	if prec, ok := f.Precision(); ok {
+		spec = append(spec, '.')
+		spec = strconv.AppendInt(spec, int64(prec), 10)
+	}
+	spec = append(spec, string(verb)...)
+
+	// Convert the Value as appropriate for the verb.
+	var arg interface{}
+	switch verb {
+	case 'x', 'X':
+		if !v.looksNumeric() {
+			arg = v.String()
+			break
+		}
+		fallthrough
+	case 'd', 'b', 'o', 'O', 'U':
+		if v.isBig() {
+			arg = v.BigInt()
+		} else {
+			arg = v.truncInt()
+		}
+	case 'c':
+		if v.looksNumeric() {
+			arg = rune(v.truncInt())
+		} else {
+			r, _ := utf8.DecodeRuneInString(v.String())
+			arg = r
+		}
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		if v.bflt != nil {
+			arg = v.bflt
+		} else {
+			arg = v.Float64()
+		}
+	case 'v':
+		// Format %v, %+v, and %#v as a plain string, not Go syntax.
+		spec[len(spec)-1] = 's'
+		arg = v.String()
+	default:
+		arg = v.String()
+	}
+	fmt.Fprintf(f, string(spec), arg)
+}
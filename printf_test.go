@@ -0,0 +1,87 @@
+// This file tests AWK-style formatted output.
+
+package awk
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestSprintf tests formatting values with AWK printf conversions.
+func TestSprintf(t *testing.T) {
+	scr := NewScript()
+	tests := []struct {
+		format string        // Format string
+		args   []interface{} // Arguments
+		want   string        // Expected output
+	}{
+		{"%d items", []interface{}{"42abc"}, "42 items"},
+		{"%i|%d", []interface{}{3.99, "1e3"}, "3|1000"},
+		{"%5.2f|%-6s|", []interface{}{scr.NewValue("3.14159"), "ab"}, " 3.14|ab    |"},
+		{"%*d|%-*d|", []interface{}{5, 42, 4, 7}, "   42|7   |"},
+		{"%*d|", []interface{}{-4, 7}, "7   |"},
+		{"%.*f", []interface{}{1, 2.25}, "2.2"},
+		{"%c%c%c", []interface{}{65, "hello", scr.NewValue("66")}, "AhB"},
+		{"%c", []interface{}{"66"}, "6"},
+		{"%x %X %o %u", []interface{}{255, 255, 8, 17}, "ff FF 10 17"},
+		{"%e %G", []interface{}{12345.678, 0.0001}, "1.234568e+04 0.0001"},
+		{"%05.1f%%", []interface{}{scr.NewValue(9.87)}, "009.9%"},
+		{"%s and %s", []interface{}{"one"}, "one and "},
+		{"%.3s", []interface{}{"日本語です"}, "日本語"},
+		{"%q %", []interface{}{1}, "%q %"},
+	}
+	for _, tc := range tests {
+		if got := scr.Sprintf(tc.format, tc.args...).String(); got != tc.want {
+			t.Fatalf("Expected %q for %q but received %q", tc.want, tc.format, got)
+		}
+	}
+}
+
+// TestPrintf tests writing formatted output to the script's output stream.
+func TestPrintf(t *testing.T) {
+	var out bytes.Buffer
+	scr := NewScript()
+	scr.Output = &out
+	scr.AppendStmt(nil, func(s *Script) { s.Printf("%-3s|%3d\n", s.F(1), s.F(2)) })
+	err := scr.Run(strings.NewReader("a 1\nbb 22.5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "a  |  1\nbb | 22\n"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
+
+// TestValueFormat tests that the fmt package coerces Values as appropriate
+// for each verb.
+func TestValueFormat(t *testing.T) {
+	scr := NewScript()
+	tests := []struct {
+		format string
+		arg    *Value
+		want   string
+	}{
+		{"%d", scr.NewValue("42.9"), "42"},
+		{"%05d", scr.NewValue(" 7 "), "00007"},
+		{"%+d", scr.NewValue(3), "+3"},
+		{"%X", scr.NewValue(255), "FF"},
+		{"%x", scr.NewValue("hi"), "6869"},
+		{"%c", scr.NewValue(65), "A"},
+		{"%c", scr.NewValue("hello"), "h"},
+		{"%.2f", scr.NewValue("3.14159"), "3.14"},
+		{"%8.3e", scr.NewValue(1234), "1.234e+03"},
+		{"%g", scr.NewValue("abc"), "0"},
+		{"%-5s|", scr.NewValue(12), "12   |"},
+		{"%q", scr.NewValue("a\tb"), `"a\tb"`},
+		{"%v", scr.NewValue(2.5), "2.5"},
+		{"%+v", scr.NewValue("x"), "x"},
+		{"%4v", scr.NewValue("x"), "   x"},
+	}
+	for _, tc := range tests {
+		if got := fmt.Sprintf(tc.format, tc.arg); got != tc.want {
+			t.Fatalf("Expected %q for %q but received %q", tc.want, tc.format, got)
+		}
+	}
+}
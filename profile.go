@@ -0,0 +1,281 @@
+// This file lets a script profile the columns of its input, inferring each
+// column's apparent type and gathering summary statistics.
+
+package awk
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A ColumnType is the apparent data type of a column of input.
+type ColumnType int
+
+// The following are the possibilities for a ColumnType, from most to least
+// specific.
+const (
+	IntColumn    ColumnType = iota // All non-empty values are integers
+	FloatColumn                    // All non-empty values are numbers
+	DateColumn                     // All non-empty values are dates or times
+	StringColumn                   // Values are arbitrary strings
+)
+
+// String returns the name of a ColumnType.
+func (ct ColumnType) String() string {
+	switch ct {
+	case IntColumn:
+		return "int"
+	case FloatColumn:
+		return "float"
+	case DateColumn:
+		return "date"
+	case StringColumn:
+		return "string"
+	default:
+		return fmt.Sprintf("ColumnType(%d)", int(ct))
+	}
+}
+
+// dateLayouts lists the formats that profiling recognizes as dates.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.ANSIC,
+}
+
+// parseDate attempts to parse a string as a date using each of dateLayouts in
+// turn.
+func parseDate(str string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// sketchSize is the number of hashes retained for estimating the number of
+// distinct values in a column.
+const sketchSize = 256
+
+// A distinctSketch estimates the number of distinct values in a stream using
+// bounded memory.  It retains the sketchSize smallest hash values seen so far
+// (a "k minimum values" sketch).
+type distinctSketch struct {
+	hashes []uint64 // Smallest hash values seen so far, sorted
+}
+
+// add incorporates a string into the sketch.
+func (ds *distinctSketch) add(str string) {
+	h := fnv.New64a()
+	h.Write([]byte(str))
+	hv := h.Sum64()
+	i := sort.Search(len(ds.hashes), func(i int) bool { return ds.hashes[i] >= hv })
+	switch {
+	case i < len(ds.hashes) && ds.hashes[i] == hv:
+		// Already present
+	case len(ds.hashes) < sketchSize:
+		ds.hashes = append(ds.hashes, 0)
+		copy(ds.hashes[i+1:], ds.hashes[i:])
+		ds.hashes[i] = hv
+	case i < len(ds.hashes):
+		copy(ds.hashes[i+1:], ds.hashes[i:len(ds.hashes)-1])
+		ds.hashes[i] = hv
+	}
+}
+
+// estimate returns the estimated number of distinct strings added to the
+// sketch.  The estimate is exact for fewer than sketchSize distinct strings.
+func (ds *distinctSketch) estimate() int {
+	if len(ds.hashes) < sketchSize {
+		return len(ds.hashes)
+	}
+	frac := float64(ds.hashes[sketchSize-1]) / math.MaxUint64
+	return int(math.Round(float64(sketchSize-1) / frac))
+}
+
+// A columnStats accumulates statistics about a single column.
+type columnStats struct {
+	nonNull  int            // Number of non-empty values
+	ints     int            // Number of values that parse as integers
+	floats   int            // Number of values that parse as numbers
+	dates    int            // Number of values that parse as dates
+	minNum   string         // Value with the smallest numeric value
+	maxNum   string         // Value with the largest numeric value
+	minNumF  float64        // Numeric value of minNum
+	maxNumF  float64        // Numeric value of maxNum
+	minDate  string         // Value with the earliest date
+	maxDate  string         // Value with the latest date
+	minDateT time.Time      // Date represented by minDate
+	maxDateT time.Time      // Date represented by maxDate
+	minStr   string         // Lexicographically smallest value
+	maxStr   string         // Lexicographically largest value
+	distinct distinctSketch // Estimator of the number of distinct values
+}
+
+// observe incorporates a single value into a column's statistics.
+func (cs *columnStats) observe(str string) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return
+	}
+	cs.nonNull++
+	cs.distinct.add(str)
+
+	// Track the lexicographic range of values.
+	if cs.nonNull == 1 || str < cs.minStr {
+		cs.minStr = str
+	}
+	if cs.nonNull == 1 || str > cs.maxStr {
+		cs.maxStr = str
+	}
+
+	// Track the numeric range of values.
+	if _, err := strconv.ParseInt(str, 10, 64); err == nil {
+		cs.ints++
+	}
+	if f, err := strconv.ParseFloat(str, 64); err == nil {
+		cs.floats++
+		if cs.floats == 1 || f < cs.minNumF {
+			cs.minNum, cs.minNumF = str, f
+		}
+		if cs.floats == 1 || f > cs.maxNumF {
+			cs.maxNum, cs.maxNumF = str, f
+		}
+	}
+
+	// Track the chronological range of values.
+	if t, ok := parseDate(str); ok {
+		cs.dates++
+		if cs.dates == 1 || t.Before(cs.minDateT) {
+			cs.minDate, cs.minDateT = str, t
+		}
+		if cs.dates == 1 || t.After(cs.maxDateT) {
+			cs.maxDate, cs.maxDateT = str, t
+		}
+	}
+}
+
+// A profiler accumulates statistics about all columns of the input.
+type profiler struct {
+	records int            // Number of records observed
+	columns []*columnStats // Statistics for each column, starting from column 1
+}
+
+// observe incorporates the current record into the profile.
+func (p *profiler) observe(s *Script) {
+	p.records++
+	for len(p.columns) < s.NF {
+		p.columns = append(p.columns, &columnStats{})
+	}
+	for i := 1; i <= s.NF; i++ {
+		p.columns[i-1].observe(s.F(i).String())
+	}
+}
+
+// A ColumnProfile summarizes the contents of a single column of input.  Min
+// and Max are compared numerically for IntColumn and FloatColumn,
+// chronologically for DateColumn, and lexicographically for StringColumn.
+type ColumnProfile struct {
+	Column   int        // 1-based column number
+	Type     ColumnType // Apparent type of the column
+	Nulls    int        // Number of records in which the column is empty or missing
+	NullRate float64    // Fraction of records in which the column is empty or missing
+	Min      *Value     // Smallest non-empty value
+	Max      *Value     // Largest non-empty value
+	Distinct int        // Approximate number of distinct non-empty values
+}
+
+// A ProfileReport summarizes the columns of an input stream.
+type ProfileReport struct {
+	Records int             // Number of records profiled
+	Columns []ColumnProfile // Profile of each column
+}
+
+// String formats a ProfileReport as a table with one row per column.
+func (pr *ProfileReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%6s  %-6s  %8s  %8s  %8s  %-20s  %-20s\n",
+		"Column", "Type", "Nulls", "NullRate", "Distinct", "Min", "Max")
+	for _, c := range pr.Columns {
+		fmt.Fprintf(&sb, "%6d  %-6s  %8d  %8.3f  %8d  %-20v  %-20v\n",
+			c.Column, c.Type, c.Nulls, c.NullRate, c.Distinct, c.Min, c.Max)
+	}
+	fmt.Fprintf(&sb, "%d records\n", pr.Records)
+	return sb.String()
+}
+
+// Profile enables or disables profiling of the input.  When profiling is
+// enabled, Run infers each column's apparent type and gathers summary
+// statistics about its contents.  These can be retrieved from the End action
+// (or after Run returns) by calling ProfileReport.
+func (s *Script) Profile(on bool) {
+	switch {
+	case !on:
+		s.prof = nil
+	case s.prof == nil:
+		s.prof = &profiler{}
+	}
+}
+
+// ProfileReport returns a summary of the columns of the input seen so far.
+// It returns nil if profiling is not enabled.
+func (s *Script) ProfileReport() *ProfileReport {
+	p := s.prof
+	if p == nil {
+		return nil
+	}
+	rpt := &ProfileReport{
+		Records: p.records,
+		Columns: make([]ColumnProfile, len(p.columns)),
+	}
+	for i, cs := range p.columns {
+		cp := ColumnProfile{
+			Column: i + 1,
+			Nulls:  p.records - cs.nonNull,
+		}
+		if p.records > 0 {
+			cp.NullRate = float64(cp.Nulls) / float64(p.records)
+		}
+		cp.Distinct = cs.distinct.estimate()
+		switch {
+		case cs.nonNull == 0:
+			cp.Type = StringColumn
+		case cs.ints == cs.nonNull:
+			cp.Type = IntColumn
+		case cs.floats == cs.nonNull:
+			cp.Type = FloatColumn
+		case cs.dates == cs.nonNull:
+			cp.Type = DateColumn
+		default:
+			cp.Type = StringColumn
+		}
+		switch {
+		case cs.nonNull == 0:
+			cp.Min = s.NewValue("")
+			cp.Max = s.NewValue("")
+		case cp.Type == DateColumn:
+			cp.Min = s.NewValue(cs.minDate)
+			cp.Max = s.NewValue(cs.maxDate)
+		case cp.Type == StringColumn:
+			cp.Min = s.NewValue(cs.minStr)
+			cp.Max = s.NewValue(cs.maxStr)
+		default:
+			cp.Min = s.NewValue(cs.minNum)
+			cp.Max = s.NewValue(cs.maxNum)
+		}
+		rpt.Columns[i] = cp
+	}
+	return rpt
+}
@@ -0,0 +1,71 @@
+// This file tests input profiling.
+
+package awk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestProfile tests inferring column types and gathering column statistics.
+func TestProfile(t *testing.T) {
+	// Profile a small CSV file.
+	input := `1,2.5,2020-01-02,apple
+2,-3,2019-12-31,banana
+,7e2,2020-03-04,cherry
+10,,2021-05-06,2
+3,0.5,,apple
+`
+	var rpt *ProfileReport
+	scr := NewScript()
+	scr.Begin = func(s *Script) {
+		s.SetFS(",")
+		s.Profile(true)
+	}
+	scr.AppendStmt(nil, func(s *Script) {})
+	scr.End = func(s *Script) { rpt = s.ProfileReport() }
+	err := scr.Run(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate the report.
+	if rpt == nil {
+		t.Fatal("Failed to produce a profile report")
+	}
+	if rpt.Records != 5 {
+		t.Fatalf("Expected 5 records but received %d", rpt.Records)
+	}
+	want := []string{
+		"1 int 1 0.2 4 1 10",
+		"2 float 1 0.2 4 -3 7e2",
+		"3 date 1 0.2 4 2019-12-31 2021-05-06",
+		"4 string 0 0 4 2 cherry",
+	}
+	if len(rpt.Columns) != len(want) {
+		t.Fatalf("Expected %d columns but received %d", len(want), len(rpt.Columns))
+	}
+	for i, c := range rpt.Columns {
+		got := fmt.Sprintf("%d %v %d %.1g %d %v %v",
+			c.Column, c.Type, c.Nulls, c.NullRate, c.Distinct, c.Min, c.Max)
+		if got != want[i] {
+			t.Fatalf("Expected %q but received %q", want[i], got)
+		}
+	}
+}
+
+// TestDistinctSketch tests estimating the number of distinct values in a
+// column.
+func TestDistinctSketch(t *testing.T) {
+	var ds distinctSketch
+	for rep := 0; rep < 3; rep++ {
+		for i := 0; i < 10000; i++ {
+			ds.add(fmt.Sprint(i))
+		}
+	}
+	est := ds.estimate()
+	if est < 8000 || est > 12000 {
+		t.Fatalf("Expected approximately 10000 but received %d", est)
+	}
+}
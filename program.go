@@ -0,0 +1,56 @@
+// This file separates the definition of a script from its execution.
+
+package awk
+
+import (
+	"io"
+)
+
+// A Program is an immutable, compiled form of a Script: its rules, its Begin
+// and End actions, and its configuration (record and field separators, case
+// sensitivity, and so forth).  Unlike a Script, a Program can safely be run
+// any number of times, including concurrently, because each run operates on
+// a separate execution state.  Note, however, that a Program cannot protect
+// state that is shared by the functions it comprises, such as that of the
+// PatternFunc functions returned by Range, FirstSeen, and Changed, a
+// DupTracker, a pseudonym table, or whatever is referenced by the State field.
+type Program struct {
+	proto *Script // Script from which each execution is copied
+}
+
+// Compile returns a Program that captures a Script's current rules and
+// configuration.  Subsequent modifications to the Script do not affect the
+// Program.  It is invalid to call Compile from a running script.
+func (s *Script) Compile() *Program {
+	if s.state != notRunning {
+		s.abortScript("Compile was called from a running script")
+	}
+	proto := s.Copy()
+	proto.freezeRegexps()
+	return &Program{proto: proto}
+}
+
+// newExecution returns a copy of a Script that shares none of the original's
+// per-run state.
+func (s *Script) newExecution() *Script {
+	return s.Clone()
+}
+
+// NewExecution returns a new Script that can run the Program.  The Script
+// begins with the Program's rules and configuration, and all of its per-run
+// state (NR, NF, fields, GetLine state, and so forth) is private to it.
+// Because a random-number source cannot be shared safely, each execution
+// starts with the default source; call SetRandSource on the execution to
+// override that.
+func (p *Program) NewExecution() *Script {
+	return p.proto.newExecution()
+}
+
+// Run executes a Program against a given input stream using a new execution
+// state.  It returns the Script that represents that state so the caller can
+// inspect the final values of NR, State, and the like.
+func (p *Program) Run(r io.Reader) (*Script, error) {
+	sc := p.NewExecution()
+	err := sc.Run(r)
+	return sc, err
+}
@@ -0,0 +1,63 @@
+// This file tests compiled programs.
+
+package awk
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestProgram tests running a compiled program concurrently on multiple
+// inputs.
+func TestProgram(t *testing.T) {
+	// Define and compile a script that sums its first column.
+	scr := NewScript()
+	scr.Begin = func(s *Script) {
+		s.SetFS(",")
+		s.State = 0
+	}
+	scr.AppendStmt(nil, func(s *Script) { s.State = s.State.(int) + s.F(1).Int() })
+	prog := scr.Compile()
+
+	// Modifying the script should not affect the program.
+	scr.SetFS(";")
+	scr.AppendStmt(nil, func(s *Script) { t.Fatal("Program ran a rule added after compilation") })
+
+	// Run the program concurrently on a number of inputs.
+	const nRuns = 20
+	var wg sync.WaitGroup
+	sums := make([]int, nRuns)
+	nrs := make([]int, nRuns)
+	errs := make([]error, nRuns)
+	for i := 0; i < nRuns; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var input strings.Builder
+			for j := 1; j <= i+1; j++ {
+				fmt.Fprintf(&input, "%d,x\n", j)
+			}
+			sc, err := prog.Run(strings.NewReader(input.String()))
+			errs[i] = err
+			if err == nil {
+				sums[i] = sc.State.(int)
+				nrs[i] = sc.NR
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Validate the results.
+	for i := 0; i < nRuns; i++ {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		n := i + 1
+		if sums[i] != n*(n+1)/2 || nrs[i] != n {
+			t.Fatalf("Expected sum %d and NR %d but received sum %d and NR %d",
+				n*(n+1)/2, n, sums[i], nrs[i])
+		}
+	}
+}
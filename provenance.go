@@ -0,0 +1,168 @@
+// This file lets the stages of a pipeline learn where in the original input
+// each record came from.
+
+package awk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// A Provenance describes the origin of a record in the original input to a
+// pipeline of scripts.
+type Provenance struct {
+	NR   int    // Record number in the original input (AWK's NR)
+	FNR  int    // Record number within the original input stream (AWK's FNR)
+	Name string // Name of the original input stream, if known (AWK's FILENAME)
+}
+
+// Provenance returns the origin of the current record.  In all but the first
+// stage of a pipeline run by RunPipelineProvenance, this is the record of
+// the original input whose processing produced the current record.
+// Otherwise, Provenance describes the current record itself, using NR, FNR,
+// and the name reported by CurrentSource.  Records queued by Inject are
+// likewise considered their own origin.  Outside of a record (e.g., in the
+// Begin and End actions), Provenance returns a zero Provenance.
+func (s *Script) Provenance() Provenance {
+	switch {
+	case s.state != inMiddle:
+		return Provenance{}
+	case s.provKnown:
+		return s.prov
+	}
+	return Provenance{NR: s.NR, FNR: s.FNR, Name: s.CurrentSource().Name}
+}
+
+// RunPipelineProvenance is like RunPipeline but additionally passes the
+// provenance of each record from each script to the next so that every
+// stage's Provenance method reports where in the original input the current
+// record came from.  This enables a late stage to report errors in terms of
+// the original input rather than of the intermediate stream it reads.
+// Output written during a record is attributed to that record's origin;
+// output written by the Begin and End actions has a zero Provenance.  The
+// provenance is conveyed by framing the data passed between scripts, which is
+// invisible to the scripts themselves.  When a script uses a RecordReader
+// (see SetRecordReader), provenance is tracked only approximately.
+func RunPipelineProvenance(r io.Reader, ss ...*Script) error {
+	return runPipeline(r, true, ss)
+}
+
+// provHeaderLen is the length in bytes of the fixed part of a frame header:
+// a 4-byte payload length, an 8-byte NR, an 8-byte FNR, and a 2-byte name
+// length.
+const provHeaderLen = 22
+
+// A provWriter frames each write with the provenance of the writing script's
+// current record.
+type provWriter struct {
+	w io.Writer // Underlying writer
+	s *Script   // Script whose provenance is attached to each write
+}
+
+// Write writes a frame header followed by the data.
+func (pw *provWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	prov := pw.s.Provenance()
+	name := prov.Name
+	if len(name) > 0xffff {
+		name = name[:0xffff]
+	}
+	buf := make([]byte, provHeaderLen, provHeaderLen+len(name)+len(p))
+	binary.BigEndian.PutUint32(buf[0:], uint32(len(p)))
+	binary.BigEndian.PutUint64(buf[4:], uint64(prov.NR))
+	binary.BigEndian.PutUint64(buf[12:], uint64(prov.FNR))
+	binary.BigEndian.PutUint16(buf[20:], uint16(len(name)))
+	buf = append(buf, name...)
+	buf = append(buf, p...)
+	if _, err := pw.w.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// A provFrame associates a range of the unframed data with its provenance.
+type provFrame struct {
+	end    int64      // Offset just past the frame's data
+	origin Provenance // Provenance of the frame's data
+}
+
+// A provReader strips the framing added by a provWriter and remembers the
+// provenance of each frame's data.
+type provReader struct {
+	r      io.Reader   // Underlying reader
+	frames []provFrame // Frames whose data has not been entirely consumed
+	last   Provenance  // Provenance of the most recently discarded frame
+	end    int64       // Offset just past the data of the most recent frame
+	left   int         // Number of bytes remaining in the most recent frame
+}
+
+// Read reads unframed data from the underlying reader.
+func (pr *provReader) Read(p []byte) (int, error) {
+	// Read a new frame header if needed.
+	for pr.left == 0 {
+		var hdr [provHeaderLen]byte
+		if _, err := io.ReadFull(pr.r, hdr[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = fmt.Errorf("Provenance frame header was truncated")
+			}
+			return 0, err
+		}
+		name := make([]byte, binary.BigEndian.Uint16(hdr[20:]))
+		if _, err := io.ReadFull(pr.r, name); err != nil {
+			return 0, fmt.Errorf("Provenance frame header was truncated")
+		}
+		pr.left = int(binary.BigEndian.Uint32(hdr[0:]))
+		pr.end += int64(pr.left)
+		pr.frames = append(pr.frames, provFrame{
+			end: pr.end,
+			origin: Provenance{
+				NR:   int(binary.BigEndian.Uint64(hdr[4:])),
+				FNR:  int(binary.BigEndian.Uint64(hdr[12:])),
+				Name: string(name),
+			},
+		})
+	}
+
+	// Read no further than the end of the current frame.
+	if len(p) > pr.left {
+		p = p[:pr.left]
+	}
+	n, err := pr.r.Read(p)
+	pr.left -= n
+	if err == io.EOF && pr.left > 0 {
+		err = fmt.Errorf("Provenance frame data was truncated")
+	}
+	return n, err
+}
+
+// originAt returns the provenance of the data at a given offset.  Because
+// records are consumed in order, all frames that end at or before the offset
+// are discarded.
+func (pr *provReader) originAt(off int64) Provenance {
+	for len(pr.frames) > 0 && pr.frames[0].end <= off {
+		pr.last = pr.frames[0].origin
+		pr.frames = pr.frames[1:]
+	}
+	if len(pr.frames) == 0 {
+		return pr.last
+	}
+	return pr.frames[0].origin
+}
+
+// noteProvenance records the provenance of a record just read from the input
+// stream.
+func (s *Script) noteProvenance() {
+	if s.provIn == nil {
+		s.provKnown = false
+		return
+	}
+	off := s.provOff - 1
+	if s.recReader != nil {
+		off = s.provIn.end - 1
+	}
+	s.prov = s.provIn.originAt(off)
+	s.provKnown = true
+}
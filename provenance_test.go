@@ -0,0 +1,64 @@
+// This file tests tracking the provenance of records through a pipeline.
+
+package awk
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestRunPipelineProvenance tests that the last stage of a pipeline can
+// determine where in the original input each record came from.
+func TestRunPipelineProvenance(t *testing.T) {
+	// The first stage discards comments and splits each remaining line
+	// into one record per word.
+	s1 := NewScript()
+	s1.Begin = func(s *Script) { s.Println("header") }
+	s1.AppendStmt(func(s *Script) bool { return !s.F(1).StrEqual("#") }, func(s *Script) {
+		for i := 1; i <= s.NF; i++ {
+			s.Println(s.F(i))
+		}
+	})
+
+	// The second stage discards the header and passes the rest through.
+	s2 := NewScript()
+	s2.AppendStmt(func(s *Script) bool { return s.F(1).String() != "header" }, nil)
+
+	// The third stage reports each record's provenance.
+	var out bytes.Buffer
+	s3 := NewScript()
+	s3.AppendStmt(nil, func(s *Script) {
+		p := s.Provenance()
+		fmt.Fprintf(&out, "%s:%d:%d\n", s.F(1), s.NR, p.NR)
+	})
+	s3.Output = &out
+
+	input := "a b\n# comment\nc\n\n# more\nd e f\n"
+	err := RunPipelineProvenance(strings.NewReader(input), s1, s2, s3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a:1:1\nb:2:1\nc:3:3\nd:4:6\ne:5:6\nf:6:6\n"
+	if got := out.String(); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
+
+// TestProvenanceUnframed tests that a script not run in a framed pipeline is
+// its own provenance.
+func TestProvenanceUnframed(t *testing.T) {
+	scr := NewScript()
+	var got []Provenance
+	scr.Begin = func(s *Script) { got = append(got, s.Provenance()) }
+	scr.AppendStmt(nil, func(s *Script) { got = append(got, s.Provenance()) })
+	err := scr.RunReaders(strings.NewReader("a\nb\n"), strings.NewReader("c\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Provenance{{}, {NR: 1, FNR: 1}, {NR: 2, FNR: 2}, {NR: 3, FNR: 1}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Expected %v but received %v", want, got)
+	}
+}
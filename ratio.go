@@ -0,0 +1,52 @@
+// This file provides division that is safe to use in reports.
+
+package awk
+
+import "math"
+
+// A ZeroDivPolicy specifies what Ratio returns when its divisor is zero.
+type ZeroDivPolicy int
+
+// These are the policies for division by zero in Ratio.
+const (
+	ZeroDivZero  ZeroDivPolicy = iota // Produce zero
+	ZeroDivNaN                        // Produce NaN
+	ZeroDivError                      // Abort the script with an error
+)
+
+// SetZeroDivPolicy specifies what Ratio returns when its divisor is zero.
+// The default is ZeroDivZero.
+func (s *Script) SetZeroDivPolicy(p ZeroDivPolicy) {
+	s.zeroDiv = p
+}
+
+// Ratio returns a divided by b.  Unlike a.Div(b), which produces an infinity
+// or NaN when b is zero, Ratio handles division by zero according to the
+// policy specified by SetZeroDivPolicy so that, for example, a percentage
+// computed in an End action for an empty category reports 0 rather than
+// "inf" or "nan".  With ZeroDivError, Ratio aborts the script, which must be
+// running.
+func (s *Script) Ratio(a, b *Value) *Value {
+	if b.Float64() != 0 {
+		return a.Div(b).Bind(s)
+	}
+	switch s.zeroDiv {
+	case ZeroDivNaN:
+		return s.NewValue(math.NaN())
+	case ZeroDivError:
+		s.abortScript("Ratio was asked to divide %s by zero", a)
+	}
+	return s.NewValue(0)
+}
+
+// Percent returns a Value that represents a ratio as a percentage.
+// Numerically, the result is 100 times the original Value.  As a string, it
+// is that number, formatted according to the script's ConvFmt if not
+// integral, followed by a percent sign.  Hence, with the default ConvFmt,
+// s.Ratio(s.NewValue(1), s.NewValue(3)).Percent() produces "33.3333%".
+func (v *Value) Percent() *Value {
+	p := v.Mul(100)
+	p.sval = p.String() + "%"
+	p.svalOk = true
+	return p
+}
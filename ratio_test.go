@@ -0,0 +1,62 @@
+// This file tests safe division and percentages.
+
+package awk
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestRatio tests division under each policy for division by zero.
+func TestRatio(t *testing.T) {
+	scr := NewScript()
+	if got := scr.Ratio(scr.NewValue(3), scr.NewValue(4)).String(); got != "0.75" {
+		t.Fatalf("Expected %q but received %q", "0.75", got)
+	}
+	if got := scr.Ratio(scr.NewValue(5), scr.NewValue("0")).String(); got != "0" {
+		t.Fatalf("Expected %q but received %q", "0", got)
+	}
+	scr.SetZeroDivPolicy(ZeroDivNaN)
+	if got := scr.Ratio(scr.NewValue(5), scr.NewValue(0)).Float64(); !math.IsNaN(got) {
+		t.Fatalf("Expected NaN but received %v", got)
+	}
+
+	// ZeroDivError should abort a running script.
+	scr = NewScript()
+	scr.SetZeroDivPolicy(ZeroDivError)
+	scr.AppendStmt(nil, func(s *Script) {
+		s.Println(s.Ratio(s.F(1), s.F(2)))
+	})
+	err := scr.Run(strings.NewReader("6 3\n1 0\n"))
+	if err == nil {
+		t.Fatal("Expected division by zero to abort the script")
+	}
+}
+
+// TestPercent tests representing ratios as percentages.
+func TestPercent(t *testing.T) {
+	scr := NewScript()
+	tests := []struct {
+		a, b int    // Numerator and denominator
+		want string // Expected percentage
+	}{
+		{1, 4, "25%"},
+		{1, 3, "33.3333%"},
+		{7, 0, "0%"},
+	}
+	for _, tc := range tests {
+		p := scr.Ratio(scr.NewValue(tc.a), scr.NewValue(tc.b)).Percent()
+		if got := p.String(); got != tc.want {
+			t.Fatalf("Expected %q but received %q", tc.want, got)
+		}
+	}
+	scr.ConvFmt = "%.1f"
+	p := scr.Ratio(scr.NewValue(2), scr.NewValue(3)).Percent()
+	if got := p.String(); got != "66.7%" {
+		t.Fatalf("Expected %q but received %q", "66.7%", got)
+	}
+	if got := p.Float64(); math.Abs(got-200.0/3) > 1e-9 {
+		t.Fatalf("Expected %v but received %v", 200.0/3, got)
+	}
+}
@@ -0,0 +1,103 @@
+// This file lets a script reformat numeric columns on output without
+// disturbing the layout of the record.
+
+package awk
+
+import (
+	"sort"
+	"strings"
+)
+
+// ReformatNumeric specifies that, whenever the default print action or
+// Println with no arguments outputs a record, each numeric field (see
+// Value.IsNumeric) in the given columns is to be reformatted according to an
+// AWK-style format string, as with Sprintf (e.g., "%.2f").  The default print
+// action preserves the record's layout: using the field offsets reported by
+// FOffset, each reformatted field replaces the original text in place,
+// right-justified in the same width.  A reformatted field that is wider than
+// the original consumes spaces to its left, always leaving at least one
+// between it and the preceding field, and is otherwise written in full,
+// lengthening the record.  If the record's layout is unknown (e.g., because
+// a field was modified), or when Println is used, the reformatted fields are
+// simply separated by OFS.  Only output is affected; the fields themselves
+// are not modified.  Calling ReformatNumeric with no columns disables
+// reformatting.
+func (s *Script) ReformatNumeric(cols []int, format string) {
+	s.reformatCols = nil
+	s.reformatFmt = format
+	if len(cols) == 0 {
+		return
+	}
+	s.reformatCols = make([]int, len(cols))
+	copy(s.reformatCols, cols)
+	sort.Ints(s.reformatCols)
+}
+
+// reformatField returns the reformatted text of field i, or false if field i
+// is not to be reformatted.
+func (s *Script) reformatField(i int) (string, bool) {
+	if i < 1 || i > s.NF {
+		return "", false
+	}
+	v := s.F(i)
+	if !v.IsNumeric() {
+		return "", false
+	}
+	return s.Sprintf(s.reformatFmt, v).String(), true
+}
+
+// reformattedFields returns all fields of the current record, with numeric
+// columns reformatted as specified by ReformatNumeric.
+func (s *Script) reformattedFields() []string {
+	strs := s.FStrings()
+	for _, c := range s.reformatCols {
+		if text, ok := s.reformatField(c); ok {
+			strs[c-1] = text
+		}
+	}
+	return strs
+}
+
+// reformattedRecord returns the current record with numeric columns
+// reformatted as specified by ReformatNumeric, preserving the record's
+// layout if possible.
+func (s *Script) reformattedRecord() string {
+	if st, _ := s.FOffset(0); st < 0 {
+		return strings.Join(s.reformattedFields(), s.ofs)
+	}
+	rec := s.F(0).String()
+	var sb strings.Builder
+	prev := 0 // End of the text already written
+	for _, c := range s.reformatCols {
+		start, end := s.FOffset(c)
+		if start < prev {
+			continue // Nonexistent or duplicate column
+		}
+		text, ok := s.reformatField(c)
+		if !ok {
+			continue
+		}
+
+		// Borrow spaces from the left if needed, but leave one space
+		// after the previous field.
+		minStart := prev
+		if c > 1 {
+			if _, pe := s.FOffset(c - 1); pe+1 > minStart {
+				minStart = pe + 1
+			}
+		}
+		for len(text) > end-start && start > minStart && rec[start-1] == ' ' {
+			start--
+		}
+
+		// Right-justify the text within the available width.
+		if pad := end - start - len(text); pad > 0 {
+			text = strings.Repeat(" ", pad) + text
+		}
+		sb.WriteString(rec[prev:start])
+		sb.WriteString(text)
+		prev = end
+	}
+	sb.WriteString(rec[prev:])
+	return sb.String()
+}
@@ -0,0 +1,53 @@
+// This file tests reformatting numeric columns on output.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestReformatNumeric tests that reformatting preserves a record's layout.
+func TestReformatNumeric(t *testing.T) {
+	var out bytes.Buffer
+	scr := NewScript()
+	scr.Output = &out
+	scr.ReformatNumeric([]int{2, 3}, "%.2f")
+	scr.AppendStmt(nil, nil)
+	input := "" +
+		"apples      12.5  3\n" +
+		"pears     1234.5 n/a\n" +
+		"plums 7.125 0.5\n"
+	if err := scr.Run(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"apples     12.50 3.00\n" +
+		"pears    1234.50 n/a\n" +
+		"plums  7.12 0.50\n"
+	if got := out.String(); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
+
+// TestReformatNumericModified tests reformatting a record whose layout was
+// lost and reformatting with Println.
+func TestReformatNumericModified(t *testing.T) {
+	var out bytes.Buffer
+	scr := NewScript()
+	scr.Output = &out
+	scr.ReformatNumeric([]int{1}, "%05d")
+	scr.AppendStmt(func(s *Script) bool { return s.NR == 1 }, func(s *Script) {
+		s.SetF(2, s.NewValue("x"))
+	})
+	scr.AppendStmt(nil, nil)
+	scr.AppendStmt(nil, func(s *Script) { s.Println() })
+	if err := scr.Run(strings.NewReader("42  a\n7   b\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := "00042 x\n00042 x\n00007   b\n00007 b\n"
+	if got := out.String(); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
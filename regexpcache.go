@@ -0,0 +1,71 @@
+// This file lets compiled regular expressions be shared among unrelated
+// scripts.
+
+package awk
+
+import "regexp"
+
+// A RegexpCache is a read-only collection of compiled regular expressions
+// exported from a Script or Program.  Importing it into other scripts (see
+// ImportRegexps) spares them the cost of compiling the same regular
+// expressions, which matters for services that create many short-lived
+// Scripts with the same set of patterns.  A RegexpCache is never modified
+// after it is created, so it is safe for concurrent use and can be imported
+// into any number of Scripts, including ones running concurrently.
+type RegexpCache struct {
+	regexps map[string]*regexp.Regexp // Map from a regular-expression string to a compiled regular expression
+}
+
+// Len returns the number of regular expressions in a RegexpCache.
+func (c *RegexpCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.regexps)
+}
+
+// ExportRegexps returns a RegexpCache containing every regular expression the
+// script has compiled so far, including any it imported, plus those reported
+// by RuleRegexps (compiled as if by PrecompileRegexps, with failures
+// ignored).  Regular expressions are cached under the case sensitivity in
+// effect when they were compiled, so an importing script that uses
+// different IgnoreCase settings will simply compile the variants it needs.
+// It is invalid to call ExportRegexps from a running script.
+func (s *Script) ExportRegexps() *RegexpCache {
+	if s.state != notRunning {
+		s.abortScript("ExportRegexps was called from a running script")
+	}
+	s.precompileRegexps()
+	c := &RegexpCache{regexps: make(map[string]*regexp.Regexp)}
+	if s.sharedRegexps != nil {
+		for k, v := range s.sharedRegexps.regexps {
+			c.regexps[k] = v
+		}
+	}
+	for k, v := range s.frozenRegexps {
+		c.regexps[k] = v
+	}
+	for k, v := range s.regexps {
+		c.regexps[k] = v
+	}
+	return c
+}
+
+// ExportRegexps returns a RegexpCache containing the regular expressions
+// compiled by the Script from which the Program was compiled, as by
+// Script.ExportRegexps.  Compiled regular expressions are already shared
+// among a Program's own executions; ExportRegexps lets them be shared with
+// other Programs and Scripts as well.
+func (p *Program) ExportRegexps() *RegexpCache {
+	return p.proto.Copy().ExportRegexps()
+}
+
+// ImportRegexps makes a script consult a RegexpCache before compiling a
+// regular expression.  The cache is shared, not copied, so importing is
+// cheap, and the script's Clones, Copies, and Programs share it as well.
+// Regular expressions found in the cache are not checked against
+// SetRegexpLimit; only import caches exported from trusted scripts.
+// Passing nil stops the script from consulting a cache.
+func (s *Script) ImportRegexps(c *RegexpCache) {
+	s.sharedRegexps = c
+}
@@ -0,0 +1,58 @@
+// This file tests sharing compiled regular expressions among scripts.
+
+package awk
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestRegexpCache tests exporting and importing compiled regular
+// expressions.
+func TestRegexpCache(t *testing.T) {
+	// Export the regular expressions from a compiled Program.
+	proto := NewScript()
+	if err := proto.AppendAutoStmt(nil, "^a+b"); err != nil {
+		t.Fatal(err)
+	}
+	proto.Begin = func(s *Script) { s.NewValue("xyz").Match("y.") }
+	if err := proto.Run(strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+	cache := proto.Compile().ExportRegexps()
+	if n := cache.Len(); n != 2 {
+		t.Fatalf("Expected 2 regular expressions but received %d", n)
+	}
+
+	// Import the cache into several unrelated scripts that run
+	// concurrently.
+	var wg sync.WaitGroup
+	results := make([]string, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			scr := NewScript()
+			scr.ImportRegexps(cache)
+			var out strings.Builder
+			scr.Output = &out
+			scr.AppendStmt(Auto("^a+b"), nil)
+			if err := scr.Run(strings.NewReader("aab\nb\nab\n")); err != nil {
+				results[i] = err.Error()
+				return
+			}
+			if len(scr.regexps) != 0 {
+				results[i] = "compiled a regular expression"
+				return
+			}
+			results[i] = out.String()
+		}(i)
+	}
+	wg.Wait()
+	for _, r := range results {
+		if r != "aab\nab\n" {
+			t.Fatalf("Expected %q but received %q", "aab\nab\n", r)
+		}
+	}
+}
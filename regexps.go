@@ -0,0 +1,143 @@
+// This file lets a script inspect and limit the regular expressions used by
+// its rules.
+
+package awk
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// A RegexpInfo describes a regular expression used by a rule's pattern.
+type RegexpInfo struct {
+	Expr     string // Regular expression as provided to AppendAutoStmt
+	ProgSize int    // Number of instructions in the compiled program
+}
+
+// progSize returns the number of instructions in the program compiled from a
+// regular expression, which is a measure of the regular expression's
+// complexity.  Because Go's regular expressions run in time linear in the
+// size of the input, this is also a bound on the per-byte matching cost.
+func progSize(expr string) (int, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return 0, err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return 0, err
+	}
+	return len(prog.Inst), nil
+}
+
+// SetRegexpLimit specifies the maximum size, in compiled instructions (see
+// RegexpInfo), of any regular expression the script may use.  Larger regular
+// expressions are rejected by AppendAutoStmt and fail to compile when used
+// at run time.  A limit of zero, the default, imposes no limit.
+func (s *Script) SetRegexpLimit(maxProgSize int) {
+	s.maxProgSize = maxProgSize
+}
+
+// checkRegexpLimit returns an error if a regular expression exceeds the size
+// limit specified by SetRegexpLimit.
+func (s *Script) checkRegexpLimit(expr string) error {
+	if s.maxProgSize <= 0 {
+		return nil
+	}
+	n, err := progSize(expr)
+	if err != nil {
+		return err
+	}
+	if n > s.maxProgSize {
+		return fmt.Errorf("Regular expression %q compiles to %d instructions, exceeding the limit of %d", expr, n, s.maxProgSize)
+	}
+	return nil
+}
+
+// autoRegexps returns the regular expressions contained in a list of
+// arguments to Auto.
+func autoRegexps(v []interface{}) []string {
+	exprs := make([]string, 0, len(v))
+	for _, x := range v {
+		switch x := x.(type) {
+		case string:
+			exprs = append(exprs, x)
+		case *regexp.Regexp:
+			exprs = append(exprs, x.String())
+		}
+	}
+	return exprs
+}
+
+// AppendAutoStmt is like AppendStmt but accepts a list of arguments to pass to
+// Auto rather than a PatternFunc.  This lets the script validate the
+// pattern's regular expressions up front and report their sizes through
+// RuleRegexps.  AppendAutoStmt returns an error, without appending anything,
+// if a regular expression fails to compile or exceeds the limit specified by
+// SetRegexpLimit.  It is invalid to call AppendAutoStmt from a running script.
+func (s *Script) AppendAutoStmt(a ActionFunc, v ...interface{}) error {
+	// Validate and measure each regular expression.
+	exprs := autoRegexps(v)
+	infos := make([]RegexpInfo, len(exprs))
+	for i, expr := range exprs {
+		if _, err := regexp.Compile(expr); err != nil {
+			return err
+		}
+		if err := s.checkRegexpLimit(expr); err != nil {
+			return err
+		}
+		n, _ := progSize(expr)
+		infos[i] = RegexpInfo{Expr: expr, ProgSize: n}
+	}
+
+	// Append the rule and associate the regular expressions with it.
+	s.AppendStmt(Auto(v...), a)
+	s.rules[len(s.rules)-1].regexps = infos
+	return nil
+}
+
+// RuleRegexps returns information about the regular expressions used by the
+// pattern of the ith rule (numbered from 0 in the order the rules were
+// appended).  Only rules appended with AppendAutoStmt report their regular
+// expressions; for other rules, RuleRegexps returns nil.
+func (s *Script) RuleRegexps(i int) []RegexpInfo {
+	if i < 0 || i >= len(s.rules) || s.rules[i].regexps == nil {
+		return nil
+	}
+	infos := make([]RegexpInfo, len(s.rules[i].regexps))
+	copy(infos, s.rules[i].regexps)
+	return infos
+}
+
+// PrecompileRegexps specifies whether Run should compile all of the regular
+// expressions reported by RuleRegexps before processing any input.  If so,
+// and any of them fail to compile (e.g., because IgnoreCase or
+// SetRegexpLimit changed since the rule was appended), Run returns a single
+// error describing all of the failures instead of aborting at the first
+// record that triggers one.  The default is PrecompileRegexps(false).
+func (s *Script) PrecompileRegexps(pre bool) {
+	s.precompile = pre
+}
+
+// precompileRegexps compiles all of the regular expressions associated with
+// the script's rules and returns an error describing every failure.
+func (s *Script) precompileRegexps() error {
+	var msgs []string
+	for i, r := range s.rules {
+		for _, info := range r.regexps {
+			if _, err := s.compileRegexp(info.Expr); err != nil {
+				msgs = append(msgs, fmt.Sprintf("rule %d: %s", i, err))
+			}
+		}
+	}
+	switch len(msgs) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("1 regular expression failed to compile: %s", msgs[0])
+	default:
+		return fmt.Errorf("%d regular expressions failed to compile: %s", len(msgs), strings.Join(msgs, "; "))
+	}
+}
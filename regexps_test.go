@@ -0,0 +1,78 @@
+// This file tests inspecting and limiting regular expressions.
+
+package awk
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestRuleRegexps tests reporting the regular expressions used by rules.
+func TestRuleRegexps(t *testing.T) {
+	scr := NewScript()
+	err := scr.AppendAutoStmt(nil, "^a", regexp.MustCompile("z$"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scr.AppendStmt(Auto("b"), nil)
+	infos := scr.RuleRegexps(0)
+	if len(infos) != 2 || infos[0].Expr != "^a" || infos[1].Expr != "z$" {
+		t.Fatalf("Expected regexps %q and %q but received %v", "^a", "z$", infos)
+	}
+	if infos[0].ProgSize <= 0 {
+		t.Fatalf("Expected a positive program size but received %d", infos[0].ProgSize)
+	}
+	if infos := scr.RuleRegexps(1); infos != nil {
+		t.Fatalf("Expected no regexps but received %v", infos)
+	}
+	if err := scr.AppendAutoStmt(nil, "("); err == nil {
+		t.Fatal("Expected an error for an invalid regexp")
+	}
+}
+
+// TestSetRegexpLimit tests rejecting overly complex regular expressions.
+func TestSetRegexpLimit(t *testing.T) {
+	// Reject complex regexps at append time.
+	scr := NewScript()
+	scr.SetRegexpLimit(20)
+	if err := scr.AppendAutoStmt(nil, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := scr.AppendAutoStmt(nil, "(a|b|c){10,20}"); err == nil {
+		t.Fatal("Expected an error for an overly complex regexp")
+	}
+	if len(scr.rules) != 1 {
+		t.Fatalf("Expected 1 rule but received %d", len(scr.rules))
+	}
+
+	// Reject complex regexps at run time.
+	scr = NewScript()
+	scr.SetRegexpLimit(20)
+	scr.AppendStmt(Auto("(a|b|c){10,20}"), nil)
+	if err := scr.Run(strings.NewReader("abc\n")); err == nil {
+		t.Fatal("Expected an error for an overly complex regexp")
+	}
+}
+
+// TestPrecompileRegexps tests reporting all regexp errors before running.
+func TestPrecompileRegexps(t *testing.T) {
+	scr := NewScript()
+	scr.AppendAutoStmt(nil, "x{5}")
+	scr.AppendAutoStmt(nil, "y{5}")
+	scr.AppendAutoStmt(nil, "z")
+	scr.SetRegexpLimit(3)
+	scr.PrecompileRegexps(true)
+	seen := false
+	scr.Begin = func(s *Script) { seen = true }
+	err := scr.Run(strings.NewReader("z\n"))
+	if err == nil {
+		t.Fatal("Expected an error for overly complex regexps")
+	}
+	if !strings.HasPrefix(err.Error(), "2 regular expressions") {
+		t.Fatalf("Expected two failures to be reported but received %q", err)
+	}
+	if seen {
+		t.Fatal("Begin action ran despite regexp errors")
+	}
+}
@@ -0,0 +1,290 @@
+// This file provides a builder for tabular reports, typically output by a
+// script's End action.
+
+package awk
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// A ReportFormat specifies how a Report is written.
+type ReportFormat int
+
+// These are the formats in which a Report can be written.
+const (
+	ReportText     ReportFormat = iota // Aligned columns of plain text
+	ReportCSV                          // Comma-separated values (RFC 4180)
+	ReportMarkdown                     // A Markdown (GitHub-flavored) table
+)
+
+// A Report is a table of Values, typically built in a script's End action
+// from the contents of a ValueArray, then sorted, truncated, and written in
+// one of several formats.  This supports AWK's classic "aggregate, then print
+// a sorted report" workflow.  A Report's methods return the Report to
+// facilitate chaining.
+type Report struct {
+	script  *Script    // Script that determines the comparison rules
+	headers []string   // Column headers (nil if none)
+	rows    [][]*Value // Rows of the table
+	sortCol int        // Column on which to sort (-1 for none)
+	desc    bool       // true: sort in descending order; false: ascending
+	limit   int        // Maximum number of rows to write (0=unlimited)
+}
+
+// NewReport creates an empty Report with the given column headers, which may
+// be omitted.
+func (s *Script) NewReport(headers ...string) *Report {
+	r := &Report{script: s, sortCol: -1}
+	if len(headers) > 0 {
+		r.headers = make([]string, len(headers))
+		copy(r.headers, headers)
+	}
+	return r
+}
+
+// AddRow appends a row to a Report.  Each column can be provided either as a
+// Value or as any type that can be converted to a Value.
+func (r *Report) AddRow(cols ...interface{}) *Report {
+	row := make([]*Value, len(cols))
+	for i, c := range cols {
+		if v, ok := c.(*Value); ok {
+			row[i] = v
+		} else {
+			row[i] = r.script.NewValue(c)
+		}
+	}
+	r.rows = append(r.rows, row)
+	return r
+}
+
+// AddArray appends one row to a Report for each scalar element of a
+// ValueArray.  Each row contains the element's keys followed by its value.
+// Simulated multidimensional keys (see ValueArray.Set) are split into
+// separate columns, as are the keys of subarrays.  Rows are appended in
+// order of their keys so that the Report's contents are deterministic.
+func (r *Report) AddArray(va *ValueArray) *Report {
+	var rows [][]*Value
+	sep := va.subSep()
+	va.Walk(func(keys []*Value, v *Value) {
+		row := make([]*Value, 0, len(keys)+1)
+		for _, k := range keys {
+			for _, sub := range strings.Split(k.String(), sep) {
+				row = append(row, r.script.NewValue(sub))
+			}
+		}
+		rows = append(rows, append(row, v))
+	})
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		for c := 0; c < len(a)-1 && c < len(b)-1; c++ {
+			if a[c].String() != b[c].String() {
+				return a[c].String() < b[c].String()
+			}
+		}
+		return len(a) < len(b)
+	})
+	r.rows = append(r.rows, rows...)
+	return r
+}
+
+// SortBy specifies that a Report's rows should be sorted on a given column
+// (numbered from 0) in ascending or descending order.  Values are compared
+// as with Value.Cmp, so numbers and numeric strings sort numerically.  Rows
+// with equal values retain their original relative order.  A negative column
+// number disables sorting.
+func (r *Report) SortBy(col int, descending bool) *Report {
+	r.sortCol = col
+	r.desc = descending
+	return r
+}
+
+// Limit specifies the maximum number of rows (after sorting) that a Report
+// should write.  A limit of zero, the default, writes all rows.
+func (r *Report) Limit(n int) *Report {
+	r.limit = n
+	return r
+}
+
+// Rows returns a Report's rows as they would be written, that is, after
+// sorting and limiting.
+func (r *Report) Rows() [][]*Value {
+	rows := make([][]*Value, len(r.rows))
+	copy(rows, r.rows)
+	if r.sortCol >= 0 {
+		col := r.sortCol
+		cell := func(row []*Value) *Value {
+			if col < len(row) {
+				return row[col]
+			}
+			return r.script.NewValue("")
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			c := cell(rows[i]).Cmp(cell(rows[j]))
+			if r.desc {
+				return c > 0
+			}
+			return c < 0
+		})
+	}
+	if r.limit > 0 && len(rows) > r.limit {
+		rows = rows[:r.limit]
+	}
+	return rows
+}
+
+// table returns a Report's headers and rows as strings, with every row padded
+// to the same number of columns, and says which columns are numeric.
+func (r *Report) table() ([]string, [][]string, []bool) {
+	// Determine the number of columns.
+	rows := r.Rows()
+	ncols := len(r.headers)
+	for _, row := range rows {
+		if len(row) > ncols {
+			ncols = len(row)
+		}
+	}
+
+	// Convert each cell to a string, and note which columns contain only
+	// numbers.
+	numeric := make([]bool, ncols)
+	for c := range numeric {
+		numeric[c] = len(rows) > 0
+	}
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make([]string, ncols)
+		for c := range cells[i] {
+			if c >= len(row) {
+				numeric[c] = false
+				continue
+			}
+			cells[i][c] = row[c].String()
+			if _, ok := row[c].looksNumber(); !ok || cells[i][c] == "" {
+				numeric[c] = false
+			}
+		}
+	}
+	var headers []string
+	if r.headers != nil {
+		headers = make([]string, ncols)
+		copy(headers, r.headers)
+	}
+	return headers, cells, numeric
+}
+
+// Write writes a Report to an io.Writer in a given format.  In ReportText and
+// ReportMarkdown formats, columns that contain only numbers are
+// right-justified.
+func (r *Report) Write(w io.Writer, f ReportFormat) error {
+	headers, cells, numeric := r.table()
+	switch f {
+	case ReportText:
+		return writeTextReport(w, headers, cells, numeric)
+	case ReportCSV:
+		cw := csv.NewWriter(w)
+		if headers != nil {
+			cw.Write(headers)
+		}
+		cw.WriteAll(cells)
+		return cw.Error()
+	case ReportMarkdown:
+		return writeMarkdownReport(w, headers, cells, numeric)
+	default:
+		return fmt.Errorf("Invalid ReportFormat %d passed to Report.Write", f)
+	}
+}
+
+// Print writes a Report to its script's Output stream in a given format.
+func (r *Report) Print(f ReportFormat) error {
+	return r.Write(r.script.Output, f)
+}
+
+// writeTextReport writes a table as aligned columns of plain text separated
+// by two spaces.
+func writeTextReport(w io.Writer, headers []string, cells [][]string, numeric []bool) error {
+	// Compute the width of each column.
+	widths := make([]int, len(numeric))
+	for _, row := range append([][]string{headers}, cells...) {
+		for c, str := range row {
+			if n := utf8.RuneCountInString(str); n > widths[c] {
+				widths[c] = n
+			}
+		}
+	}
+
+	// Output each row.
+	writeRow := func(row []string) error {
+		var sb strings.Builder
+		for c, str := range row {
+			if c > 0 {
+				sb.WriteString("  ")
+			}
+			pad := strings.Repeat(" ", widths[c]-utf8.RuneCountInString(str))
+			switch {
+			case numeric[c]:
+				sb.WriteString(pad + str)
+			case c < len(row)-1:
+				sb.WriteString(str + pad)
+			default:
+				sb.WriteString(str)
+			}
+		}
+		sb.WriteByte('\n')
+		_, err := io.WriteString(w, sb.String())
+		return err
+	}
+	if headers != nil {
+		if err := writeRow(headers); err != nil {
+			return err
+		}
+	}
+	for _, row := range cells {
+		if err := writeRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMarkdownReport writes a table in GitHub-flavored Markdown.
+func writeMarkdownReport(w io.Writer, headers []string, cells [][]string, numeric []bool) error {
+	// Markdown tables require a header row.
+	if headers == nil {
+		headers = make([]string, len(numeric))
+	}
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+	writeRow := func(row []string) error {
+		var sb strings.Builder
+		sb.WriteByte('|')
+		for _, str := range row {
+			sb.WriteString(" " + escape.Replace(str) + " |")
+		}
+		sb.WriteByte('\n')
+		_, err := io.WriteString(w, sb.String())
+		return err
+	}
+	if err := writeRow(headers); err != nil {
+		return err
+	}
+	align := make([]string, len(numeric))
+	for c, num := range numeric {
+		align[c] = "---"
+		if num {
+			align[c] = "--:"
+		}
+	}
+	if err := writeRow(align); err != nil {
+		return err
+	}
+	for _, row := range cells {
+		if err := writeRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// This file tests the report builder.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// reportInput is the input used to build the reports in the tests.
+const reportInput = `alice 3
+bob 12
+carol 7
+bob 1
+dave 10
+alice 2
+`
+
+// buildReport runs a script that totals the second column of reportInput by
+// the first column and returns a report of the totals.
+func buildReport(t *testing.T) (*Script, *Report) {
+	scr := NewScript()
+	totals := scr.NewValueArray()
+	scr.AppendStmt(nil, func(s *Script) {
+		totals.Set(s.F(1), totals.Get(s.F(1)).Add(s.F(2)))
+	})
+	if err := scr.Run(strings.NewReader(reportInput)); err != nil {
+		t.Fatal(err)
+	}
+	return scr, scr.NewReport("Name", "Total").AddArray(totals)
+}
+
+// TestReportText tests sorting, limiting, and writing a report as text.
+func TestReportText(t *testing.T) {
+	_, rpt := buildReport(t)
+	var out bytes.Buffer
+	if err := rpt.SortBy(1, true).Limit(3).Write(&out, ReportText); err != nil {
+		t.Fatal(err)
+	}
+	want := "Name   Total\nbob       13\ndave      10\ncarol      7\n"
+	if out.String() != want {
+		t.Fatalf("Expected %q but received %q", want, out.String())
+	}
+}
+
+// TestReportCSV tests writing a report as CSV.
+func TestReportCSV(t *testing.T) {
+	_, rpt := buildReport(t)
+	rpt.AddRow("eve, jr.", 0)
+	var out bytes.Buffer
+	if err := rpt.Write(&out, ReportCSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "Name,Total\nalice,5\nbob,13\ncarol,7\ndave,10\n\"eve, jr.\",0\n"
+	if out.String() != want {
+		t.Fatalf("Expected %q but received %q", want, out.String())
+	}
+}
+
+// TestReportMarkdown tests writing a report as a Markdown table to the
+// script's output.
+func TestReportMarkdown(t *testing.T) {
+	scr, rpt := buildReport(t)
+	var out bytes.Buffer
+	scr.Output = &out
+	rpt.AddRow("x|y", 1).SortBy(0, true).Limit(2)
+	if err := rpt.Print(ReportMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	want := "| Name | Total |\n| --- | --: |\n| x\\|y | 1 |\n| dave | 10 |\n"
+	if out.String() != want {
+		t.Fatalf("Expected %q but received %q", want, out.String())
+	}
+}
+
+// TestReportMultidimensional tests that multidimensional keys are split into
+// columns.
+func TestReportMultidimensional(t *testing.T) {
+	scr := NewScript()
+	va := scr.NewValueArray()
+	va.Set("a", "x", 1)
+	va.Set("b", "y", 2)
+	rows := scr.NewReport().AddArray(va).Rows()
+	if len(rows) != 2 || len(rows[1]) != 3 || rows[1][1].String() != "y" || rows[1][2].Int() != 2 {
+		t.Fatalf("Expected [[a x 1] [b y 2]] but received %v", rows)
+	}
+}
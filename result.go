@@ -0,0 +1,57 @@
+// This file summarizes what a run of a script did.
+
+package awk
+
+import (
+	"io"
+	"time"
+)
+
+// A RunResult summarizes a run of a script.
+type RunResult struct {
+	Records    int           // Number of records read (the final value of NR)
+	Matched    int           // Number of records matched by at least one rule's pattern
+	Rejected   int           // Number of records rejected because of RequireNF
+	ExitStatus int           // Status passed to ExitStatus, or 2 if the run failed
+	Duration   time.Duration // Time taken by the run, as measured by the script's clock
+}
+
+// ExitStatus is like Exit but additionally records an exit status, which
+// RunResult reports, as with the argument to AWK's exit statement.
+func (s *Script) ExitStatus(status int) {
+	s.exitStatus = status
+	s.Exit()
+}
+
+// RunResult is like Run but additionally returns a summary of the run.
+func (s *Script) RunResult(r io.Reader) (RunResult, error) {
+	start := s.now()
+	err := s.Run(r)
+	res := RunResult{
+		Records:    s.NR,
+		Matched:    s.matched,
+		Rejected:   s.rejected,
+		ExitStatus: s.exitStatus,
+		Duration:   s.now().Sub(start),
+	}
+	if err != nil {
+		res.ExitStatus = 2
+	}
+	return res, err
+}
+
+// FirstMatchOnly specifies whether a run should stop as soon as any rule's
+// pattern matches a record, like grep -q.  If so, the matching rule's action
+// is not performed, no further input is read, and the End action is skipped,
+// so a script can cheaply test whether any record of a large input matches.
+// Call Matched after the run to learn the outcome.  The default is
+// FirstMatchOnly(false).
+func (s *Script) FirstMatchOnly(first bool) {
+	s.firstMatch = first
+}
+
+// Matched says whether any rule's pattern matched any record during the
+// current (or most recent) run of the script.
+func (s *Script) Matched() bool {
+	return s.matched > 0
+}
@@ -0,0 +1,81 @@
+// This file tests run summaries.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRunResult tests summarizing a run of a script.
+func TestRunResult(t *testing.T) {
+	// Define a script with a clock that advances one second per call.
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	scr := NewScript()
+	scr.SetClock(func() time.Time {
+		t0 = t0.Add(time.Second)
+		return t0
+	})
+	scr.RequireNF(2, NFReject)
+	scr.AppendStmt(Auto("x"), func(s *Script) {})
+	scr.AppendStmt(Auto("stop"), func(s *Script) { s.ExitStatus(3) })
+
+	// Run the script and check the summary.
+	res, err := scr.RunResult(strings.NewReader("a x\nb y\nc\nd x\ne\nstop now\nf x\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RunResult{
+		Records:    6,
+		Matched:    3,
+		Rejected:   2,
+		ExitStatus: 3,
+		Duration:   time.Second,
+	}
+	if res != want {
+		t.Fatalf("Expected %+v but received %+v", want, res)
+	}
+
+	// Ensure that a failed run is reported as such.
+	scr.RequireNF(2, NFError)
+	res, err = scr.RunResult(strings.NewReader("a x\nb\n"))
+	if err == nil {
+		t.Fatal("Expected an error for a short record")
+	}
+	if res.ExitStatus != 2 || res.Records != 2 {
+		t.Fatalf("Expected a status of 2 after 2 records but received %+v", res)
+	}
+}
+
+// TestFirstMatchOnly tests that FirstMatchOnly stops a run at the first match
+// without producing output.
+func TestFirstMatchOnly(t *testing.T) {
+	scr := NewScript()
+	var out bytes.Buffer
+	scr.Output = &out
+	scr.FirstMatchOnly(true)
+	scr.AppendStmt(Auto("needle"), nil)
+	scr.End = func(s *Script) { s.Println("end") }
+	if err := scr.Run(strings.NewReader("hay\nneedle\nhay\nneedle\n")); err != nil {
+		t.Fatal(err)
+	}
+	if !scr.Matched() {
+		t.Fatalf("Expected a match but found none")
+	}
+	if scr.NR != 2 {
+		t.Fatalf("Expected the run to stop at record 2 but it stopped at record %d", scr.NR)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("Expected no output but received %q", out.String())
+	}
+
+	// A run with no matches reads all of the input and runs End.
+	if err := scr.Run(strings.NewReader("hay\nhay\n")); err != nil {
+		t.Fatal(err)
+	}
+	if scr.Matched() || scr.NR != 2 || out.String() != "end\n" {
+		t.Fatalf("Expected no match, NR=2, and %q but received %v, %d, and %q", "end\n", scr.Matched(), scr.NR, out.String())
+	}
+}
@@ -0,0 +1,86 @@
+// This file lets a script declare field rewrites that apply to every record.
+
+package awk
+
+import (
+	"strings"
+)
+
+// A rewrite is a transformation of one field of each record.
+type rewrite struct {
+	when  PatternFunc           // Records to which the rewrite applies (nil for all)
+	field int                   // Field to rewrite
+	fn    func(v *Value) *Value // Function that maps the old field value to a new one
+}
+
+// Rewrite declares that field i of every record is to be replaced by the
+// result of applying a function to it.  Rewrites are applied in the order
+// they were declared, immediately before the record is matched against the
+// script's rules, so patterns, actions, and the default print action all see
+// the rewritten fields.  A rewrite does not apply to records with fewer than
+// i fields, and a function that returns nil leaves the field unchanged.
+// Rewriting field 0 replaces and re-splits the entire record.  It is invalid
+// to call Rewrite from a running script.
+func (s *Script) Rewrite(i int, fn func(v *Value) *Value) {
+	s.RewriteWhen(nil, i, fn)
+}
+
+// RewriteWhen is like Rewrite but applies only to records matching a given
+// pattern.  A nil pattern matches every record.
+func (s *Script) RewriteWhen(p PatternFunc, i int, fn func(v *Value) *Value) {
+	if s.state != notRunning {
+		s.abortScript("RewriteWhen was called from a running script")
+	}
+	s.rewrites = append(s.rewrites, rewrite{when: p, field: i, fn: fn})
+}
+
+// RewriteMap declares that field i of every record is to be replaced
+// according to a map from old to new contents.  Fields whose contents do not
+// appear in the map are left unchanged.  If the script called
+// IgnoreCase(true) or IgnoreCaseStrings(true), a field that does not appear
+// in the map exactly is looked up in a case-insensitive manner; if several
+// keys differ only in case, the one that sorts first is used.  Fields are
+// always compared to keys as strings, even if they look like numbers.
+// RewriteMap is otherwise like Rewrite.
+func (s *Script) RewriteMap(i int, m map[string]string) {
+	// Copy the map so later changes to it do not affect the script, and
+	// prepare a case-insensitive version of it.
+	repl := make(map[string]string, len(m))
+	folded := make(map[string]string, len(m))
+	foldedKey := make(map[string]string, len(m))
+	for k, v := range m {
+		repl[k] = v
+		lk := strings.ToLower(k)
+		if fk, ok := foldedKey[lk]; !ok || k < fk {
+			foldedKey[lk] = k
+			folded[lk] = v
+		}
+	}
+	s.Rewrite(i, func(v *Value) *Value {
+		str := v.String()
+		if r, ok := repl[str]; ok {
+			return v.script.NewValue(r)
+		}
+		if v.ignoreCase() {
+			if r, ok := folded[strings.ToLower(str)]; ok {
+				return v.script.NewValue(r)
+			}
+		}
+		return nil
+	})
+}
+
+// applyRewrites applies all declared rewrites to the current record.
+func (s *Script) applyRewrites() {
+	for _, rw := range s.rewrites {
+		if rw.field < 0 || rw.field > s.NF {
+			continue
+		}
+		if rw.when != nil && !rw.when(s) {
+			continue
+		}
+		if v := rw.fn(s.F(rw.field)); v != nil {
+			s.SetF(rw.field, v)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// This file tests declarative field rewrites.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestRewrite tests that rewrites are applied before rules are matched.
+func TestRewrite(t *testing.T) {
+	scr := NewScript()
+	var out bytes.Buffer
+	scr.Output = &out
+	scr.Rewrite(2, func(v *Value) *Value { return v.Mul(100) })
+	scr.RewriteMap(1, map[string]string{"NY": "New York", "CA": "California"})
+	scr.RewriteWhen(Auto(3), 3, func(v *Value) *Value { return NewValue("third") })
+	scr.Rewrite(5, func(v *Value) *Value { return NewValue("missing") })
+	scr.AppendStmt(func(s *Script) bool { return s.F(2).Int() > 100 }, nil)
+	scr.AppendStmt(Auto(3), nil)
+	if err := scr.Run(strings.NewReader("NY 1 x\nCA 2 y\nTX 3 z\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := "California 200 y\nTX 300 third\nTX 300 third\n"
+	if out.String() != want {
+		t.Fatalf("Expected %q but received %q", want, out.String())
+	}
+}
+
+// TestRewriteMapIgnoreCase tests that case-insensitive lookups in RewriteMap
+// are deterministic and compare fields as strings.
+func TestRewriteMapIgnoreCase(t *testing.T) {
+	m := map[string]string{"GET": "upper", "get": "lower", "Get": "mixed", "1": "one"}
+	for n := 0; n < 10; n++ {
+		scr := NewScript()
+		scr.SetCompat(CompatPOSIX)
+		scr.IgnoreCase(true)
+		var out bytes.Buffer
+		scr.Output = &out
+		scr.RewriteMap(1, m)
+		scr.AppendStmt(nil, nil)
+		if err := scr.Run(strings.NewReader("gEt\nget\n01\n1\n")); err != nil {
+			t.Fatal(err)
+		}
+		if want := "upper\nlower\n01\none\n"; out.String() != want {
+			t.Fatalf("Expected %q but received %q", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// This file implements automatic detection of each input stream's record
+// terminator.
+
+package awk
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// AutoDetectRS specifies whether the record terminator of each input stream
+// should be detected automatically.  With AutoDetectRS(true), if RS is a
+// newline (the default), the first buffer of each input stream, including
+// each stream passed to GetLine, is examined for the first newline or
+// carriage return.  The stream's records are then taken to be terminated by
+// "\r\n" (as on Windows), "\r" (as on classic Mac OS), or "\n" (as on Unix),
+// whichever appears first.  This keeps stray carriage returns from ending
+// up in the last field of every record when input comes from mixed
+// platforms.  A stream whose first buffer contains neither character uses
+// RS as is, as does every stream when RS is anything other than a newline.
+// The terminator that was detected is reported by DetectedRS and, for each
+// record, by RT.  The default is AutoDetectRS(false).  It is invalid to call
+// AutoDetectRS from a running script.
+func (s *Script) AutoDetectRS(detect bool) {
+	if s.state == inMiddle {
+		s.abortScript("AutoDetectRS was called from a running script")
+	}
+	s.autoRS = detect
+}
+
+// DetectedRS returns the record terminator that AutoDetectRS detected in the
+// current input stream, or the empty string if no terminator was detected.
+func (s *Script) DetectedRS() string {
+	return s.detectedRS
+}
+
+// detectRS examines the beginning of an input stream to determine its record
+// terminator, which it stores in detectedRS.  It returns an io.Reader from
+// which to read the complete stream.
+func (s *Script) detectRS(r io.Reader) io.Reader {
+	s.detectedRS = ""
+	if s.rs != "\n" {
+		return r
+	}
+
+	// Examine whatever data the first read returns.
+	br := bufio.NewReaderSize(r, 2*initialRecordSize)
+	br.Peek(1)
+	buf, _ := br.Peek(br.Buffered())
+	i := bytes.IndexAny(buf, "\r\n")
+	switch {
+	case i < 0:
+		return br
+	case buf[i] == '\n':
+		s.detectedRS = "\n"
+		return br
+	}
+
+	// We saw a carriage return.  Determine if it is followed by a
+	// newline, reading more data if necessary.
+	if i+1 == len(buf) {
+		buf, _ = br.Peek(i + 2)
+	}
+	if i+1 < len(buf) && buf[i+1] == '\n' {
+		s.detectedRS = "\r\n"
+	} else {
+		s.detectedRS = "\r"
+	}
+	return br
+}
@@ -0,0 +1,62 @@
+// This file tests automatic detection of record terminators.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestAutoDetectRS tests detecting a different record terminator in each of
+// several input streams.
+func TestAutoDetectRS(t *testing.T) {
+	var out bytes.Buffer
+	scr := NewScript()
+	scr.Output = &out
+	scr.AutoDetectRS(true)
+	scr.AppendStmt(nil, func(s *Script) {
+		s.Println(s.F(2), s.NF, len(s.DetectedRS()), len(s.RT))
+	})
+	err := scr.RunReaders(
+		strings.NewReader("a b\r\nc d\r\n"),
+		strings.NewReader("e f\rg h\r"),
+		strings.NewReader("i j\nk l"),
+		strings.NewReader("m n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "b 2 2 2\nd 2 2 2\nf 2 1 1\nh 2 1 1\nj 2 1 1\nl 2 1 0\nn 2 0 1\n"
+	if got := out.String(); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
+
+// TestAutoDetectRSCustom tests that AutoDetectRS leaves a custom RS alone
+// and applies to GetLine.
+func TestAutoDetectRSCustom(t *testing.T) {
+	scr := NewScript()
+	scr.AutoDetectRS(true)
+	scr.SetRS(";")
+	var got []string
+	scr.Begin = func(s *Script) {
+		s.SetRS("\n")
+		aux := strings.NewReader("x\r\ny\r\n")
+		for {
+			v, err := s.GetLine(aux)
+			if err != nil {
+				break
+			}
+			got = append(got, v.String())
+		}
+		s.SetRS(";")
+	}
+	scr.AppendStmt(nil, func(s *Script) { got = append(got, s.F(0).String()) })
+	if err := scr.Run(strings.NewReader("p\r\n;q")); err != nil {
+		t.Fatal(err)
+	}
+	want := "x|y|p\r\n|q"
+	if s := strings.Join(got, "|"); s != want {
+		t.Fatalf("Expected %q but received %q", want, s)
+	}
+}
@@ -0,0 +1,40 @@
+// This file keeps track of how often each rule fires.
+
+package awk
+
+// A RuleStat reports how often a rule fired during a run of a script.
+type RuleStat struct {
+	Index   int    // 0-based position of the rule in the order rules were appended
+	Name    string // Name given to AppendNamedStmt or "" if none
+	Matches int    // Number of records for which the rule's pattern returned true
+	Actions int    // Number of times the rule's action ran to completion (i.e., without calling Next or aborting)
+	Vetoes  int    // Number of records for which the rule's guard (see Guard) prevented its pattern from being evaluated
+}
+
+// AppendNamedStmt is like AppendStmt but additionally assigns a name to the
+// rule, which RuleStats reports.
+func (s *Script) AppendNamedStmt(name string, p PatternFunc, a ActionFunc) {
+	s.AppendStmt(p, a)
+	s.rules[len(s.rules)-1].name = name
+}
+
+// resetRuleStats zeroes all rules' hit counters.
+func (s *Script) resetRuleStats() {
+	s.ruleStats = make([]RuleStat, len(s.rules))
+	for i, r := range s.rules {
+		s.ruleStats[i] = RuleStat{Index: i, Name: r.name}
+	}
+}
+
+// RuleStats returns hit counters for each rule, in the order the rules were
+// appended, from the current (or most recent) run of the script.  Rules that
+// never matched are easily identified by their zero Matches count, which can
+// help find dead rules in large, programmatically generated rule sets.
+func (s *Script) RuleStats() []RuleStat {
+	if len(s.ruleStats) != len(s.rules) {
+		s.resetRuleStats()
+	}
+	stats := make([]RuleStat, len(s.ruleStats))
+	copy(stats, s.ruleStats)
+	return stats
+}
@@ -0,0 +1,36 @@
+// This file tests per-rule hit counters.
+
+package awk
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRuleStats tests counting how often each rule fires.
+func TestRuleStats(t *testing.T) {
+	scr := NewScript()
+	scr.AppendNamedStmt("has-a", Auto("a"), func(s *Script) {})
+	scr.AppendNamedStmt("skip-b", Auto("b"), func(s *Script) { s.Next() })
+	scr.AppendStmt(Auto("z"), func(s *Script) {})
+	scr.AppendNamedStmt("all", nil, func(s *Script) {})
+	if stats := scr.RuleStats(); len(stats) != 4 || stats[0].Matches != 0 {
+		t.Fatalf("Expected 4 zeroed counters but received %v", stats)
+	}
+	err := scr.Run(strings.NewReader("a\nab\nb\nc\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RuleStat{
+		{0, "has-a", 2, 2, 0},
+		{1, "skip-b", 2, 0, 0},
+		{2, "", 0, 0, 0},
+		{3, "all", 2, 2, 0},
+	}
+	stats := scr.RuleStats()
+	for i, st := range stats {
+		if st != want[i] {
+			t.Fatalf("Expected %+v but received %+v", want[i], st)
+		}
+	}
+}
@@ -4,12 +4,16 @@ package awk
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"os"
 	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -57,6 +61,7 @@ type Script struct {
 	ConvFmt       string      // Conversion format for numbers, "%.6g" by default
 	SubSep        string      // Separator for simulated multidimensional arrays
 	NR            int         // Number of input records seen so far
+	FNR           int         // Number of input records seen so far in the current input stream
 	NF            int         // Number of fields in the current input record
 	RT            string      // Actual string terminating the current record
 	RStart        int         // 1-based index of the previous regexp match (Value.Match)
@@ -64,22 +69,107 @@ type Script struct {
 	MaxRecordSize int         // Maximum number of characters allowed in each record
 	MaxFieldSize  int         // Maximum number of characters allowed in each field
 
-	nf0          int                       // Value of NF for which F(0) was computed
-	rs           string                    // Input record separator, newline by default
-	fs           string                    // Input field separator, space by default
-	fieldWidths  []int                     // Fixed-width column sizes
-	fPat         string                    // Input field regular expression
-	ors          string                    // Output record separator, newline by default
-	ofs          string                    // Output field separator, space by default
-	ignCase      bool                      // true: REs are case-insensitive; false: case-sensitive
-	rules        []statement               // List of pattern-action pairs to execute
-	fields       []*Value                  // Fields in the current record; fields[0] is the entire record
-	regexps      map[string]*regexp.Regexp // Map from a regular-expression string to a compiled regular expression
-	getlineState map[io.Reader]*Script     // Parsing state needed to invoke GetLine repeatedly on a given io.Reader
-	rsScanner    *bufio.Scanner            // Scanner associated with RS
-	input        io.Reader                 // Script input stream
-	state        parseState                // What we're currently parsing
-	stop         stopState                 // What we should stop doing
+	// DropTrailingEmpty indicates what happens when the input ends with
+	// a record terminator.  If true (the default), the input simply
+	// ends, as in AWK.  If false, the terminator is treated as preceding
+	// one final, empty record.
+	DropTrailingEmpty bool
+
+	// BeforePrint, if non-nil, is called before the default action
+	// (that of a nil ActionFunc) or Println with no arguments outputs a
+	// record.  It is passed the text to be output, excluding the output
+	// record separator, and returns whether to output anything and, if
+	// so, the text to output in its place.  This enables global
+	// post-processing of output, such as trimming trailing spaces or
+	// redacting sensitive data, without writing a custom action for
+	// every rule.
+	BeforePrint func(s *Script, text string) (bool, string)
+
+	nf0           int                          // Value of NF for which F(0) was computed
+	rs            string                       // Input record separator, newline by default
+	fs            string                       // Input field separator, space by default
+	fsLiteral     bool                         // true: fs is an exact string; false: fs follows AWK rules
+	rsLiteral     bool                         // true: rs is an exact string; false: rs follows AWK rules
+	fieldWidths   []int                        // Fixed-width column sizes
+	fPat          string                       // Input field regular expression
+	ors           string                       // Output record separator, newline by default
+	ofs           string                       // Output field separator, space by default
+	ignCaseRE     bool                         // true: REs are case-insensitive; false: case-sensitive
+	ignCaseStr    bool                         // true: string comparisons are case-insensitive; false: case-sensitive
+	escFS         bool                         // true: a backslash escapes FS; false: backslashes are ordinary
+	quoteAware    bool                         // true: double-quoted segments are single fields when FS is " "
+	rawBytes      bool                         // true: single-byte separators match raw bytes; false: they match runes
+	rules         []statement                  // List of pattern-action pairs to execute
+	fields        []*Value                     // Fields in the current record; fields[0] is the entire record
+	offsets       [][2]int                     // Byte offsets of each field within the record as split
+	fieldSplitter FieldSplitter                // User-provided field splitter (nil to use the built-in one)
+	regexps       map[string]*regexp.Regexp    // Map from a regular-expression string to a compiled regular expression
+	frozenRegexps map[string]*regexp.Regexp    // Read-only counterpart of regexps shared among clones
+	sharedRegexps *RegexpCache                 // Read-only cache of regexps shared among unrelated scripts
+	getlineState  map[io.Reader]*Script        // Parsing state needed to invoke GetLine repeatedly on a given io.Reader
+	getlineOpts   map[io.Reader]GetLineOptions // Per-reader options to apply when GetLine first reads from an io.Reader
+	urlCache      map[int]parsedURL            // Fields most recently parsed by URLField
+	linesErr      error                        // Error that ended the most recent Lines iteration
+	autoRS        bool                         // true: detect the record terminator of each input stream
+	detectedRS    string                       // Record terminator detected in the current input stream ("" if none)
+	rsScanner     *bufio.Scanner               // Scanner associated with RS
+	recReader     RecordReader                 // User-provided record reader (nil to use rsScanner)
+	newRecReader  func(io.Reader) RecordReader // Function that returns a user-provided record reader
+	input         io.Reader                    // Script input stream
+	state         parseState                   // What we're currently parsing
+	stop          stopState                    // What we should stop doing
+	clock         func() time.Time             // Function that returns the current time
+	timeLayouts   []string                     // Layouts Value.Time tries (nil for the defaults)
+	numLocale     *NumericLocale               // Locale for lenient numeric conversion (nil for strict)
+	timeLoc       *time.Location               // Time zone for time functions (nil for time.Local)
+	rng           *rand.Rand                   // Random-number generator
+	seed          int                          // Seed most recently passed to Srand
+	reqNF         int                          // Required number of fields per record (0=any)
+	nfPolicy      Policy                       // What to do when a record has other than reqNF fields
+	prof          *profiler                    // Column statistics (nil if profiling is disabled)
+	dupTrackers   []*DupTracker                // Detectors of duplicate keys
+	checks        []check                      // Constraints each record is expected to satisfy
+	rewrites      []rewrite                    // Field transformations to apply to each record
+	violations    []Violation                  // Records that failed to satisfy a constraint
+	validated     int                          // Number of records checked against constraints
+	canon         Canon                        // How to canonicalize strings before hashing them
+	pseudonyms    *ValueArray                  // Map from pseudonym to original text (nil if not wanted)
+	injected      []string                     // Synthesized records to process before reading more input
+	peeked        *peekedRecord                // Record read ahead by IsLastRecord (nil if none)
+	groupHooks    []groupHook                  // Functions to call when a key field changes
+	groupKeys     []*Value                     // Value of each groupHooks key field in the previous record
+	joinNF        int                          // Minimum number of fields in a complete record (0=don't join records)
+	joinMax       int                          // Maximum number of records to append to an incomplete record (0=unlimited)
+	joinSep       string                       // Separator to insert between joined records
+	collator      func(a, b string) int        // Function that compares strings (nil for byte order)
+	compat        Compat                       // Level of compatibility with AWK semantics
+	maxProgSize   int                          // Maximum size of a compiled regular expression (0=unlimited)
+	bigPrec       uint                         // Precision of arbitrary-precision arithmetic (0=disabled)
+	precompile    bool                         // true: compile all rules' regular expressions before running
+	atomicOut     bool                         // true: write each record's output with a single Write call
+	recOut        *bytes.Buffer                // Buffer for the current record's output when atomicOut is true
+	arrayOrder    ArrayOrder                   // Iteration order of new ValueArrays
+	zeroDiv       ZeroDivPolicy                // Result of Ratio when dividing by zero
+	reformatCols  []int                        // Columns whose numbers are reformatted on output (nil for none)
+	reformatFmt   string                       // Format with which to reformat numbers on output
+	maxOutRec     int                          // Maximum length of an output record in bytes (0=unlimited)
+	truncMark     string                       // Marker that replaces the end of a truncated output record
+	escCtl        bool                         // true: escape control characters in output records
+	ordered       *OrderedOutput               // Collector of each record's output (nil to write to Output)
+	orderedSeq    func(s *Script) int          // Function that maps a record to a sequence number (nil for NR)
+	matched       int                          // Number of records matched by at least one pattern
+	firstMatch    bool                         // true: stop the run at the first record any pattern matches
+	rejected      int                          // Number of records rejected by RequireNF
+	exitStatus    int                          // Status passed to ExitStatus
+	carried       map[string]interface{}       // Items that survive from one run to the next
+	ruleStats     []RuleStat                   // Per-rule hit counters for the current run
+	srcs          []io.Reader                  // All input streams for the current run
+	srcIdx        int                          // Index into srcs of the current input stream
+	srcCounter    *countingReader              // Wrapper for the current input stream that counts bytes read
+	provIn        *provReader                  // Current input stream if it carries provenance (nil otherwise)
+	provOff       int64                        // Number of bytes of provIn consumed by the record splitter
+	prov          Provenance                   // Origin of the current record when provKnown is true
+	provKnown     bool                         // true: prov is valid; false: the current record is its own origin
 }
 
 // NewScript initializes a new Script with default values.
@@ -97,12 +187,16 @@ func NewScript() *Script {
 		fs:            " ",
 		ors:           "\n",
 		ofs:           " ",
-		ignCase:       false,
+		ignCaseRE:     false,
+		ignCaseStr:    false,
 		rules:         make([]statement, 0, 10),
 		fields:        make([]*Value, 0),
 		regexps:       make(map[string]*regexp.Regexp, 10),
 		getlineState:  make(map[io.Reader]*Script),
+		getlineOpts:   make(map[io.Reader]GetLineOptions),
 		state:         notRunning,
+
+		DropTrailingEmpty: true,
 	}
 }
 
@@ -112,26 +206,245 @@ func (s *Script) abortScript(format string, a ...interface{}) {
 	panic(scriptAborter{fmt.Errorf(format, a...)})
 }
 
-// Copy returns a copy of a Script.
+// Copy returns a deep copy of a Script that is ready to run and that shares
+// no mutable state with the original: its rules, configuration, current
+// record, and regular-expression cache are copied, while its GetLine state and
+// its state from any run in progress are not.  Like a Program execution, the
+// copy starts with the default random-number source.  The copy does share
+// whatever is referenced by the State field, the items registered by Carry
+// (though not the registry itself), the functions that make up its rules,
+// and any DupTracker or pseudonym table.
 func (s *Script) Copy() *Script {
-	sc := *s
+	sc := s.clone()
 	sc.rules = make([]statement, len(s.rules))
 	copy(sc.rules, s.rules)
-	sc.fieldWidths = make([]int, len(s.fieldWidths))
-	copy(sc.fieldWidths, s.fieldWidths)
-	sc.fields = make([]*Value, len(s.fields))
-	copy(sc.fields, s.fields)
+	sc.checks = make([]check, len(s.checks))
+	copy(sc.checks, s.checks)
+	sc.rewrites = make([]rewrite, len(s.rewrites))
+	copy(sc.rewrites, s.rewrites)
+	if s.fieldWidths != nil {
+		sc.fieldWidths = make([]int, len(s.fieldWidths))
+		copy(sc.fieldWidths, s.fieldWidths)
+	}
 	sc.regexps = make(map[string]*regexp.Regexp, len(s.regexps))
 	for k, v := range s.regexps {
 		sc.regexps[k] = v
 	}
-	sc.getlineState = make(map[io.Reader]*Script, len(s.getlineState))
-	for k, v := range s.getlineState {
-		sc.getlineState[k] = v
+
+	// Copy the current record.
+	sc.NR = s.NR
+	sc.FNR = s.FNR
+	sc.NF = s.NF
+	sc.RT = s.RT
+	sc.nf0 = s.nf0
+	sc.fields = make([]*Value, len(s.fields))
+	for i, v := range s.fields {
+		nv := *v
+		sc.fields[i] = nv.Bind(sc)
+	}
+	sc.offsets = make([][2]int, len(s.offsets))
+	copy(sc.offsets, s.offsets)
+	return sc
+}
+
+// Clone is a faster alternative to Copy intended for creating a fresh Script
+// per request in a server.  It returns a ready-to-run Script with the same
+// rules and configuration as the original but none of its mutable run state
+// (NR, NF, the current record, GetLine state, and so forth).  Unlike Copy,
+// Clone shares the original's immutable data, including its list of rules and
+// its compiled regular expressions, rather than copying it.  Clone does not
+// modify the original, so a Script that is not running can be cloned
+// concurrently, but Clone should not be called on a running script or
+// concurrently with other methods on the same Script.  Cloning is cheapest
+// when the regular expressions have already been frozen, as they are in a
+// Program (see Compile).
+func (s *Script) Clone() *Script {
+	sc := s.clone()
+	if len(s.regexps) > 0 {
+		sc.frozenRegexps = mergeRegexps(s.frozenRegexps, s.regexps)
+	}
+	return sc
+}
+
+// clone returns a copy of a Script that shares the original's rules and
+// frozen regular expressions but none of its mutable run state.  The copy
+// starts with an empty cache of newly compiled regular expressions.  clone
+// does not modify the original.
+func (s *Script) clone() *Script {
+	sc := *s
+	sc.rules = s.rules[:len(s.rules):len(s.rules)]
+	sc.checks = s.checks[:len(s.checks):len(s.checks)]
+	sc.rewrites = s.rewrites[:len(s.rewrites):len(s.rewrites)]
+	sc.regexps = make(map[string]*regexp.Regexp)
+	sc.getlineState = make(map[io.Reader]*Script)
+	sc.getlineOpts = make(map[io.Reader]GetLineOptions, len(s.getlineOpts))
+	for k, v := range s.getlineOpts {
+		sc.getlineOpts[k] = v
+	}
+	if s.carried != nil {
+		sc.carried = make(map[string]interface{}, len(s.carried))
+		for k, v := range s.carried {
+			sc.carried[k] = v
+		}
+	}
+
+	// Reset all per-run state.
+	sc.NR = 0
+	sc.FNR = 0
+	sc.NF = 0
+	sc.RT = ""
+	sc.RStart = 0
+	sc.RLength = 0
+	sc.nf0 = 0
+	sc.fields = make([]*Value, 0)
+	sc.offsets = nil
+	sc.rsScanner = nil
+	sc.detectedRS = ""
+	sc.recReader = nil
+	sc.input = nil
+	sc.srcs = nil
+	sc.srcIdx = 0
+	sc.srcCounter = nil
+	sc.peeked = nil
+	sc.groupKeys = nil
+	sc.linesErr = nil
+	sc.urlCache = nil
+	sc.provIn = nil
+	sc.provOff = 0
+	sc.provKnown = false
+	sc.state = notRunning
+	sc.stop = dontStop
+	sc.rng = nil
+	sc.seed = 0
+	sc.violations = nil
+	sc.validated = 0
+	sc.injected = nil
+	sc.recOut = nil
+	sc.ordered = nil
+	sc.orderedSeq = nil
+	sc.ruleStats = nil
+	sc.matched = 0
+	sc.rejected = 0
+	sc.exitStatus = 0
+	if s.prof != nil {
+		sc.prof = &profiler{}
 	}
 	return &sc
 }
 
+// freezeRegexps moves all compiled regular expressions into a read-only cache
+// that can be shared among clones.
+func (s *Script) freezeRegexps() {
+	if len(s.regexps) == 0 {
+		return
+	}
+	s.frozenRegexps = mergeRegexps(s.frozenRegexps, s.regexps)
+	s.regexps = make(map[string]*regexp.Regexp, 10)
+}
+
+// mergeRegexps returns a new map containing the union of two maps of compiled
+// regular expressions.  Neither argument is modified.
+func mergeRegexps(frozen, fresh map[string]*regexp.Regexp) map[string]*regexp.Regexp {
+	merged := make(map[string]*regexp.Regexp, len(frozen)+len(fresh))
+	for k, v := range frozen {
+		merged[k] = v
+	}
+	for k, v := range fresh {
+		merged[k] = v
+	}
+	return merged
+}
+
+// SetClock specifies a function that the script should call to determine the
+// current time.  This is intended primarily for testing code that depends on
+// the time.  Passing nil restores the default, time.Now.
+func (s *Script) SetClock(clock func() time.Time) {
+	s.clock = clock
+}
+
+// now returns the current time according to the script's clock.
+func (s *Script) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock()
+}
+
+// SetRandSource specifies the source of random numbers the script should use.
+// This is intended primarily for making code that depends on random numbers
+// deterministic.  Passing nil restores the default source, which, as in AWK,
+// is seeded with 0.
+func (s *Script) SetRandSource(src rand.Source) {
+	if src == nil {
+		s.rng = nil
+		return
+	}
+	s.rng = rand.New(src)
+}
+
+// random returns the script's random-number generator, creating it if
+// necessary.
+func (s *Script) random() *rand.Rand {
+	if s.rng == nil {
+		s.rng = rand.New(rand.NewSource(0))
+	}
+	return s.rng
+}
+
+// Rand returns a random number in the range [0, 1), as with AWK's rand
+// function.  Each script has its own random-number generator (see Srand and
+// SetRandSource), so scripts do not disturb each other's sequences or that of
+// the math/rand package.
+func (s *Script) Rand() float64 {
+	return s.random().Float64()
+}
+
+// Srand seeds the script's random-number generator and returns the previous
+// seed, as with AWK's srand function.  If no seed is given, the current time
+// (as determined by the script's clock; see SetClock) is used.  The initial
+// seed is 0.
+func (s *Script) Srand(seed ...int) int {
+	prev := s.seed
+	if len(seed) == 0 {
+		s.seed = int(s.now().Unix())
+	} else {
+		s.seed = seed[0]
+	}
+	s.rng = rand.New(rand.NewSource(int64(s.seed)))
+	return prev
+}
+
+// A Compat specifies a level of compatibility with AWK semantics.
+type Compat int
+
+// The following are the possibilities for a Compat.
+const (
+	CompatLegacy Compat = iota // Behave as earlier versions of the awk package did
+	CompatPOSIX                // Follow the POSIX specification of AWK
+	CompatGawk                 // Follow GNU AWK, which extends POSIX AWK
+)
+
+// SetCompat specifies the level of compatibility with AWK semantics.  The
+// default, CompatLegacy, preserves the behavior of earlier versions of the awk
+// package so existing code is not silently affected by semantic fixes.  The
+// following behaviors currently depend on the compatibility level:
+//
+// • At CompatPOSIX and above, calling Exit from a Begin action or from a rule
+// skips the remaining input but still performs the End action.
+//
+// • At CompatPOSIX and above, blank lines at the beginning of the input are
+// ignored when RS is empty (paragraph mode).
+//
+// • At CompatPOSIX and above, StrEqual compares a numeric string read from
+// the input numerically with a number or another numeric string (see
+// IsStrNum), so a field containing " 10 " equals 10.
+//
+// CompatGawk currently behaves identically to CompatPOSIX but will gate
+// behaviors specific to GNU AWK.
+func (s *Script) SetCompat(c Compat) {
+	s.compat = c
+}
+
 // SetRS sets the input record separator (really, a record terminator).  It is
 // invalid to call SetRS after the first record is read.  (It is acceptable to
 // call SetRS from a Begin action, though.)  As in AWK, if the record separator
@@ -146,6 +459,19 @@ func (s *Script) SetRS(rs string) {
 		s.abortScript("SetRS was called from a running script")
 	}
 	s.rs = rs
+	s.rsLiteral = false
+}
+
+// SetRSLiteral sets the input record separator to an exact string.  Unlike
+// with SetRS, a multi-character separator is not treated as a regular
+// expression, so no characters need to be escaped.  An empty separator is
+// treated as by SetRS.
+func (s *Script) SetRSLiteral(rs string) {
+	if s.state == inMiddle {
+		s.abortScript("SetRSLiteral was called from a running script")
+	}
+	s.rs = rs
+	s.rsLiteral = true
 }
 
 // SetFS sets the input field separator.  As in AWK, if the field separator is
@@ -156,11 +482,22 @@ func (s *Script) SetRS(rs string) {
 // characters, it's treated as a regular expression (subject to the current
 // setting of Script.IgnoreCase).
 func (s *Script) SetFS(fs string) {
+	s.fieldSplitter = nil
 	s.fs = fs
+	s.fsLiteral = false
 	s.fieldWidths = nil
 	s.fPat = ""
 }
 
+// SetFSLiteral sets the input field separator to an exact string.  Unlike
+// with SetFS, a multi-character separator is not treated as a regular
+// expression, and a single space separates fields only by exactly one space.
+// An empty separator is treated as by SetFS.
+func (s *Script) SetFSLiteral(fs string) {
+	s.SetFS(fs)
+	s.fsLiteral = true
+}
+
 // SetFieldWidths indicates that each record is composed of fixed-width columns
 // and specifies the width in characters of each column.  It is invalid to pass
 // SetFieldWidths a nil argument or a non-positive field width.
@@ -179,6 +516,7 @@ func (s *Script) SetFieldWidths(fw []int) {
 	// matcher (not strictly but consistent with the SetFS method).
 	s.fs = " "
 	s.fieldWidths = fw
+	s.fieldSplitter = nil
 	s.fPat = ""
 }
 
@@ -186,6 +524,7 @@ func (s *Script) SetFieldWidths(fw []int) {
 // This lies in contrast to providing a regular expression to SetFS, which
 // matches the separation between fields, not the fields themselves.
 func (s *Script) SetFPat(fp string) {
+	s.fieldSplitter = nil
 	s.fs = " "
 	s.fieldWidths = nil
 	s.fPat = fp
@@ -193,6 +532,7 @@ func (s *Script) SetFPat(fp string) {
 
 // recomputeF0 recomputes F(0) by concatenating F(1)...F(NF) with OFS.
 func (s *Script) recomputeF0() {
+	s.offsets = nil
 	if len(s.fields) >= 1 {
 		s.fields[0] = s.NewValue(strings.Join(s.FStrings(), s.ofs))
 	}
@@ -247,6 +587,77 @@ func (s *Script) SetF(i int, v *Value) {
 
 	// Force F(0) to be recomputed the next time it's accessed.
 	s.nf0 = -1
+	s.offsets = nil
+}
+
+// SubF replaces the first match of a regular expression in field i of the
+// current record, as in Value.Sub, and stores the result with SetF so that
+// F(0) (or, for i = 0, the other fields) are updated accordingly.  It returns
+// the number of replacements made (0 or 1).  The field is left unmodified if
+// no replacement was made.
+func (s *Script) SubF(i int, expr, repl string) int {
+	v, n := s.F(i).Sub(expr, repl)
+	if n > 0 {
+		s.SetF(i, v)
+	}
+	return n
+}
+
+// GsubF is like SubF but replaces all non-overlapping matches of the regular
+// expression, as in Value.Gsub.
+func (s *Script) GsubF(i int, expr, repl string) int {
+	v, n := s.F(i).Gsub(expr, repl)
+	if n > 0 {
+		s.SetF(i, v)
+	}
+	return n
+}
+
+// Split splits a string into a new ValueArray, much like AWK's split
+// function, and returns both the array and the number of elements.  The
+// elements are indexed by the integers 1 through the number of elements.  The
+// separator is interpreted the same way as by SetFS: a single space separates
+// elements by runs of whitespace, an empty string separates each character
+// into its own element, and a multi-character string is treated as a regular
+// expression (subject to the current setting of IgnoreCase).
+func (s *Script) Split(str, sep string) (*ValueArray, int) {
+	return s.splitString(str, sep, false)
+}
+
+// SplitFS is like Split but splits on the current field separator, honoring
+// SetFSLiteral.
+func (s *Script) SplitFS(str string) (*ValueArray, int) {
+	return s.splitString(str, s.fs, s.fsLiteral)
+}
+
+// splitString implements Split and SplitFS.
+func (s *Script) splitString(str, sep string, literal bool) (*ValueArray, int) {
+	// Split the string using a copy of the script that differs only in
+	// its field separator.
+	sc := *s
+	sc.fs = sep
+	sc.fsLiteral = literal
+	sc.escFS = false
+	sc.fieldWidths = nil
+	sc.fPat = ""
+	sc.rs = "\n"
+	va := s.NewValueArray()
+	if str == "" {
+		return va, 0
+	}
+	fs, err := scannerSplitter{
+		makeSplit: sc.makeFieldSplitter,
+		maxSize:   s.MaxFieldSize,
+	}.Split(str)
+	if err != nil {
+		s.abortScript("Split failed to split %q on %q (%s)", str, sep, err)
+	}
+
+	// Store each element in the array.
+	for i, f := range fs {
+		va.Set(i+1, f.Text)
+	}
+	return va, len(fs)
 }
 
 // FStrings returns all fields in the current record as a []string of length
@@ -278,30 +689,177 @@ func (s *Script) FFloat64s() []float64 {
 	return a
 }
 
+// FSlice returns fields i through j, inclusive, of the current record, as in
+// FSlice(3, s.NF) to obtain all fields from the third onward.  Field numbers
+// less than 1 are treated as 1 and field numbers greater than NF as NF, so
+// the result is empty if no fields lie in the range.
+func (s *Script) FSlice(i, j int) []*Value {
+	if i < 1 {
+		i = 1
+	}
+	if j > s.NF {
+		j = s.NF
+	}
+	if i > j {
+		return []*Value{}
+	}
+	a := make([]*Value, j-i+1)
+	for k := range a {
+		a[k] = s.F(i + k)
+	}
+	return a
+}
+
+// FJoin returns fields i through j, inclusive, of the current record, joined
+// by the output field separator (see SetOFS), as a single Value.  The range
+// of fields is interpreted as in FSlice.
+func (s *Script) FJoin(i, j int) *Value {
+	fs := s.FSlice(i, j)
+	strs := make([]string, len(fs))
+	for k, f := range fs {
+		strs[k] = f.String()
+	}
+	return s.NewValue(strings.Join(strs, s.ofs))
+}
+
+// A Policy specifies how a script should respond to a record that contains
+// other than the number of fields specified by RequireNF.
+type Policy int
+
+// The following are the possibilities for a Policy.
+const (
+	NFPad         Policy = iota // Pad short records with empty fields; accept long records as is
+	NFTruncate                  // Truncate long records; accept short records as is
+	NFPadTruncate               // Pad short records and truncate long records
+	NFReject                    // Silently skip the record
+	NFError                     // Abort the script with an error
+)
+
+// RequireNF specifies the number of fields each record is expected to
+// contain and how to handle records that contain a different number of
+// fields.  The check is performed immediately after each record is split
+// into fields, before any pattern is evaluated.  A non-positive n disables
+// the check.
+func (s *Script) RequireNF(n int, onMismatch Policy) {
+	if n < 0 {
+		n = 0
+	}
+	s.reqNF = n
+	s.nfPolicy = onMismatch
+}
+
+// enforceNF applies the policy specified by RequireNF to the current record.
+// It returns true if the record should be processed and false if it should be
+// skipped.  In the latter case, it additionally returns a non-nil error if the
+// script should abort.
+func (s *Script) enforceNF() (bool, error) {
+	if s.reqNF == 0 || s.NF == s.reqNF {
+		return true, nil
+	}
+	short := s.NF < s.reqNF
+	switch s.nfPolicy {
+	case NFPad, NFTruncate, NFPadTruncate:
+		switch {
+		case short && s.nfPolicy != NFTruncate:
+			s.SetF(s.reqNF, s.NewValue(""))
+		case !short && s.nfPolicy != NFPad:
+			s.fields = s.fields[:s.reqNF+1]
+			s.NF = s.reqNF
+			s.nf0 = -1
+		}
+		return true, nil
+	case NFReject:
+		return false, nil
+	case NFError:
+		return false, fmt.Errorf("Record %d contains %d fields but %d are required", s.NR, s.NF, s.reqNF)
+	default:
+		return false, fmt.Errorf("Invalid Policy %d passed to RequireNF", s.nfPolicy)
+	}
+}
+
 // IgnoreCase specifies whether regular-expression and string comparisons
-// should be performed in a case-insensitive manner.
+// should be performed in a case-insensitive manner.  It is equivalent to
+// calling both IgnoreCaseRegex and IgnoreCaseStrings.
 func (s *Script) IgnoreCase(ign bool) {
-	s.ignCase = ign
+	s.ignCaseRE = ign
+	s.ignCaseStr = ign
+}
+
+// IgnoreCaseRegex specifies whether regular-expression matching, including
+// field and record splitting, should be performed in a case-insensitive
+// manner, independently of string comparisons.
+func (s *Script) IgnoreCaseRegex(ign bool) {
+	s.ignCaseRE = ign
+}
+
+// IgnoreCaseStrings specifies whether string comparisons (Value.StrEqual,
+// Value.Cmp, Value.IndexOf, and the like) should be performed in a
+// case-insensitive manner, independently of regular-expression matching.
+// For example, a script can match patterns case-insensitively while still
+// comparing join keys case-sensitively.
+func (s *Script) IgnoreCaseStrings(ign bool) {
+	s.ignCaseStr = ign
+}
+
+// RawBytes specifies whether a single-byte field separator or record
+// separator should be matched against the input as a raw byte rather than as
+// a UTF-8-encoded character.  In raw-bytes mode, input is never decoded as
+// UTF-8 when splitting on such a separator, which is faster and lets NUL
+// bytes and invalid UTF-8 in the input be treated as ordinary data.  It also
+// enables separators that are not valid UTF-8 on their own, such as "\xff".
+// RT reports the separator byte as is.  The default is RawBytes(false).
+func (s *Script) RawBytes(raw bool) {
+	s.rawBytes = raw
+}
+
+// EscapeFS specifies whether a backslash preceding an occurrence of the field
+// separator (as specified by SetFS) should prevent that occurrence from
+// separating fields.  If so, the backslash is removed from the field, as is
+// the first backslash of each pair of backslashes (so a field can end in a
+// literal backslash).  Other backslashes are left as is.  For example, with
+// EscapeFS(true) and SetFS(","), the record a\,b,c has two fields, "a,b" and
+// "c".  The default is EscapeFS(false).
+func (s *Script) EscapeFS(esc bool) {
+	s.escFS = esc
+}
+
+// SetQuoteAware specifies whether, when FS is a single space (the default),
+// a double-quoted segment should count as one field even if it contains
+// whitespace, as with QuoteSplitter.  This lets web-server logs in the
+// Apache combined log format be split correctly with the default FS: the
+// request line and the user agent each become a single field, without their
+// quotes.  The setting has no effect when FS is anything other than a single
+// space or when SetFieldSplitter, SetFieldWidths, or SetFPat is in effect.
+// The default is SetQuoteAware(false).
+func (s *Script) SetQuoteAware(aware bool) {
+	s.quoteAware = aware
 }
 
 // Println is like fmt.Println but honors the current output stream, output
 // field separator, and output record separator.  If called with no arguments,
-// Println outputs all fields in the current record.
+// Println outputs all fields in the current record, subject to the
+// BeforePrint hook and ReformatNumeric.  Println honors MaxOutputRecord and
+// EscapeControl.
 func (s *Script) Println(args ...interface{}) {
 	// No arguments: Output all fields of the current record.
 	if args == nil {
-		for i := 1; i <= s.NF; i++ {
-			fmt.Fprintf(s.Output, "%v", s.F(i))
-			if i == s.NF {
-				fmt.Fprintf(s.Output, "%s", s.ors)
-			} else {
-				fmt.Fprintf(s.Output, "%s", s.ofs)
-			}
+		if s.NF > 0 && s.reformatCols != nil {
+			s.printText(strings.Join(s.reformattedFields(), s.ofs))
+		} else if s.NF > 0 {
+			s.printText(strings.Join(s.FStrings(), s.ofs))
 		}
 		return
 	}
 
 	// One or more arguments: Output them.
+	if s.escCtl || s.maxOutRec > 0 {
+		strs := make([]string, len(args))
+		for i, arg := range args {
+			strs[i] = fmt.Sprintf("%v", arg)
+		}
+		fmt.Fprintf(s.Output, "%s%s", s.safeOutput(strings.Join(strs, s.ofs)), s.ors)
+		return
+	}
 	for i, arg := range args {
 		fmt.Fprintf(s.Output, "%v", arg)
 		if i == len(args)-1 {
@@ -326,6 +884,10 @@ type ActionFunc func(*Script)
 type statement struct {
 	Pattern PatternFunc
 	Action  ActionFunc
+
+	regexps []RegexpInfo // Regular expressions used by Pattern, if known
+	name    string       // Name of the rule for reporting purposes
+	guard   PatternFunc  // Test that must pass before Pattern is evaluated (nil for none)
 }
 
 // The matchAny pattern is true only in the middle of a script, when a record
@@ -337,7 +899,24 @@ func matchAny(s *Script) bool {
 // The printRecord statement outputs the current record verbatim to the current
 // output stream.
 func printRecord(s *Script) {
-	fmt.Fprintf(s.Output, "%v%s", s.fields[0], s.ors)
+	if s.reformatCols != nil {
+		s.printText(s.reformattedRecord())
+		return
+	}
+	s.printText(s.F(0).String())
+}
+
+// printText outputs a record's text, subject to the BeforePrint hook and the
+// output safety options, followed by the output record separator.
+func (s *Script) printText(text string) {
+	if s.BeforePrint != nil {
+		ok, t := s.BeforePrint(s, text)
+		if !ok {
+			return
+		}
+		text = t
+	}
+	fmt.Fprintf(s.Output, "%s%s", s.safeOutput(text), s.ors)
 }
 
 // Next stops processing the current record and proceeds with the next record.
@@ -349,6 +928,8 @@ func (s *Script) Next() {
 }
 
 // Exit stops processing the entire script, causing the Run method to return.
+// At a compatibility level of CompatPOSIX or higher (see SetCompat), the End
+// action is performed first, as in AWK.
 func (s *Script) Exit() {
 	if s.stop == dontStop {
 		s.stop = stopScript
@@ -384,11 +965,22 @@ func Range(p1, p2 PatternFunc) PatternFunc {
 //
 // • An int returns a function that matches that int against NR.
 //
+// • A float64 or float32 with an integral value is treated like an int.  A
+// non-integral value is ambiguous, so it returns a function that aborts the
+// script with an error when first evaluated.  (Use AutoE to detect this case
+// without running the script.)
+//
 // • Any other type causes a run-time panic.
 //
 // If given an even number of arguments, pairs of arguments are treated as
 // ranges (cf. the Range function).  The PatternFunc returns true if the record
-// lies within any of the ranges.
+// lies within any of the ranges.  The two endpoints of a range are converted
+// independently, so they need not be of the same type.  For example,
+// Auto(4, "^END") matches from the fourth record through the next record that
+// begins with "END", and Auto("^BEGIN", 10) matches from the first record
+// beginning with "BEGIN" through the tenth record (or, if the tenth record
+// has already passed, through the end of the input).  RangeNR and RangeRE
+// provide type-checked alternatives for the common cases.
 func Auto(v ...interface{}) PatternFunc {
 	if len(v) == 0 {
 		// No arguments: Match anything.
@@ -437,6 +1029,14 @@ func Auto(v ...interface{}) PatternFunc {
 			return func(s *Script) bool {
 				return s.NR == x
 			}
+		case float64:
+			// Floating-point number: Match against NR if
+			// integral; otherwise, abort.
+			return autoFloat(x)
+		case float32:
+			// Floating-point number: Match against NR if
+			// integral; otherwise, abort.
+			return autoFloat(float64(x))
 		case *regexp.Regexp:
 			// Regular expression: Convert to a string then,
 			// dynamically, back to a regular expression.  This
@@ -456,6 +1056,79 @@ func Auto(v ...interface{}) PatternFunc {
 	panic("Auto expects 0, 1, or an even number of arguments")
 }
 
+// autoFloat returns a PatternFunc that matches a floating-point number against
+// NR if the number is integral and aborts the script otherwise.
+func autoFloat(x float64) PatternFunc {
+	if x != math.Trunc(x) {
+		return func(s *Script) bool {
+			s.abortScript("Auto was passed a non-integral record number (%v)", x)
+			return false
+		}
+	}
+	nr := int(x)
+	return func(s *Script) bool {
+		return s.NR == nr
+	}
+}
+
+// RangeNR returns a PatternFunc that matches records a through b, inclusive,
+// where a and b are record numbers (i.e., values of NR).
+func RangeNR(a, b int) PatternFunc {
+	return func(s *Script) bool {
+		return s.NR >= a && s.NR <= b
+	}
+}
+
+// RangeRE returns a PatternFunc that matches from each record that matches
+// the start regular expression through the next record after it that matches
+// the stop regular expression, inclusive, as with Range.  Both regular
+// expressions are matched against the entire record and honor IgnoreCase.
+func RangeRE(start, stop string) PatternFunc {
+	return Range(Auto(start), Auto(stop))
+}
+
+// AutoE is like Auto but validates its arguments up front.  Instead of
+// panicking when given an argument of an unsupported type or an invalid
+// number of arguments, AutoE returns an error, and it also returns an error
+// if any regular expression fails to compile rather than deferring that error
+// until the pattern is first evaluated.
+func AutoE(v ...interface{}) (PatternFunc, error) {
+	if len(v) > 1 && len(v)%2 == 1 {
+		return nil, errors.New("Auto expects 0, 1, or an even number of arguments")
+	}
+	for _, x := range v {
+		switch x := x.(type) {
+		case PatternFunc, int, *regexp.Regexp:
+		case float64:
+			if x != math.Trunc(x) {
+				return nil, fmt.Errorf("Auto was passed a non-integral record number (%v)", x)
+			}
+		case float32:
+			if float64(x) != math.Trunc(float64(x)) {
+				return nil, fmt.Errorf("Auto was passed a non-integral record number (%v)", x)
+			}
+		case string:
+			if _, err := regexp.Compile(x); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("Auto does not accept arguments of type %T", x)
+		}
+	}
+	return Auto(v...), nil
+}
+
+// MustAuto is like AutoE but panics if its arguments are invalid.  This lets
+// configuration errors be detected when a pattern is constructed rather than
+// when a script is run.
+func MustAuto(v ...interface{}) PatternFunc {
+	p, err := AutoE(v...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
 // AppendStmt appends a pattern-action pair to a Script.  If the pattern
 // function is nil, the action will be performed on every record.  If the
 // action function is nil, the record will be output verbatim to the standard
@@ -482,15 +1155,38 @@ func (s *Script) AppendStmt(p PatternFunc, a ActionFunc) {
 
 // compileRegexp caches and returns the result of regexp.Compile.  It
 // automatically prepends "(?i)" to the expression if the script is currently
-// set to perform case-insensitive regular-expression matching.
+// set to perform case-insensitive regular-expression matching.  As a special
+// case, a nil Script compiles the expression without caching it.
 func (s *Script) compileRegexp(expr string) (*regexp.Regexp, error) {
-	if s.ignCase {
+	return s.compileRegexpCase(expr, s != nil && s.ignCaseRE)
+}
+
+// compileRegexpCase is like compileRegexp but is told explicitly whether to
+// match in a case-insensitive manner.
+func (s *Script) compileRegexpCase(expr string, ign bool) (*regexp.Regexp, error) {
+	if ign {
 		expr = "(?i)" + expr
 	}
+	if s == nil {
+		return regexp.Compile(expr)
+	}
 	re, found := s.regexps[expr]
 	if found {
 		return re, nil
 	}
+	re, found = s.frozenRegexps[expr]
+	if found {
+		return re, nil
+	}
+	if s.sharedRegexps != nil {
+		re, found = s.sharedRegexps.regexps[expr]
+		if found {
+			return re, nil
+		}
+	}
+	if err := s.checkRegexpLimit(expr); err != nil {
+		return nil, err
+	}
 	var err error
 	re, err = regexp.Compile(expr)
 	if err != nil {
@@ -588,6 +1284,42 @@ func (s *Script) makeREFieldSplitter() func([]byte, bool) (int, []byte, error) {
 	}
 }
 
+// makeLiteralFieldSplitter returns a splitter that returns the next field by
+// splitting on an exact string.
+func (s *Script) makeLiteralFieldSplitter() func([]byte, bool) (int, []byte, error) {
+	sep := []byte(s.fs)
+	returnedFinalToken := false // true=already returned a final, non-terminated token; false=didn't
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		// If we find the separator, return everything up to it.  As
+		// with other separators, if the record terminator is empty,
+		// newlines also separate fields.
+		i := bytes.Index(data, sep)
+		adv := i + len(sep)
+		if s.rs == "" {
+			if j := bytes.IndexByte(data, '\n'); j >= 0 && (i < 0 || j < i) {
+				i, adv = j, j+1
+				if i > 0 && data[i-1] == '\r' {
+					i--
+				}
+			}
+		}
+		if i >= 0 {
+			return adv, data[:i], nil
+		}
+
+		// We didn't see a separator.  If we're at EOF, we have a
+		// final, non-terminated token.  Return it (unless we already
+		// did).
+		if atEOF && !returnedFinalToken {
+			returnedFinalToken = true
+			return len(data), data, nil
+		}
+
+		// Request more data.
+		return 0, nil, nil
+	}
+}
+
 // makeFixedFieldSplitter returns a splitter than returns the next field by
 // splitting a record into fixed-size chunks.
 func (s *Script) makeFixedFieldSplitter() func([]byte, bool) (int, []byte, error) {
@@ -662,6 +1394,12 @@ func (s *Script) makeFieldSplitter() func([]byte, bool) (int, []byte, error) {
 		return bufio.ScanRunes
 	}
 
+	// If the separator is to be taken literally or is a single byte in
+	// raw-bytes mode, split on exactly that string.
+	if s.fsLiteral || (s.rawBytes && len(s.fs) == 1 && s.fs != " " && s.rs != "") {
+		return s.makeLiteralFieldSplitter()
+	}
+
 	// If the separator is a single space, return the next word as the
 	// field.
 	if s.fs == " " {
@@ -683,13 +1421,66 @@ func (s *Script) makeFieldSplitter() func([]byte, bool) (int, []byte, error) {
 // makeRecordSplitter returns a splitter that returns the next record.
 // Although all the AWK documentation I've read define RS as a record
 // separator, as far as I can tell, AWK in fact treats it as a record
-// *terminator* so we do, too.
+// *terminator* so we do, too.  Consequently, input that ends with a
+// terminator does not produce a trailing empty record unless
+// DropTrailingEmpty is false.
 func (s *Script) makeRecordSplitter() func([]byte, bool) (int, []byte, error) {
+	// Keep track of whether the input ended with a terminator.
+	lastWasTerm := false        // true=most recent token was terminated; false=it wasn't
+	returnedFinalToken := false // true=already returned a trailing empty token; false=didn't
+	atStart := true             // true=no data has been consumed yet; false=some has
+	rs, rsLiteral := s.rs, s.rsLiteral
+	if s.detectedRS != "" {
+		rs, rsLiteral = s.detectedRS, true
+	}
+	trailingEmpty := func(data []byte, atEOF bool) bool {
+		if atEOF && len(data) == 0 && lastWasTerm && !s.DropTrailingEmpty && !returnedFinalToken {
+			returnedFinalToken = true
+			s.RT = ""
+			return true
+		}
+		return false
+	}
+
+	// If the terminator is to be taken literally or is a single byte in
+	// raw-bytes mode, scan for exactly that string.  This avoids decoding
+	// the input as UTF-8.
+	if rs != "" && (rsLiteral || (s.rawBytes && len(rs) == 1)) {
+		term := []byte(rs)
+		return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+			// If we find the terminator, return everything up to
+			// it.
+			if i := bytes.Index(data, term); i >= 0 {
+				s.RT = rs
+				lastWasTerm = true
+				return i + len(term), data[:i], nil
+			}
+
+			// We didn't see a terminator.  If we're at EOF, we
+			// have a final, non-terminated token.  Return it if
+			// it's nonempty.
+			if atEOF && len(data) > 0 {
+				s.RT = ""
+				lastWasTerm = false
+				return len(data), data, nil
+			}
+
+			// If the input ended with a terminator, we may need
+			// to return a trailing empty token.
+			if trailingEmpty(data, atEOF) {
+				return 0, []byte{}, nil
+			}
+
+			// Request more data.
+			return 0, nil, nil
+		}
+	}
+
 	// If the terminator is a single character, scan based on that.  This
 	// code is derived from the bufio.ScanWords source.
-	if utf8.RuneCountInString(s.rs) == 1 {
+	if utf8.RuneCountInString(rs) == 1 {
 		// Ensure the terminator character is valid.
-		firstRune, _ := utf8.DecodeRuneInString(s.rs)
+		firstRune, _ := utf8.DecodeRuneInString(rs)
 		if firstRune == utf8.RuneError {
 			return func(data []byte, atEOF bool) (int, []byte, error) {
 				return 0, nil, errors.New("Invalid rune in terminator")
@@ -710,6 +1501,7 @@ func (s *Script) makeRecordSplitter() func([]byte, bool) (int, []byte, error) {
 					return 0, nil, nil
 				}
 				if r == firstRune {
+					lastWasTerm = true
 					return i + width, data[:i], nil
 				}
 			}
@@ -718,9 +1510,16 @@ func (s *Script) makeRecordSplitter() func([]byte, bool) (int, []byte, error) {
 			// have a final, non-terminated token.  Return it if
 			// it's nonempty.
 			if atEOF && len(data) > 0 {
+				lastWasTerm = false
 				return len(data), data, nil
 			}
 
+			// If the input ended with a terminator, we may need
+			// to return a trailing empty token.
+			if trailingEmpty(data, atEOF) {
+				return 0, []byte{}, nil
+			}
+
 			// Request more data.
 			return 0, nil, nil
 		}
@@ -734,28 +1533,57 @@ func (s *Script) makeRecordSplitter() func([]byte, bool) (int, []byte, error) {
 		// Generate a regular expression based on the current RS and
 		// IgnoreCase.
 		var termRegexp *regexp.Regexp
-		if s.rs == "" {
+		if rs == "" {
 			termRegexp, err = s.compileRegexp(`\r?\n(\r?\n)+`)
 		} else {
-			termRegexp, err = s.compileRegexp(s.rs)
+			termRegexp, err = s.compileRegexp(rs)
 		}
 		if err != nil {
 			return 0, nil, err
 		}
 
+		// As of CompatPOSIX, skip blank lines at the beginning of the
+		// input in paragraph mode.
+		if rs == "" && atStart && s.compat >= CompatPOSIX {
+			skip := len(data) - len(bytes.TrimLeft(data, "\r\n"))
+			if skip > 0 {
+				return skip, nil, nil
+			}
+		}
+		atStart = false
+
 		// If we match the regular expression, return everything up to
 		// the match.
 		loc := termRegexp.FindIndex(data)
 		if loc != nil {
 			s.RT = string(data[loc[0]:loc[1]])
+			lastWasTerm = true
 			return loc[1], data[:loc[0]], nil
 		}
 
 		// We didn't see a terminator.  If we're at EOF, we have a
 		// final, non-terminated token.  Return it if it's nonempty.
+		// In paragraph mode, the final token's trailing newlines are
+		// not part of the record, and a final token consisting
+		// entirely of newlines does not constitute a record.
 		if atEOF && len(data) > 0 {
 			s.RT = ""
-			return len(data), data, nil
+			tok := data
+			if rs == "" {
+				tok = bytes.TrimRight(data, "\r\n")
+				s.RT = string(data[len(tok):])
+				if len(tok) == 0 {
+					return len(data), nil, nil
+				}
+			}
+			lastWasTerm = false
+			return len(data), tok, nil
+		}
+
+		// If the input ended with a terminator, we may need to return
+		// a trailing empty token.
+		if trailingEmpty(data, atEOF) {
+			return 0, []byte{}, nil
 		}
 
 		// Request more data.
@@ -763,8 +1591,68 @@ func (s *Script) makeRecordSplitter() func([]byte, bool) (int, []byte, error) {
 	}
 }
 
+// A RecordReader reads records from an input stream.  Next returns the next
+// record and the text that terminated it (AWK's RT).  At the end of the input
+// stream, Next returns io.EOF.
+type RecordReader interface {
+	Next() (record string, rt string, err error)
+}
+
+// SetRecordReader specifies a function that returns a custom RecordReader for
+// a given input stream.  The function is invoked once for each stream passed
+// to Run or RunReaders and for each stream passed to GetLine.  The returned
+// RecordReader is used in place of RS.  A RecordReader that obtains records
+// from elsewhere (e.g., a database cursor) can simply ignore its io.Reader
+// argument.  Passing nil reverts to splitting the input stream according to
+// RS.  It is invalid to call SetRecordReader from a running script.
+func (s *Script) SetRecordReader(newRR func(r io.Reader) RecordReader) {
+	if s.state == inMiddle {
+		s.abortScript("SetRecordReader was called from a running script")
+	}
+	s.newRecReader = newRR
+}
+
+// A scannerReader is a RecordReader that uses a bufio.Scanner to split an
+// input stream into records.
+type scannerReader struct {
+	sc *Script // Script whose RS and MaxRecordSize determine how to split records
+}
+
+// Next returns the next record and its terminator.
+func (sr scannerReader) Next() (string, string, error) {
+	if sr.sc.rsScanner.Scan() {
+		return sr.sc.rsScanner.Text(), sr.sc.RT, nil
+	}
+	if err := sr.sc.rsScanner.Err(); err != nil {
+		return "", "", err
+	}
+	return "", "", io.EOF
+}
+
+// NewRecordReader returns a RecordReader that splits an input stream into
+// records according to the script's current RS and MaxRecordSize.  This is
+// the RecordReader a script uses by default and may be useful for wrapping
+// within a custom RecordReader.
+func (s *Script) NewRecordReader(r io.Reader) RecordReader {
+	sc := s.Copy()
+	sc.rsScanner = bufio.NewScanner(r)
+	sc.rsScanner.Buffer(make([]byte, initialRecordSize), sc.MaxRecordSize)
+	sc.rsScanner.Split(sc.makeRecordSplitter())
+	return scannerReader{sc: sc}
+}
+
 // Read the next record from a stream and return it.
 func (s *Script) readRecord() (string, error) {
+	// Defer to a user-provided record reader if one was specified.
+	if s.recReader != nil {
+		rec, rt, err := s.recReader.Next()
+		if err != nil {
+			return "", err
+		}
+		s.RT = rt
+		return rec, nil
+	}
+
 	// Return the next record.
 	if s.rsScanner.Scan() {
 		return s.rsScanner.Text(), nil
@@ -775,72 +1663,402 @@ func (s *Script) readRecord() (string, error) {
 	return "", io.EOF
 }
 
+// nextRecord returns the next record to process, preferring records queued by
+// Inject to records read from the input stream.
+func (s *Script) nextRecord() (string, error) {
+	if len(s.injected) > 0 {
+		rec := s.injected[0]
+		s.injected = s.injected[1:]
+		s.RT = ""
+		s.provKnown = false
+		return rec, nil
+	}
+	return s.readInput()
+}
+
+// openSource begins reading from the input stream with a given index into
+// the list of input streams.
+func (s *Script) openSource(i int) {
+	s.srcIdx = i
+	s.srcCounter = &countingReader{r: s.srcs[i]}
+	s.input = s.srcCounter
+	if s.autoRS {
+		s.input = s.detectRS(s.input)
+	}
+	s.FNR = 0
+	s.provIn, _ = s.srcs[i].(*provReader)
+	s.provOff = 0
+	s.recReader = nil
+	if s.newRecReader != nil {
+		s.recReader = s.newRecReader(s.input)
+		return
+	}
+	s.rsScanner = bufio.NewScanner(s.input)
+	s.rsScanner.Buffer(make([]byte, initialRecordSize), s.MaxRecordSize)
+	split := s.makeRecordSplitter()
+	if s.provIn != nil {
+		// Track the exact number of bytes consumed so each record can
+		// be mapped to its provenance.
+		s.rsScanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			adv, tok, err := split(data, atEOF)
+			s.provOff += int64(adv)
+			return adv, tok, err
+		})
+		return
+	}
+	s.rsScanner.Split(split)
+}
+
+// A countingReader is an io.Reader that counts the bytes read through it.
+type countingReader struct {
+	r io.Reader // Underlying reader
+	n int64     // Number of bytes read so far
+}
+
+// Read reads from the underlying reader and tallies the bytes read.
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// SourceInfo describes the input stream a script is currently reading.
+type SourceInfo struct {
+	Name    string // Name of the stream (from its Name method, if any, as for an *os.File)
+	Index   int    // 1-based position of the stream in the list passed to RunReaders (AWK's ARGIND)
+	Bytes   int64  // Number of bytes read from the stream so far, including read-ahead
+	Records int    // Number of records read from the stream so far (FNR)
+}
+
+// CurrentSource returns information about the input stream the script is
+// currently reading.  If no stream is being read (e.g., when called before
+// Run), CurrentSource returns a zero SourceInfo.
+func (s *Script) CurrentSource() SourceInfo {
+	if s.srcCounter == nil || s.srcIdx >= len(s.srcs) {
+		return SourceInfo{}
+	}
+	info := SourceInfo{
+		Index:   s.srcIdx + 1,
+		Bytes:   s.srcCounter.n,
+		Records: s.FNR,
+	}
+	if nr, ok := s.srcs[s.srcIdx].(interface{ Name() string }); ok {
+		info.Name = nr.Name()
+	}
+	return info
+}
+
+// readInput reads the next record from the current input stream, advancing
+// to the next input stream when the current one is exhausted.  If
+// IsLastRecord already read the record ahead, readInput returns that record.
+func (s *Script) readInput() (string, error) {
+	if s.peeked != nil {
+		return s.takePeeked()
+	}
+	for {
+		rec, err := s.readRecord()
+		if err == nil {
+			s.noteProvenance()
+		}
+		if err != io.EOF || s.srcIdx+1 >= len(s.srcs) {
+			return rec, err
+		}
+		s.openSource(s.srcIdx + 1)
+	}
+}
+
+// Inject queues a synthesized record to be processed by the entire script
+// after the current record, before the next record is read from the input
+// stream.  Injected records are processed in the order they were queued and
+// increment NR like any other record.  Inject can be called from a Begin
+// action, in which case the injected records precede the first input record,
+// or from any pattern or action.  It is invalid to call Inject from an End
+// action.
+func (s *Script) Inject(rec string) {
+	if s.state == atEnd {
+		s.abortScript("Inject was called from an End action")
+	}
+	s.injected = append(s.injected, rec)
+}
+
+// A Field is a single field of a record, as produced by a FieldSplitter.
+type Field struct {
+	Text  string // Contents of the field
+	Start int    // Byte offset within the record of the field's beginning (-1 if unknown)
+	End   int    // Byte offset within the record just past the field's end (-1 if unknown)
+}
+
+// A FieldSplitter splits a record into fields.
+type FieldSplitter interface {
+	Split(record string) ([]Field, error)
+}
+
+// SetFieldSplitter specifies a custom FieldSplitter to use in place of FS,
+// FIELDWIDTHS, or FPAT.  Passing nil reverts to the built-in field splitting
+// specified by SetFS, SetFieldWidths, or SetFPat, and calling any of those
+// likewise discards the custom FieldSplitter.
+func (s *Script) SetFieldSplitter(fs FieldSplitter) {
+	s.fieldSplitter = fs
+}
+
+// A scannerSplitter is a FieldSplitter that uses a bufio.Scanner to split a
+// record into fields.
+type scannerSplitter struct {
+	makeSplit func() func([]byte, bool) (int, []byte, error) // Function that returns a fresh split function
+	maxSize   int                                            // Maximum number of characters allowed in each field
+	escape    bool                                           // true=honor backslash-escaped separators
+}
+
+// Split splits a record into fields using a bufio.Scanner.
+func (ss scannerSplitter) Split(rec string) ([]Field, error) {
+	// Wrap the split function to keep track of the byte offset of each
+	// field within the record.  A token is normally a subslice of the data
+	// passed to the split function; if not, we can't determine its offset.
+	split := ss.makeSplit()
+	pos := 0
+	fields := make([]Field, 0, 100)
+	trackOffsets := func(data []byte, atEOF bool) (int, []byte, error) {
+		adv, tok, err := split(data, atEOF)
+		if tok != nil {
+			f := Field{Text: string(tok), Start: -1, End: -1}
+			off := cap(data) - cap(tok)
+			if off >= 0 && off+len(tok) <= len(data) && bytes.Equal(data[off:off+len(tok)], tok) {
+				f.Start = pos + off
+				f.End = pos + off + len(tok)
+			}
+			fields = append(fields, f)
+		}
+		pos += adv
+		return adv, tok, err
+	}
+
+	// Split the record into fields.
+	fsScanner := bufio.NewScanner(strings.NewReader(rec))
+	fsScanner.Buffer(make([]byte, initialFieldSize), ss.maxSize)
+	fsScanner.Split(trackOffsets)
+	for fsScanner.Scan() {
+	}
+	if err := fsScanner.Err(); err != nil {
+		return nil, err
+	}
+	if ss.escape {
+		fields = unescapeFields(rec, fields)
+	}
+	return fields, nil
+}
+
+// unescapeFields merges each field that ends in an unescaped backslash with
+// the separator and field that follow it.  It then replaces each escaped
+// separator and each pair of backslashes with the character being escaped.
+func unescapeFields(rec string, fields []Field) []Field {
+	merged := make([]Field, 0, len(fields))
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		var sb strings.Builder
+		for {
+			// Count the backslashes at the end of the field.
+			nbs := 0
+			for nbs < len(f.Text) && f.Text[len(f.Text)-nbs-1] == '\\' {
+				nbs++
+			}
+			next := i + 1
+			if nbs%2 == 0 || next >= len(fields) || f.End < 0 || fields[next].Start < f.End {
+				sb.WriteString(strings.ReplaceAll(f.Text, `\\`, `\`))
+				break
+			}
+
+			// Merge the escaped separator and the following field.
+			sb.WriteString(strings.ReplaceAll(f.Text[:len(f.Text)-1], `\\`, `\`))
+			sb.WriteString(rec[f.End:fields[next].Start])
+			f.Text, f.End = fields[next].Text, fields[next].End
+			i = next
+		}
+
+		// Report offsets only for fields that are unchanged.
+		text := sb.String()
+		if f.Start < 0 || f.End < 0 || rec[f.Start:f.End] != text {
+			f.Start, f.End = -1, -1
+		}
+		f.Text = text
+		merged = append(merged, f)
+	}
+	return merged
+}
+
 // splitRecord splits a record into fields.  It stores the fields in the Script
 // struct's F field and update NF.  As in real AWK, field 0 is the entire
 // record.
 func (s *Script) splitRecord(rec string) error {
-	fsScanner := bufio.NewScanner(strings.NewReader(rec))
-	fsScanner.Buffer(make([]byte, initialFieldSize), s.MaxFieldSize)
-	fsScanner.Split(s.makeFieldSplitter())
-	fields := make([]*Value, 0, 100)
-	fields = append(fields, s.NewValue(rec))
-	for fsScanner.Scan() {
-		fields = append(fields, s.NewValue(fsScanner.Text()))
+	// Split the record using either the user-provided or the built-in
+	// field splitter.
+	splitter := s.fieldSplitter
+	if splitter == nil && s.quoteAware && s.fs == " " && s.fieldWidths == nil && s.fPat == "" {
+		splitter = QuoteSplitter{}
 	}
-	if err := fsScanner.Err(); err != nil {
+	if splitter == nil {
+		splitter = scannerSplitter{
+			makeSplit: s.makeFieldSplitter,
+			maxSize:   s.MaxFieldSize,
+			escape:    s.escFS && s.fieldWidths == nil && s.fPat == "" && s.fs != "",
+		}
+	}
+	fs, err := splitter.Split(rec)
+	if err != nil {
 		return err
 	}
+
+	// Store the fields and their offsets.
+	fields := make([]*Value, len(fs)+1)
+	offsets := make([][2]int, len(fs)+1)
+	fields[0] = s.newInputValue(rec)
+	offsets[0] = [2]int{0, len(rec)}
+	for i, f := range fs {
+		fields[i+1] = s.newInputValue(f.Text)
+		offsets[i+1] = [2]int{f.Start, f.End}
+		if f.Start < 0 || f.End < f.Start || f.End > len(rec) {
+			offsets[i+1] = [2]int{-1, -1}
+		}
+	}
 	s.fields = fields
+	s.offsets = offsets
 	s.NF = len(fields) - 1
 	s.nf0 = s.NF
 	return nil
 }
 
+// FOffset returns the byte offsets within F(0) of the beginning and end of a
+// given field of the current record, as produced by splitting the record into
+// fields.  That is, F(i) equals F(0)[start:end].  FOffset returns (-1, -1) if
+// the field does not exist, if the offsets can't be determined, or if any
+// field has been modified since the record was split (because F(0) is then
+// rebuilt).
+func (s *Script) FOffset(i int) (start, end int) {
+	if s.nf0 != s.NF || i < 0 || i >= len(s.offsets) {
+		return -1, -1
+	}
+	return s.offsets[i][0], s.offsets[i][1]
+}
+
+// GetLineOptions specifies how GetLine should parse a particular auxiliary
+// input stream.  Zero-valued fields inherit the corresponding setting from the
+// script.
+type GetLineOptions struct {
+	MaxRecordSize int    // Maximum number of characters allowed in each record
+	RS            string // Input record separator, honored only if UseRS is true
+	UseRS         bool   // true: use RS; false: inherit the script's record separator
+	Name          string // Name of the stream to use in error messages
+}
+
+// SetGetLineOptions associates options with an auxiliary input stream that
+// will later be passed to GetLine.  It is invalid to call SetGetLineOptions
+// after GetLine has already read from the given io.Reader.
+func (s *Script) SetGetLineOptions(r io.Reader, opts GetLineOptions) {
+	if r == nil {
+		s.abortScript("SetGetLineOptions was passed a nil io.Reader")
+	}
+	if _, found := s.getlineState[r]; found {
+		s.abortScript("SetGetLineOptions was called after GetLine read from the same io.Reader")
+	}
+	s.getlineOpts[r] = opts
+}
+
+// GetLineNR returns the number of records GetLine has read from a given
+// auxiliary input stream.  If the argument is nil, GetLineNR returns NR.
+func (s *Script) GetLineNR(r io.Reader) int {
+	if r == nil {
+		return s.NR
+	}
+	sc := s.getlineState[r]
+	if sc == nil {
+		return 0
+	}
+	return sc.NR
+}
+
 // GetLine reads the next record from an input stream and returns it.  If the
 // argument to GetLine is nil, GetLine reads from the current input stream and
-// increments NR.  Otherwise, it reads from the given io.Reader and does not
-// increment NR.  Call SetF(0, ...) on the Value returned by GetLine to perform
-// the equivalent of AWK's getline with no variable argument.
+// increments NR and FNR.  Otherwise, it reads from the given io.Reader and
+// does not increment NR or FNR.  Call SetF(0, ...) on the Value returned by
+// GetLine to perform the equivalent of AWK's getline with no variable
+// argument.  Use SetGetLineOptions to customize how a given io.Reader is
+// parsed.
 func (s *Script) GetLine(r io.Reader) (*Value, error) {
 	// Handle the simpler case of a nil argument (to read from the current
 	// input stream).
 	if r == nil {
-		rec, err := s.readRecord()
+		rec, err := s.readInput()
 		if err != nil {
 			return nil, err
 		}
-		s.NR++
-		return s.NewValue(rec), nil
+		s.consumeRecord()
+		return s.newInputValue(rec), nil
 	}
 
 	// If we've seen this io.Reader before, reuse its parsing state.
 	// Otherwise, create a new Script for storing state.
 	sc := s.getlineState[r]
+	opts := s.getlineOpts[r]
 	if sc == nil {
 		// Copy the given script so we don't alter any of the original
 		// script's state.
 		sc = s.Copy()
+		sc.NR = 0
+		sc.FNR = 0
 		s.getlineState[r] = sc
 
+		// Apply any per-reader options.
+		if opts.MaxRecordSize > 0 {
+			sc.MaxRecordSize = opts.MaxRecordSize
+		}
+		if opts.UseRS {
+			sc.rs = opts.RS
+			sc.rsLiteral = false
+		}
+
 		// Create (and store) a new scanner based on the record
-		// terminator.
+		// terminator.  Note that a bufio.Scanner honors the larger of
+		// its initial buffer size and its maximum token size.
+		bufSize := initialRecordSize
+		if sc.MaxRecordSize < bufSize {
+			bufSize = sc.MaxRecordSize
+		}
 		sc.input = r
-		sc.rsScanner = bufio.NewScanner(sc.input)
-		sc.rsScanner.Buffer(make([]byte, initialRecordSize), sc.MaxRecordSize)
-		sc.rsScanner.Split(sc.makeRecordSplitter())
+		if sc.autoRS {
+			sc.input = sc.detectRS(r)
+		}
+		sc.recReader = nil
+		if sc.newRecReader != nil {
+			sc.recReader = sc.newRecReader(sc.input)
+		} else {
+			sc.rsScanner = bufio.NewScanner(sc.input)
+			sc.rsScanner.Buffer(make([]byte, bufSize), sc.MaxRecordSize)
+			sc.rsScanner.Split(sc.makeRecordSplitter())
+		}
 	}
 
 	// Read a record from the given reader.
 	rec, err := sc.readRecord()
 	if err != nil {
+		if err != io.EOF && opts.Name != "" {
+			err = fmt.Errorf("%s: %w", opts.Name, err)
+		}
 		return nil, err
 	}
-	return sc.NewValue(rec), nil
+	sc.NR++
+	sc.FNR++
+	return sc.newInputValue(rec), nil
 }
 
 // Run executes a script against a given input stream.  It is perfectly valid
 // to run the same script on multiple input streams.
-func (s *Script) Run(r io.Reader) (err error) {
+func (s *Script) Run(r io.Reader) error {
+	return s.RunReaders(r)
+}
+
+// RunReaders executes a script against a sequence of input streams, much as
+// AWK does when given multiple input files.  NR counts records across all of
+// the streams while FNR restarts from zero at the beginning of each stream.
+func (s *Script) RunReaders(rs ...io.Reader) (err error) {
 	// Catch scriptAborter panics and return them as errors.  Re-throw all
 	// other panics.
 	defer func() {
@@ -854,28 +2072,58 @@ func (s *Script) Run(r io.Reader) (err error) {
 	}()
 
 	// Reinitialize most of our state.
-	s.input = r
+	s.srcs = rs
+	if len(s.srcs) == 0 {
+		s.srcs = []io.Reader{strings.NewReader("")}
+	}
+	s.srcIdx = 0
+	s.input = s.srcs[0]
+	s.provKnown = false
+	s.peeked = nil
+	s.groupKeys = nil
 	s.ConvFmt = "%.6g"
 	s.NF = 0
 	s.NR = 0
+	s.FNR = 0
+	s.RT = ""
+	s.RStart = 0
+	s.RLength = 0
+	s.urlCache = nil
+	if s.prof != nil {
+		s.prof = &profiler{}
+	}
+	s.violations = nil
+	s.validated = 0
+	s.injected = nil
+	s.matched = 0
+	s.rejected = 0
+	s.exitStatus = 0
+	s.resetRuleStats()
+
+	// Optionally ensure that all regular expressions compile.
+	if s.precompile {
+		if err := s.precompileRegexps(); err != nil {
+			return err
+		}
+	}
 
 	// Process the Begin action, if any.
+	s.stop = dontStop
 	if s.Begin != nil {
 		s.state = atBegin
 		s.Begin(s)
 	}
 
 	// Create (and store) a new scanner based on the record terminator.
-	s.rsScanner = bufio.NewScanner(s.input)
-	s.rsScanner.Buffer(make([]byte, initialRecordSize), s.MaxRecordSize)
-	s.rsScanner.Split(s.makeRecordSplitter())
+	s.openSource(0)
 
-	// Process each record in turn.
+	// Process each record in turn.  As of CompatPOSIX, calling Exit from
+	// the Begin action skips all records.
 	s.state = inMiddle
-	for {
+	for s.compat < CompatPOSIX || s.stop != stopScript {
 		// Read a record.
 		s.stop = dontStop
-		rec, err := s.readRecord()
+		rec, err := s.nextRecord()
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -883,6 +2131,7 @@ func (s *Script) Run(r io.Reader) (err error) {
 			return err
 		}
 		s.NR++
+		s.FNR++
 
 		// Split the record into its constituent fields.
 		err = s.splitRecord(rec)
@@ -890,8 +2139,47 @@ func (s *Script) Run(r io.Reader) (err error) {
 			return err
 		}
 
+		// Reassemble a wrapped record if so requested.
+		if s.joinNF > 0 && s.NF < s.joinNF {
+			if err := s.joinContinuations(rec); err != nil {
+				return err
+			}
+		}
+
+		// Enforce the required number of fields, if any.
+		if ok, err := s.enforceNF(); !ok {
+			if err != nil {
+				return err
+			}
+			s.rejected++
+			if s.ordered != nil {
+				s.putOrdered(nil)
+			}
+			continue
+		}
+
+		// Profile the record if so requested.
+		if s.prof != nil {
+			s.prof.observe(s)
+		}
+
+		// Look for duplicate keys if so requested.
+		for _, dt := range s.dupTrackers {
+			dt.observe(s)
+		}
+
+		// Check the record against all constraints.
+		s.validate()
+
+		// Apply any declared field rewrites.
+		s.applyRewrites()
+
 		// Process all applicable actions.
 		func() {
+			// If requested, buffer the record's output so it
+			// can be written all at once.
+			defer s.bufferRecordOutput()()
+
 			// An action is able to break out of the
 			// action-processing loop by calling Next, which throws
 			// a recordStopper.  We catch that and continue
@@ -904,11 +2192,30 @@ func (s *Script) Run(r io.Reader) (err error) {
 				}
 			}()
 
+			// Report any change of group.
+			s.checkGroups()
+
 			// Perform each action whose pattern matches the
 			// current record.
-			for _, rule := range s.rules {
+			matched := false
+			defer func() {
+				if matched {
+					s.matched++
+				}
+			}()
+			for i, rule := range s.rules {
+				if rule.guard != nil && !rule.guard(s) {
+					s.ruleStats[i].Vetoes++
+					continue
+				}
 				if rule.Pattern(s) {
+					matched = true
+					s.ruleStats[i].Matches++
+					if s.firstMatch {
+						break
+					}
 					rule.Action(s)
+					s.ruleStats[i].Actions++
 					if s.stop != dontStop {
 						break
 					}
@@ -916,45 +2223,90 @@ func (s *Script) Run(r io.Reader) (err error) {
 			}
 		}()
 
-		// Stop the script if an error occurred or an action calls  Exit.
+		// Stop the script without running the End action if
+		// FirstMatchOnly was requested and a pattern matched.
+		if s.firstMatch && s.matched > 0 {
+			s.state = notRunning
+			return nil
+		}
+
+		// Stop the script if an error occurred or an action calls
+		// Exit.  As of CompatPOSIX, the End action is still performed.
 		if s.stop == stopScript {
+			if s.compat >= CompatPOSIX {
+				break
+			}
+			s.state = notRunning
 			return nil
 		}
 	}
 
-	// Process the End action, if any.
+	// Finish the final group, if any, then process the End action, if
+	// any.
+	s.state = atEnd
+	s.flushGroups()
 	if s.End != nil {
-		s.state = atEnd
 		s.End(s)
 	}
 	s.state = notRunning
 	return nil
 }
 
+// RunOn runs another script on an in-memory string, such as the contents of
+// a field, and returns everything that script outputs.  This enables nested
+// parsing (e.g., of a field that itself contains comma-separated values) using
+// the full capabilities of the awk package.  The other script's Output field is
+// restored when RunOn returns.  It is invalid for a script to RunOn itself.
+func (s *Script) RunOn(sub *Script, input string) (string, error) {
+	if sub == s {
+		s.abortScript("RunOn was asked to run a script on itself")
+	}
+	var buf bytes.Buffer
+	out := sub.Output
+	sub.Output = &buf
+	defer func() { sub.Output = out }()
+	err := sub.Run(strings.NewReader(input))
+	return buf.String(), err
+}
+
 // RunPipeline chains together a set of scripts into a pipeline, with each
 // script sending its output to the next.  (Implication: Script.Output will be
 // overwritten in all but the last script.)  If any script in the pipeline
 // fails, a non-nil error will be returned.
 func RunPipeline(r io.Reader, ss ...*Script) error {
+	return runPipeline(r, false, ss)
+}
+
+// runPipeline implements RunPipeline and RunPipelineProvenance.  If prov is
+// true, the data passed between scripts is framed with each record's
+// provenance.
+func runPipeline(r io.Reader, prov bool, ss []*Script) error {
 	// Spawn scripts in reverse order so they begin blocked on input.
 	eChan := make(chan error, len(ss))
+	pws := make([]*io.PipeWriter, len(ss))
 	for i := len(ss) - 1; i > 0; i-- {
 		s := ss[i]
 		pr, pw := io.Pipe()
+		pws[i-1] = pw
+		var in io.Reader = pr
 		ss[i-1].Output = pw
-		go func(i int, pr *io.PipeReader) {
-			eChan <- s.Run(pr)
+		if prov {
+			ss[i-1].Output = &provWriter{w: pw, s: ss[i-1]}
+			in = &provReader{r: pr}
+		}
+		go func(i int, in io.Reader) {
+			eChan <- s.Run(in)
 			if i < len(ss)-1 {
-				ss[i].Output.(*io.PipeWriter).Close()
+				pws[i].Close()
 			}
-		}(i, pr)
+		}(i, in)
 	}
 
 	// Spawn the first script to enable the rest to begin.
 	go func() {
 		eChan <- ss[0].Run(r)
 		if len(ss) > 1 {
-			ss[0].Output.(*io.PipeWriter).Close()
+			pws[0].Close()
 		}
 	}()
 
@@ -964,7 +2316,7 @@ func RunPipeline(r io.Reader, ss ...*Script) error {
 		if err != nil {
 			// Error -- close all output pipes then return.
 			for j := 0; j < len(ss)-1; j++ {
-				ss[j].Output.(*io.PipeWriter).Close()
+				pws[j].Close()
 			}
 			return err
 		}
@@ -7,10 +7,13 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math/rand"
+	"os"
 	"regexp"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestReadRecordNewline tests reading newline-separated records.
@@ -96,6 +99,53 @@ func TestReadRecordRE(t *testing.T) {
 	}
 }
 
+// TestDropTrailingEmpty tests that input ending with a record terminator is
+// handled consistently across all types of record separators.
+func TestDropTrailingEmpty(t *testing.T) {
+	type testCase struct {
+		rs    string   // Record separator
+		input string   // Input string
+		drop  []string // Records expected when DropTrailingEmpty is true
+		keep  []string // Records expected when DropTrailingEmpty is false
+	}
+	tests := []testCase{
+		{"\n", "a\nb\n", []string{"a", "b"}, []string{"a", "b", ""}},
+		{"\n", "a\nb", []string{"a", "b"}, []string{"a", "b"}},
+		{"\n", "a\n\n", []string{"a", ""}, []string{"a", "", ""}},
+		{"\n", "", nil, nil},
+		{"<+>", "a<>b<<>", []string{"a", "b"}, []string{"a", "b", ""}},
+		{"<+>", "a<>b", []string{"a", "b"}, []string{"a", "b"}},
+		{"", "a\n\nb\n\n\n", []string{"a", "b"}, []string{"a", "b", ""}},
+		{"", "a\n\nb\n", []string{"a", "b"}, []string{"a", "b"}},
+		{"", "a\n\nb", []string{"a", "b"}, []string{"a", "b"}},
+	}
+
+	// Define a script that collects all records.
+	var got []string
+	scr := NewScript()
+	scr.AppendStmt(nil, func(s *Script) { got = append(got, s.F(0).String()) })
+	check := func(tc testCase, want []string) {
+		got = nil
+		scr.SetRS(tc.rs)
+		err := scr.Run(strings.NewReader(tc.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+			t.Fatalf("Expected %q but received %q for input %q with RS %q and DropTrailingEmpty %v",
+				want, got, tc.input, tc.rs, scr.DropTrailingEmpty)
+		}
+	}
+
+	// Run each test with and without DropTrailingEmpty.
+	for _, tc := range tests {
+		scr.DropTrailingEmpty = true
+		check(tc, tc.drop)
+		scr.DropTrailingEmpty = false
+		check(tc, tc.keep)
+	}
+}
+
 // TestSplitRecordWhitespace tests splitting a record into whitespace-separated
 // fields.
 func TestSplitRecordWhitespace(t *testing.T) {
@@ -511,6 +561,29 @@ func TestFInts(t *testing.T) {
 	}
 }
 
+// TestFSlice tests extracting and joining ranges of fields.
+func TestFSlice(t *testing.T) {
+	scr := NewScript()
+	scr.SetOFS("-")
+	var output []string
+	scr.AppendStmt(nil, func(s *Script) {
+		output = append(output,
+			s.FJoin(3, s.NF).String(),
+			s.FJoin(0, 2).String(),
+			s.FJoin(4, 99).String(),
+			s.FJoin(5, 4).String(),
+			fmt.Sprint(len(s.FSlice(2, 3))))
+	})
+	err := scr.Run(strings.NewReader("a b c d\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	desiredOutput := []string{"c-d", "a-b", "d", "", "2"}
+	if strings.Join(output, "|") != strings.Join(desiredOutput, "|") {
+		t.Fatalf("Expected %q but received %q", desiredOutput, output)
+	}
+}
+
 // TestFieldCreation0 ensures that field creation updates F(0).
 func TestFieldCreation0(t *testing.T) {
 	// Define a script and some test inputs and outputs.
@@ -684,6 +757,75 @@ func TestAutoIntRanges(t *testing.T) {
 	}
 }
 
+// TestAutoMixedRanges tests ranges whose endpoints are of different types.
+func TestAutoMixedRanges(t *testing.T) {
+	input := "a\nb\nEND\nc\nBEGIN\nd\ne\n"
+	tests := []struct {
+		p    PatternFunc // Pattern to apply
+		want string      // Expected matching records
+	}{
+		{Auto(2, "^END"), "b END"},
+		{Auto("^BEGIN", 6.0), "BEGIN d"},
+		{Auto("^BEGIN", 2), "BEGIN d e"},
+		{RangeNR(3, 5), "END c BEGIN"},
+		{RangeRE("^b", "^c"), "b END c"},
+		{Auto(float32(4)), "c"},
+	}
+	for _, tc := range tests {
+		output := make([]string, 0, 7)
+		scr := NewScript()
+		scr.AppendStmt(tc.p, func(s *Script) { output = append(output, s.F(0).String()) })
+		err := scr.Run(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(output, " "); got != tc.want {
+			t.Fatalf("Expected %q but received %q", tc.want, got)
+		}
+	}
+
+	// A non-integral record number should abort the script.
+	scr := NewScript()
+	scr.AppendStmt(Auto(3.5), nil)
+	if err := scr.Run(strings.NewReader(input)); err == nil {
+		t.Fatal("Expected an error for a non-integral record number")
+	}
+}
+
+// TestAutoE tests validating pattern arguments at construction time.
+func TestAutoE(t *testing.T) {
+	// Valid arguments should produce a working pattern.
+	p, err := AutoE("^b", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := make([]string, 0, 3)
+	scr := NewScript()
+	scr.AppendStmt(p, func(s *Script) { output = append(output, s.F(1).String()) })
+	err = scr.Run(strings.NewReader("a\nb\nc\nd\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(output, " "); got != "b c" {
+		t.Fatalf("Expected %q but received %q", "b c", got)
+	}
+
+	// Invalid arguments should produce an error.
+	for _, args := range [][]interface{}{{"("}, {1.5}, {1, 2, 3}, {1, "[z-a]"}} {
+		if _, err := AutoE(args...); err == nil {
+			t.Fatalf("Expected an error for %v", args)
+		}
+	}
+
+	// MustAuto should panic when given invalid arguments.
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustAuto failed to panic")
+		}
+	}()
+	MustAuto("(")
+}
+
 // TestCatchSetRSError tests that we properly catch invalid uses of SetRS.
 func TestCatchSetRSError(t *testing.T) {
 	// Define a script.
@@ -932,6 +1074,60 @@ func TestGetLineSetF(t *testing.T) {
 	}
 }
 
+// TestGetLineOptions tests that GetLine honors per-reader options and counts
+// the records it reads from each auxiliary stream.
+func TestGetLineOptions(t *testing.T) {
+	// Define a script that reads two comma-separated records from an
+	// auxiliary stream for each record of the main stream.
+	var output []string
+	aux := strings.NewReader("a,b,c,d,e,f")
+	scr := NewScript()
+	scr.Begin = func(s *Script) {
+		output = nil
+		s.SetGetLineOptions(aux, GetLineOptions{RS: ",", UseRS: true, Name: "aux"})
+	}
+	scr.AppendStmt(nil, func(s *Script) {
+		for i := 0; i < 2; i++ {
+			v, err := s.GetLine(aux)
+			if err != nil {
+				t.Fatal(err)
+			}
+			output = append(output, s.F(1).String()+v.String())
+		}
+	})
+
+	// Run the script and validate the output.
+	err := scr.Run(strings.NewReader("1\n2\n3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	desiredOutput := "1a 1b 2c 2d 3e 3f"
+	if strings.Join(output, " ") != desiredOutput {
+		t.Fatalf("Expected %q but received %q", desiredOutput, strings.Join(output, " "))
+	}
+	if n := scr.GetLineNR(aux); n != 6 {
+		t.Fatalf("Expected 6 records but received %d", n)
+	}
+	if n := scr.GetLineNR(nil); n != 3 {
+		t.Fatalf("Expected 3 records but received %d", n)
+	}
+}
+
+// TestGetLineOptionsSize tests that a per-reader maximum record size is
+// honored and that errors are labeled with the reader's name.
+func TestGetLineOptionsSize(t *testing.T) {
+	aux := strings.NewReader(strings.Repeat("x", 100) + "\n")
+	scr := NewScript()
+	scr.SetGetLineOptions(aux, GetLineOptions{MaxRecordSize: 10, Name: "aux"})
+	_, err := scr.GetLine(aux)
+	if err == nil {
+		t.Fatal("Expected an error but received none")
+	}
+	if !strings.HasPrefix(err.Error(), "aux: ") {
+		t.Fatalf("Expected an error beginning with %q but received %q", "aux: ", err)
+	}
+}
+
 // TestBigLongLine tests splitting a very long record into whitespace-separated
 // fields
 func TestBigLongLine(t *testing.T) {
@@ -1159,3 +1355,789 @@ func TestRunPipeline5(t *testing.T) {
 		t.Fatalf("Incorrect output %q", got)
 	}
 }
+
+// TestSetClock tests that a script honors a user-provided clock.
+func TestSetClock(t *testing.T) {
+	fake := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	scr := NewScript()
+	scr.SetClock(func() time.Time { return fake })
+	if now := scr.now(); !now.Equal(fake) {
+		t.Fatalf("Expected %v but received %v", fake, now)
+	}
+	scr.SetClock(nil)
+	if now := scr.now(); now.Equal(fake) {
+		t.Fatalf("Expected the current time but received %v", now)
+	}
+}
+
+// TestSetRandSource tests that a script honors a user-provided source of
+// random numbers.
+func TestSetRandSource(t *testing.T) {
+	// Two scripts with identically seeded sources should produce the same
+	// sequence of random numbers.
+	scr1 := NewScript()
+	scr1.SetRandSource(rand.NewSource(12345))
+	scr2 := NewScript()
+	scr2.SetRandSource(rand.NewSource(12345))
+	for i := 0; i < 10; i++ {
+		r1 := scr1.random().Int63()
+		r2 := scr2.random().Int63()
+		if r1 != r2 {
+			t.Fatalf("Expected %d but received %d", r1, r2)
+		}
+	}
+
+	// The default source should be deterministic, too.
+	scr1.SetRandSource(nil)
+	scr2 = NewScript()
+	if r1, r2 := scr1.random().Int63(), scr2.random().Int63(); r1 != r2 {
+		t.Fatalf("Expected %d but received %d", r1, r2)
+	}
+}
+
+// TestRequireNF tests each of the policies for handling records with an
+// unexpected number of fields.
+func TestRequireNF(t *testing.T) {
+	input := "a b c\nd e\nf g h i\n"
+	tests := []struct {
+		policy Policy // Policy to apply
+		want   string // Expected output
+	}{
+		{NFPad, "a b c|d,e,|f g h i|"},
+		{NFTruncate, "a b c|d e|f,g,h|"},
+		{NFPadTruncate, "a b c|d,e,|f,g,h|"},
+		{NFReject, "a b c|"},
+	}
+	scr := NewScript()
+	scr.Begin = func(s *Script) {
+		s.SetOFS(",")
+		s.SetORS("|")
+	}
+	scr.AppendStmt(nil, nil)
+	for _, tc := range tests {
+		buf := new(bytes.Buffer)
+		scr.Output = buf
+		scr.RequireNF(3, tc.policy)
+		err := scr.Run(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tc.want {
+			t.Fatalf("Expected %q but received %q", tc.want, buf.String())
+		}
+	}
+
+	// Ensure that NFError aborts the script.
+	scr.Output = new(bytes.Buffer)
+	scr.RequireNF(3, NFError)
+	err := scr.Run(strings.NewReader(input))
+	if err == nil {
+		t.Fatal("Expected an error but received none")
+	}
+}
+
+// TestInject tests processing synthesized records with the entire script.
+func TestInject(t *testing.T) {
+	// Define a script that expands comma-separated lists into one record
+	// per list element.
+	var got []string
+	scr := NewScript()
+	scr.Begin = func(s *Script) { s.Inject("header") }
+	scr.AppendStmt(func(s *Script) bool { return s.F(2).Match(",") }, func(s *Script) {
+		for _, e := range strings.Split(s.F(2).String(), ",") {
+			s.Inject(s.F(1).String() + " " + e)
+		}
+		s.Next()
+	})
+	scr.AppendStmt(nil, func(s *Script) { got = append(got, fmt.Sprintf("%d:%v", s.NR, s.F(0))) })
+
+	// Run the script and validate the output.
+	err := scr.Run(strings.NewReader("a 1\nb 2,3,4\nc 5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1:header 2:a 1 4:b 2 5:b 3 6:b 4 7:c 5"
+	if s := strings.Join(got, " "); s != want {
+		t.Fatalf("Expected %q but received %q", want, s)
+	}
+}
+
+// TestRunOn tests running one script on a field parsed by another.
+func TestRunOn(t *testing.T) {
+	// Define a script that sums a semicolon-separated list of numbers.
+	sum := NewScript()
+	sum.Begin = func(s *Script) {
+		s.SetRS(";")
+		s.State = 0
+	}
+	sum.AppendStmt(nil, func(s *Script) { s.State = s.State.(int) + s.F(1).Int() })
+	sum.End = func(s *Script) { s.Println(s.State) }
+
+	// Define a script that applies the preceding script to its second
+	// column.
+	scr := NewScript()
+	scr.Output = new(bytes.Buffer)
+	scr.AppendStmt(nil, func(s *Script) {
+		out, err := s.RunOn(sum, s.F(2).String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Println(s.F(1), strings.TrimSpace(out))
+	})
+
+	// Run the script and validate the output.
+	err := scr.Run(strings.NewReader("a 1;2;3\nb 10;20\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a 6\nb 30\n"
+	if got := scr.Output.(*bytes.Buffer).String(); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	if sum.Output != os.Stdout {
+		t.Fatal("RunOn failed to restore the script's output stream")
+	}
+}
+
+// TestCompat tests behaviors that depend on the compatibility level.
+func TestCompat(t *testing.T) {
+	// Define a script that exits on a given record.
+	var recs []string
+	ended := false
+	scr := NewScript()
+	scr.Begin = func(s *Script) {
+		recs = nil
+		ended = false
+	}
+	scr.AppendStmt(nil, func(s *Script) { recs = append(recs, s.F(1).String()) })
+	scr.AppendStmt(Auto("stop"), func(s *Script) { s.Exit() })
+	scr.End = func(s *Script) { ended = true }
+	input := "a\nstop\nb\n"
+
+	// The legacy behavior is to skip the End action.
+	err := scr.Run(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ended || len(recs) != 2 {
+		t.Fatalf("Incorrect legacy behavior: ended = %v, records = %v", ended, recs)
+	}
+
+	// The POSIX behavior is to perform the End action.
+	scr.SetCompat(CompatPOSIX)
+	err = scr.Run(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ended || len(recs) != 2 {
+		t.Fatalf("Incorrect POSIX behavior: ended = %v, records = %v", ended, recs)
+	}
+
+	// The POSIX behavior is to skip all records if the Begin action exits.
+	scr.Begin = func(s *Script) {
+		recs = nil
+		ended = false
+		s.Exit()
+	}
+	err = scr.Run(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ended || len(recs) != 0 {
+		t.Fatalf("Incorrect POSIX behavior: ended = %v, records = %v", ended, recs)
+	}
+
+	// The POSIX behavior is to ignore leading blank lines in paragraph
+	// mode.
+	for _, c := range []Compat{CompatLegacy, CompatPOSIX} {
+		scr = NewScript()
+		scr.SetCompat(c)
+		scr.SetRS("")
+		recs = nil
+		scr.AppendStmt(nil, func(s *Script) { recs = append(recs, s.F(0).String()) })
+		err = scr.Run(strings.NewReader("\n\n\nx\ny\n\nz\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `["x\ny" "z"]`
+		if c == CompatLegacy {
+			want = `["" "x\ny" "z"]`
+		}
+		if got := fmt.Sprintf("%q", recs); got != want {
+			t.Fatalf("Expected %s but received %s", want, got)
+		}
+	}
+}
+
+// TestFNR tests that FNR restarts with each input stream while NR does not.
+func TestFNR(t *testing.T) {
+	var got []string
+	scr := NewScript()
+	scr.AppendStmt(nil, func(s *Script) { got = append(got, fmt.Sprintf("%d/%d:%v", s.NR, s.FNR, s.F(1))) })
+	scr.AppendStmt(Auto("skip"), func(s *Script) {
+		if _, err := s.GetLine(nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// Test a single input stream.
+	err := scr.Run(strings.NewReader("a\nb\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1/1:a 2/2:b"
+	if s := strings.Join(got, " "); s != want {
+		t.Fatalf("Expected %q but received %q", want, s)
+	}
+
+	// Test multiple input streams, including GetLine reading across a
+	// stream boundary.
+	got = nil
+	err = scr.RunReaders(strings.NewReader("a\nb\n"), strings.NewReader(""), strings.NewReader("c\nskip\n"), strings.NewReader("d\ne\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "1/1:a 2/2:b 3/1:c 4/2:skip 6/2:e"
+	if s := strings.Join(got, " "); s != want {
+		t.Fatalf("Expected %q but received %q", want, s)
+	}
+}
+
+// A namedReader is a strings.Reader with a name.
+type namedReader struct {
+	*strings.Reader
+	name string
+}
+
+// Name returns a namedReader's name.
+func (nr namedReader) Name() string { return nr.name }
+
+// TestCurrentSource tests retrieving information about the current input
+// stream.
+func TestCurrentSource(t *testing.T) {
+	var got []string
+	scr := NewScript()
+	scr.AppendStmt(nil, func(s *Script) {
+		src := s.CurrentSource()
+		got = append(got, fmt.Sprintf("%s:%d:%d:%d", src.Name, src.Index, src.Bytes, src.Records))
+	})
+	err := scr.RunReaders(
+		namedReader{strings.NewReader("a\nb\n"), "first"},
+		strings.NewReader("cc\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "first:1:4:1 first:1:4:2 :2:3:1"
+	if s := strings.Join(got, " "); s != want {
+		t.Fatalf("Expected %q but received %q", want, s)
+	}
+}
+
+// TestFOffset tests retrieving the byte offsets of fields within a record.
+func TestFOffset(t *testing.T) {
+	tests := []struct {
+		setup func(s *Script) // Field-splitting configuration
+		rec   string          // Record to split
+	}{
+		{func(s *Script) {}, "  The woods are   lovely, "},
+		{func(s *Script) { s.SetFS(",") }, "dark,,and,deep"},
+		{func(s *Script) { s.SetFS("-+") }, "foo-bar---baz-"},
+		{func(s *Script) { s.SetFieldWidths([]int{3, 2, 4}) }, "abcdefghij"},
+		{func(s *Script) { s.SetFPat(`\d+`) }, "a12b345c6"},
+		{func(s *Script) { s.SetFS("") }, "日本語"},
+	}
+	for _, tc := range tests {
+		scr := NewScript()
+		tc.setup(scr)
+		err := scr.splitRecord(tc.rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i <= scr.NF; i++ {
+			start, end := scr.FOffset(i)
+			if start < 0 || end > len(tc.rec) {
+				t.Fatalf("Invalid offsets (%d, %d) for field %d of %q", start, end, i, tc.rec)
+			}
+			if got, want := tc.rec[start:end], scr.F(i).String(); got != want {
+				t.Fatalf("Expected %q for field %d of %q but received %q", want, i, tc.rec, got)
+			}
+		}
+		if start, end := scr.FOffset(scr.NF + 1); start != -1 || end != -1 {
+			t.Fatalf("Expected (-1, -1) but received (%d, %d)", start, end)
+		}
+
+		// Modifying a field should invalidate all offsets.
+		scr.SetF(1, scr.NewValue("x"))
+		if start, end := scr.FOffset(1); start != -1 || end != -1 {
+			t.Fatalf("Expected (-1, -1) but received (%d, %d)", start, end)
+		}
+	}
+}
+
+// pairSplitter is a FieldSplitter that splits a record into two-byte fields.
+type pairSplitter struct{}
+
+// Split splits a record into two-byte fields.
+func (pairSplitter) Split(rec string) ([]Field, error) {
+	fs := make([]Field, 0, (len(rec)+1)/2)
+	for i := 0; i < len(rec); i += 2 {
+		j := i + 2
+		if j > len(rec) {
+			j = len(rec)
+		}
+		fs = append(fs, Field{Text: rec[i:j], Start: i, End: j})
+	}
+	return fs, nil
+}
+
+// TestSetFieldSplitter tests using a custom field splitter.
+func TestSetFieldSplitter(t *testing.T) {
+	// Split records with a custom splitter.
+	scr := NewScript()
+	scr.SetFieldSplitter(pairSplitter{})
+	scr.SetOFS(" ")
+	var out bytes.Buffer
+	scr.Output = &out
+	var start, end int
+	scr.AppendStmt(nil, func(s *Script) {
+		start, end = s.FOffset(2)
+		s.Println(s.NF, s.F(3))
+	})
+	err := scr.Run(strings.NewReader("abcdefg\nhijk\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 2 || end != 4 {
+		t.Fatalf("Expected (2, 4) but received (%d, %d)", start, end)
+	}
+	if got, want := out.String(), "4 ef\n2 \n"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// Ensure that SetFS discards the custom splitter.
+	scr.SetFS(",")
+	out.Reset()
+	err = scr.Run(strings.NewReader("abc,de\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "2 \n"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
+
+// lengthPrefixedReader is a RecordReader that reads records of the form
+// "<length>:<text>".
+type lengthPrefixedReader struct {
+	r *bufio.Reader // Underlying reader
+}
+
+// Next returns the next length-prefixed record.
+func (lr lengthPrefixedReader) Next() (string, string, error) {
+	var n int
+	_, err := fmt.Fscanf(lr.r, "%d:", &n)
+	if err != nil {
+		return "", "", io.EOF
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(lr.r, buf)
+	if err != nil {
+		return "", "", err
+	}
+	return string(buf), ":", nil
+}
+
+// TestSetRecordReader tests using a custom record reader.
+func TestSetRecordReader(t *testing.T) {
+	// Read length-prefixed records, which may contain newlines.
+	scr := NewScript()
+	scr.SetRecordReader(func(r io.Reader) RecordReader {
+		return lengthPrefixedReader{r: bufio.NewReader(r)}
+	})
+	recs := make([]string, 0, 3)
+	scr.AppendStmt(nil, func(s *Script) {
+		recs = append(recs, s.F(0).String()+s.RT)
+	})
+	err := scr.Run(strings.NewReader("5:hello3:a\nb0:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(recs, "|"), "hello:|a\nb:|:"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+
+	// Use the built-in record reader directly.
+	scr.SetRS(";")
+	rr := scr.NewRecordReader(strings.NewReader("x;y"))
+	for _, want := range []string{"x", "y"} {
+		rec, _, err := rr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec != want {
+			t.Fatalf("Expected %q but received %q", want, rec)
+		}
+	}
+	if _, _, err := rr.Next(); err != io.EOF {
+		t.Fatalf("Expected EOF but received %v", err)
+	}
+}
+
+// TestSubF tests substitution within fields of the current record.
+func TestSubF(t *testing.T) {
+	scr := NewScript()
+	scr.splitRecord("one two three two")
+	if n := scr.GsubF(2, "o", "0"); n != 1 {
+		t.Fatalf("Expected 1 replacement but received %d", n)
+	}
+	if got, want := scr.F(0).String(), "one tw0 three two"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	if n := scr.SubF(0, "two", "2 2"); n != 1 {
+		t.Fatalf("Expected 1 replacement but received %d", n)
+	}
+	if scr.NF != 5 || scr.F(5).String() != "2" {
+		t.Fatalf("Expected 5 fields ending in %q but received %v", "2", scr.FStrings())
+	}
+}
+
+// TestEscapeFS tests splitting records with backslash-escaped separators.
+func TestEscapeFS(t *testing.T) {
+	tests := []struct {
+		fs   string   // Field separator
+		rec  string   // Record to split
+		want []string // Expected fields
+	}{
+		{",", `a\,b,c`, []string{"a,b", "c"}},
+		{",", `a\\,b,c\`, []string{`a\`, "b", `c\`}},
+		{",", `\,\,\,`, []string{",,,"}},
+		{",", `x\\\,y,\n`, []string{`x\,y`, `\n`}},
+		{" ", `my\ file  other`, []string{"my file", "other"}},
+		{"::", `a\::b::c`, []string{"a::b", "c"}},
+	}
+	for _, tc := range tests {
+		scr := NewScript()
+		scr.SetFS(tc.fs)
+		scr.EscapeFS(true)
+		err := scr.splitRecord(tc.rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := scr.FStrings(); strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Fatalf("Expected %q but received %q", tc.want, got)
+		}
+	}
+
+	// Unchanged fields should retain their offsets.
+	scr := NewScript()
+	scr.SetFS(",")
+	scr.EscapeFS(true)
+	scr.splitRecord(`a\,b,c`)
+	if start, end := scr.FOffset(2); start != 5 || end != 6 {
+		t.Fatalf("Expected (5, 6) but received (%d, %d)", start, end)
+	}
+	if start, end := scr.FOffset(1); start != -1 || end != -1 {
+		t.Fatalf("Expected (-1, -1) but received (%d, %d)", start, end)
+	}
+}
+
+// TestSetFSLiteral tests splitting records on an exact string.
+func TestSetFSLiteral(t *testing.T) {
+	tests := []struct {
+		fs   string   // Field separator
+		rec  string   // Record to split
+		want []string // Expected fields
+	}{
+		{"||", "a||b|c||||d", []string{"a", "b|c", "", "d"}},
+		{"** ", "x** y**z** ", []string{"x", "y**z", ""}},
+		{" ", " a  b", []string{"", "a", "", "b"}},
+		{".", "1.2.3", []string{"1", "2", "3"}},
+	}
+	for _, tc := range tests {
+		scr := NewScript()
+		scr.SetFSLiteral(tc.fs)
+		err := scr.splitRecord(tc.rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := scr.FStrings(); strings.Join(got, "|") != strings.Join(tc.want, "|") || len(got) != len(tc.want) {
+			t.Fatalf("Expected %q but received %q", tc.want, got)
+		}
+	}
+}
+
+// TestSetRSLiteral tests splitting input into records on an exact string.
+func TestSetRSLiteral(t *testing.T) {
+	scr := NewScript()
+	scr.SetRSLiteral("$$")
+	recs := make([]string, 0, 3)
+	scr.AppendStmt(nil, func(s *Script) {
+		recs = append(recs, s.F(0).String()+"/"+s.RT)
+	})
+	err := scr.Run(strings.NewReader("a$b$$.*$$c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(recs, " "), "a$b/$$ .*/$$ c/"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
+
+// TestSplit tests splitting strings into arrays.
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		str  string   // String to split
+		sep  string   // Separator
+		want []string // Expected elements
+	}{
+		{"  hello   there world ", " ", []string{"hello", "there", "world"}},
+		{"a,b,,c", ",", []string{"a", "b", "", "c"}},
+		{"abc", "", []string{"a", "b", "c"}},
+		{"1-2--3", "-+", []string{"1", "2", "3"}},
+		{"", ",", []string{}},
+	}
+	scr := NewScript()
+	for _, tc := range tests {
+		va, n := scr.Split(tc.str, tc.sep)
+		if n != len(tc.want) {
+			t.Fatalf("Expected %d elements but received %d", len(tc.want), n)
+		}
+		for i, want := range tc.want {
+			if got := va.Get(i + 1).String(); got != want {
+				t.Fatalf("Expected %q for element %d but received %q", want, i+1, got)
+			}
+		}
+	}
+
+	// Ensure that SplitFS honors FS.
+	scr.SetFSLiteral("||")
+	va, n := scr.SplitFS("a||b|c")
+	if n != 2 || va.Get(2).String() != "b|c" {
+		t.Fatalf("Expected 2 elements ending in %q but received %d", "b|c", n)
+	}
+}
+
+// TestCopy tests that a copied script is independent of the original.
+func TestCopy(t *testing.T) {
+	// Prepare a script with a current record and GetLine state.
+	scr := NewScript()
+	scr.SetFS(",")
+	scr.AppendStmt(Auto("x"), nil)
+	scr.splitRecord("a,b,c")
+	aux := strings.NewReader("one\ntwo\n")
+	if _, err := scr.GetLine(aux); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure that the copy has the same record but not the GetLine state.
+	sc := scr.Copy()
+	if sc.NF != 3 || sc.F(2).String() != "b" {
+		t.Fatalf("Expected 3 fields with F(2) = %q but received %v", "b", sc.FStrings())
+	}
+	sc.SetF(2, sc.NewValue("B"))
+	if scr.F(0).String() != "a,b,c" {
+		t.Fatalf("Modifying the copy modified the original's record to %q", scr.F(0))
+	}
+	if len(sc.getlineState) != 0 {
+		t.Fatal("Copy shares the original's GetLine state")
+	}
+
+	// Ensure that the copy runs correctly and independently.
+	sc.AppendStmt(nil, func(s *Script) { s.Println(s.NF) })
+	var out1, out2 bytes.Buffer
+	scr.Output = &out1
+	sc.Output = &out2
+	for _, s := range []*Script{scr, sc} {
+		if err := s.Run(strings.NewReader("x,y\nz\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := out1.String(), "x,y\n"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	if got, want := out2.String(), "x,y\n2\n1\n"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
+
+// TestClone tests that a cloned script shares nothing mutable with the
+// original.
+func TestClone(t *testing.T) {
+	scr := NewScript()
+	scr.AppendStmt(Auto("^a"), func(s *Script) { s.Println("A", s.NR) })
+	scr.splitRecord("some record")
+	scr.NR = 10
+	if !scr.NewValue("abc").Match("b+") {
+		t.Fatal("Failed to match")
+	}
+	sc := scr.Clone()
+	if sc.NR != 0 || sc.NF != 0 {
+		t.Fatalf("Expected NR = NF = 0 but received NR = %d and NF = %d", sc.NR, sc.NF)
+	}
+	scr.AppendStmt(nil, func(s *Script) { t.Fatal("Clone ran a rule added to the original") })
+	var out bytes.Buffer
+	sc.Output = &out
+	if err := sc.Run(strings.NewReader("b\nab\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "A 2\n"; got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+	if _, found := sc.frozenRegexps["b+"]; !found {
+		t.Fatal("Clone does not share compiled regular expressions")
+	}
+}
+
+// TestCloneReadOnly tests that neither Clone nor Copy modifies the original
+// script, even when Copy is called from a running script.
+func TestCloneReadOnly(t *testing.T) {
+	scr := NewScript()
+	if !scr.NewValue("abc").Match("b+") {
+		t.Fatal("Failed to match")
+	}
+	done := make(chan *Script)
+	for i := 0; i < 4; i++ {
+		go func() { done <- scr.Clone() }()
+	}
+	for i := 0; i < 4; i++ {
+		if _, found := (<-done).frozenRegexps["b+"]; !found {
+			t.Fatal("Clone does not share compiled regular expressions")
+		}
+	}
+	if _, found := scr.regexps["b+"]; !found || scr.frozenRegexps != nil {
+		t.Fatal("Clone modified the original's regular-expression cache")
+	}
+	scr.AppendStmt(nil, func(s *Script) {
+		if !s.F(1).Match("c+") {
+			t.Fatalf("Failed to match %q", s.F(1))
+		}
+		sc := s.Copy()
+		if _, found := sc.regexps["c+"]; !found {
+			t.Fatal("Copy did not copy compiled regular expressions")
+		}
+		if _, found := s.regexps["c+"]; !found || s.frozenRegexps != nil {
+			t.Fatal("Copy modified the original's regular-expression cache")
+		}
+	})
+	if err := scr.Run(strings.NewReader("abc\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// benchmarkScript returns a script with a large number of rules and cached
+// regular expressions.
+func benchmarkScript() *Script {
+	scr := NewScript()
+	for i := 0; i < 100; i++ {
+		re := fmt.Sprintf("^%d[a-z]+$", i)
+		scr.AppendStmt(Auto(re), nil)
+		scr.compileRegexp(re)
+	}
+	return scr
+}
+
+// BenchmarkCopy measures the time needed to copy a script.
+func BenchmarkCopy(b *testing.B) {
+	scr := benchmarkScript()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scr.Copy()
+	}
+}
+
+// BenchmarkClone measures the time needed to clone a script.
+func BenchmarkClone(b *testing.B) {
+	scr := benchmarkScript()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scr.Clone()
+	}
+}
+
+// TestRawBytes tests splitting on single raw bytes.
+func TestRawBytes(t *testing.T) {
+	// Split binary-ish input on NUL-terminated records and 0xFF-separated
+	// fields.
+	scr := NewScript()
+	scr.RawBytes(true)
+	scr.SetRS("\x00")
+	scr.SetFS("\xff")
+	var out []string
+	scr.AppendStmt(nil, func(s *Script) {
+		out = append(out, fmt.Sprintf("%d:%q:%q", s.NF, s.F(2), s.RT))
+	})
+	err := scr.Run(strings.NewReader("a\xffb\xfe\xffc\x00\xc3\x28\xff\x80\x00z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`3:"b\xfe":"\x00"`,
+		`2:"\x80":"\x00"`,
+		`1:"":""`,
+	}
+	if strings.Join(out, " ") != strings.Join(want, " ") {
+		t.Fatalf("Expected %v but received %v", want, out)
+	}
+
+	// Ensure that a single-space FS retains its special meaning.
+	scr = NewScript()
+	scr.RawBytes(true)
+	scr.splitRecord("  a  b ")
+	if scr.NF != 2 {
+		t.Fatalf("Expected 2 fields but received %d", scr.NF)
+	}
+}
+
+// TestBeforePrint tests that BeforePrint can suppress and rewrite the output
+// of the default action and of Println with no arguments.
+func TestBeforePrint(t *testing.T) {
+	scr := NewScript()
+	var out bytes.Buffer
+	scr.Output = &out
+	scr.BeforePrint = func(s *Script, text string) (bool, string) {
+		if strings.Contains(text, "secret") {
+			return false, ""
+		}
+		return true, strings.TrimRight(text, " ")
+	}
+	scr.AppendStmt(Auto(1, 3), nil)
+	scr.AppendStmt(Auto(4), func(s *Script) { s.Println() })
+	scr.AppendStmt(Auto(5), func(s *Script) { s.Println("untouched ") })
+	if err := scr.Run(strings.NewReader("one   \nsecret two\nthree  \nfour five\nsix\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := "one\nthree\nfour five\nuntouched \n"
+	if out.String() != want {
+		t.Fatalf("Expected %q but received %q", want, out.String())
+	}
+}
+
+// TestRandSrand tests that Rand and Srand use a per-script random-number
+// generator.
+func TestRandSrand(t *testing.T) {
+	scr1 := NewScript()
+	scr2 := NewScript()
+	for i := 0; i < 5; i++ {
+		r := scr1.Rand()
+		if r < 0 || r >= 1 {
+			t.Fatalf("Expected a number in [0, 1) but received %v", r)
+		}
+	}
+	if prev := scr1.Srand(42); prev != 0 {
+		t.Fatalf("Expected previous seed 0 but received %d", prev)
+	}
+	scr2.Rand()
+	scr2.Srand(42)
+	for i := 0; i < 5; i++ {
+		if r1, r2 := scr1.Rand(), scr2.Rand(); r1 != r2 {
+			t.Fatalf("Expected equal sequences after Srand but received %v and %v", r1, r2)
+		}
+	}
+	scr1.SetClock(func() time.Time { return time.Unix(1000, 0) })
+	if prev := scr1.Srand(); prev != 42 {
+		t.Fatalf("Expected previous seed 42 but received %d", prev)
+	}
+	if prev := scr1.Srand(7); prev != 1000 {
+		t.Fatalf("Expected previous seed 1000 but received %d", prev)
+	}
+}
@@ -0,0 +1,233 @@
+// This file provides additional built-in field splitters.
+
+package awk
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A ShellSplitter is a FieldSplitter that tokenizes a record the way a POSIX
+// shell tokenizes a command line.  Fields are separated by runs of spaces,
+// tabs, and newlines.  Within a field, text enclosed in single quotes is taken
+// literally; text enclosed in double quotes is taken literally except that a
+// backslash escapes a following "$", "`", double quote, backslash, or
+// newline; and outside of quotes, a backslash escapes any following
+// character.  Quotes are removed from the resulting fields, so a pair of
+// quotes with nothing between them produces an empty field.  A quote that is
+// never closed extends to the end of the record.
+//
+// Because quotes and escapes are removed, a field's byte offsets (see FOffset)
+// are reported only for fields that contain no quotes or escapes.
+type ShellSplitter struct{}
+
+// Split splits a record into shell-style tokens.
+func (ShellSplitter) Split(rec string) ([]Field, error) {
+	fields := make([]Field, 0, 16)
+	var tok []byte  // Current token with quotes and escapes removed
+	inTok := false  // true=within a token; false=between tokens
+	start := 0      // Byte offset of the beginning of the current token
+	literal := true // true=current token appears verbatim in the record
+	var quote byte  // Current quote character or 0 if not within quotes
+	for i := 0; i < len(rec); i++ {
+		c := rec[i]
+		switch {
+		case quote == '\'' && c == '\'':
+			quote = 0
+		case quote == '\'':
+			tok = append(tok, c)
+		case quote == '"' && c == '"':
+			quote = 0
+		case quote == '"' && c == '\\' && i+1 < len(rec) && isShellDQEscape(rec[i+1]):
+			i++
+			if rec[i] != '\n' {
+				tok = append(tok, rec[i])
+			}
+		case quote == '"':
+			tok = append(tok, c)
+		case c == ' ' || c == '\t' || c == '\n':
+			if inTok {
+				fields = append(fields, shellField(tok, start, i, literal))
+				tok, inTok = nil, false
+			}
+		default:
+			if !inTok {
+				inTok, start, literal = true, i, true
+				tok = make([]byte, 0, 32)
+			}
+			switch c {
+			case '\'', '"':
+				quote = c
+				literal = false
+			case '\\':
+				literal = false
+				if i+1 < len(rec) {
+					i++
+					if rec[i] != '\n' {
+						tok = append(tok, rec[i])
+					}
+				}
+			default:
+				tok = append(tok, c)
+			}
+		}
+	}
+	if inTok {
+		fields = append(fields, shellField(tok, start, len(rec), literal))
+	}
+	return fields, nil
+}
+
+// isShellDQEscape says whether a backslash before a given character is an
+// escape within double quotes.
+func isShellDQEscape(c byte) bool {
+	switch c {
+	case '$', '`', '"', '\\', '\n':
+		return true
+	default:
+		return false
+	}
+}
+
+// shellField returns a Field for a shell-style token, including its byte
+// offsets only if the token appears verbatim in the record.
+func shellField(tok []byte, start, end int, literal bool) Field {
+	if !literal {
+		start, end = -1, -1
+	}
+	return Field{Text: string(tok), Start: start, End: end}
+}
+
+// A BracketSplitter is a FieldSplitter that splits a record on a separator
+// string, as with SetFS, except where the separator appears between a pair
+// of matching brackets.  Brackets may be nested.  A closing bracket that does
+// not match the most recent unclosed opening bracket is treated as an
+// ordinary character, and an opening bracket that is never closed protects
+// the remainder of the record from being split.
+type BracketSplitter struct {
+	// Sep is the field separator, which is taken literally.  As with
+	// SetFS, a single space (the default) separates fields by runs of
+	// whitespace, ignoring leading and trailing whitespace.
+	Sep string
+
+	// Pairs lists opening and closing bracket characters in alternation.
+	// The default is "()[]{}".
+	Pairs string
+}
+
+// Split splits a record into fields, ignoring separators within brackets.
+func (bs BracketSplitter) Split(rec string) ([]Field, error) {
+	// Map each closing bracket to its opening bracket.
+	sep := bs.Sep
+	if sep == "" {
+		sep = " "
+	}
+	pairs := []rune(bs.Pairs)
+	if len(pairs) == 0 {
+		pairs = []rune("()[]{}")
+	}
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("Bracket pairs %q contain an odd number of characters", bs.Pairs)
+	}
+	openers := make(map[rune]bool, len(pairs)/2)
+	closers := make(map[rune]rune, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		openers[pairs[i]] = true
+		closers[pairs[i+1]] = pairs[i]
+	}
+
+	// Find each separator that lies outside of all brackets.
+	fields := make([]Field, 0, 16)
+	var stack []rune // Unclosed opening brackets
+	start := 0       // Byte offset of the current field
+	inField := sep != " "
+	for i := 0; i < len(rec); {
+		r, w := utf8.DecodeRuneInString(rec[i:])
+		switch {
+		case len(stack) > 0 && closers[r] == stack[len(stack)-1]:
+			stack = stack[:len(stack)-1]
+		case openers[r]:
+			stack = append(stack, r)
+		case len(stack) > 0:
+		case sep == " " && unicode.IsSpace(r):
+			if inField {
+				fields = append(fields, Field{Text: rec[start:i], Start: start, End: i})
+				inField = false
+			}
+		case sep != " " && strings.HasPrefix(rec[i:], sep):
+			fields = append(fields, Field{Text: rec[start:i], Start: start, End: i})
+			i += len(sep)
+			start = i
+			continue
+		}
+		if !inField && !unicode.IsSpace(r) {
+			inField = true
+			start = i
+		}
+		i += w
+	}
+	if inField && (sep == " " || len(rec) > 0) {
+		fields = append(fields, Field{Text: rec[start:], Start: start, End: len(rec)})
+	}
+	return fields, nil
+}
+
+// A QuoteSplitter is a FieldSplitter that splits a record on runs of
+// whitespace, as with SetFS(" "), except that a double-quoted segment at the
+// beginning of a field extends the field to the closing double quote, even
+// if the segment contains whitespace.  Within the segment, a backslash
+// escapes a following double quote or backslash, as in the Apache combined
+// log format's request and user-agent fields.  A field that consists
+// entirely of a quoted segment is returned without its quotes but otherwise
+// verbatim (i.e., escapes are not removed) so that its byte offsets (see
+// FOffset) remain accurate.  Any other field, including one in which text
+// follows the closing quote, is returned verbatim.  A quote that is never
+// closed extends to the end of the record.  See also Script.SetQuoteAware.
+type QuoteSplitter struct{}
+
+// Split splits a record into whitespace-separated fields, keeping quoted
+// segments intact.
+func (QuoteSplitter) Split(rec string) ([]Field, error) {
+	fields := make([]Field, 0, 16)
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\n' }
+	for i := 0; i < len(rec); {
+		// Skip leading whitespace.
+		if isSpace(rec[i]) {
+			i++
+			continue
+		}
+
+		// Find the end of a quoted segment, if any.
+		start := i
+		closed := -1 // Offset of the closing quote
+		if rec[i] == '"' {
+			for i++; i < len(rec); i++ {
+				if rec[i] == '\\' && i+1 < len(rec) && (rec[i+1] == '"' || rec[i+1] == '\\') {
+					i++
+					continue
+				}
+				if rec[i] == '"' {
+					closed = i
+					i++
+					break
+				}
+			}
+		}
+
+		// Find the end of the field.
+		for i < len(rec) && !isSpace(rec[i]) {
+			i++
+		}
+		switch {
+		case closed == i-1:
+			fields = append(fields, Field{Text: rec[start+1 : i-1], Start: start + 1, End: i - 1})
+		case closed < 0 && rec[start] == '"':
+			fields = append(fields, Field{Text: rec[start+1 : i], Start: start + 1, End: i})
+		default:
+			fields = append(fields, Field{Text: rec[start:i], Start: start, End: i})
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,138 @@
+// This file tests the additional built-in field splitters.
+
+package awk
+
+import (
+	"testing"
+)
+
+// TestShellSplitter tests splitting records into shell-style tokens.
+func TestShellSplitter(t *testing.T) {
+	tests := []struct {
+		rec  string   // Record to split
+		want []string // Expected fields
+	}{
+		{`ls -l /tmp`, []string{"ls", "-l", "/tmp"}},
+		{`  echo 'hello,   world'  `, []string{"echo", "hello,   world"}},
+		{`grep "say \"hi\"" a\ b.txt`, []string{"grep", `say "hi"`, "a b.txt"}},
+		{`x='a b'"c d"e`, []string{"x=a bc de"}},
+		{`'' "" z`, []string{"", "", "z"}},
+		{`"\n\$HOME" '\n'`, []string{`\n$HOME`, `\n`}},
+		{`echo "unterminated quote`, []string{"echo", "unterminated quote"}},
+	}
+	for _, tc := range tests {
+		scr := NewScript()
+		scr.SetFieldSplitter(ShellSplitter{})
+		err := scr.splitRecord(tc.rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scr.NF != len(tc.want) {
+			t.Fatalf("Expected %d fields in %q but received %d", len(tc.want), tc.rec, scr.NF)
+		}
+		for i, want := range tc.want {
+			if got := scr.F(i + 1).String(); got != want {
+				t.Fatalf("Expected %q for field %d of %q but received %q", want, i+1, tc.rec, got)
+			}
+		}
+	}
+
+	// Offsets should be reported only for verbatim fields.
+	scr := NewScript()
+	scr.SetFieldSplitter(ShellSplitter{})
+	scr.splitRecord(`cat  'my file' notes`)
+	if start, end := scr.FOffset(3); start != 15 || end != 20 {
+		t.Fatalf("Expected (15, 20) but received (%d, %d)", start, end)
+	}
+	if start, end := scr.FOffset(2); start != -1 || end != -1 {
+		t.Fatalf("Expected (-1, -1) but received (%d, %d)", start, end)
+	}
+}
+
+// TestBracketSplitter tests splitting records while respecting brackets.
+func TestBracketSplitter(t *testing.T) {
+	tests := []struct {
+		bs   BracketSplitter // Splitter to use
+		rec  string          // Record to split
+		want []string        // Expected fields
+	}{
+		{BracketSplitter{Sep: ","}, "f(a, b),g[1,2],{x,{y,z}}", []string{"f(a, b)", "g[1,2]", "{x,{y,z}}"}},
+		{BracketSplitter{}, "  int f(int a, char *b)  returns ", []string{"int", "f(int a, char *b)", "returns"}},
+		{BracketSplitter{Sep: ";", Pairs: "<>"}, "a;<b;c>;(d;e)", []string{"a", "<b;c>", "(d", "e)"}},
+		{BracketSplitter{Sep: ",", Pairs: "()"}, "a),b,(c,d", []string{"a)", "b", "(c,d"}},
+		{BracketSplitter{Sep: "::"}, "a::b(::)::", []string{"a", "b(::)", ""}},
+		{BracketSplitter{Sep: ","}, "", []string{}},
+	}
+	for _, tc := range tests {
+		scr := NewScript()
+		scr.SetFieldSplitter(tc.bs)
+		err := scr.splitRecord(tc.rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scr.NF != len(tc.want) {
+			t.Fatalf("Expected %d fields in %q but received %v", len(tc.want), tc.rec, scr.FStrings())
+		}
+		for i, want := range tc.want {
+			if got := scr.F(i + 1).String(); got != want {
+				t.Fatalf("Expected %q for field %d of %q but received %q", want, i+1, tc.rec, got)
+			}
+			if start, end := scr.FOffset(i + 1); tc.rec[start:end] != want {
+				t.Fatalf("Expected offsets of %q but received (%d, %d)", want, start, end)
+			}
+		}
+	}
+
+	// Ensure that unbalanced bracket pairs are rejected.
+	if _, err := (BracketSplitter{Pairs: "()["}).Split("x"); err == nil {
+		t.Fatal("Expected an error for unbalanced bracket pairs")
+	}
+}
+
+// TestQuoteAware tests treating quoted segments as single fields.
+func TestQuoteAware(t *testing.T) {
+	rec := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326 "" "Mozilla/4.08 (\"Win98\")" x"y z"`
+	want := []string{
+		"127.0.0.1", "-", "frank", "[10/Oct/2000:13:55:36", "-0700]",
+		"GET /a.gif HTTP/1.0", "200", "2326", "", `Mozilla/4.08 (\"Win98\")`,
+		`x"y`, `z"`,
+	}
+	scr := NewScript()
+	scr.SetQuoteAware(true)
+	if err := scr.splitRecord(rec); err != nil {
+		t.Fatal(err)
+	}
+	if scr.NF != len(want) {
+		t.Fatalf("Expected %d fields but received %v", len(want), scr.FStrings())
+	}
+	for i, w := range want {
+		if got := scr.F(i + 1).String(); got != w {
+			t.Fatalf("Expected %q for field %d but received %q", w, i+1, got)
+		}
+		if start, end := scr.FOffset(i + 1); rec[start:end] != w {
+			t.Fatalf("Expected offsets of %q but received (%d, %d)", w, start, end)
+		}
+	}
+
+	// Text following a closing quote and unterminated quotes should be
+	// handled sensibly.
+	for rec, want := range map[string][]string{
+		`"a b"c d`:    {`"a b"c`, "d"},
+		`x "open end`: {"x", "open end"},
+		`  `:          {},
+	} {
+		if err := scr.splitRecord(rec); err != nil {
+			t.Fatal(err)
+		}
+		if got := scr.FStrings(); len(got) != len(want) || (len(got) > 0 && got[0] != want[0]) {
+			t.Fatalf("Expected %q but received %q", want, got)
+		}
+	}
+
+	// Quote awareness should apply only to the default FS.
+	scr.SetFS(",")
+	scr.splitRecord(`"a b",c`)
+	if got := scr.F(1).String(); got != `"a b"` {
+		t.Fatalf("Expected %q but received %q", `"a b"`, got)
+	}
+}
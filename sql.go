@@ -0,0 +1,58 @@
+// This file lets Values be read from and written to databases via the
+// database/sql package.
+
+package awk
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Scan implements sql.Scanner, letting a Value be the destination of
+// sql.Row.Scan and sql.Rows.Scan.  The Value is replaced by one holding the
+// column's contents.  Integers, floating-point numbers, and booleans produce
+// numeric Values; text and byte slices produce strings, which are treated as
+// numeric strings (see IsStrNum) if they look like numbers; times are
+// formatted as RFC 3339 strings; and SQL NULL produces an empty string, like
+// an uninitialized AWK variable.  The Value remains associated with the same
+// Script, if any.
+func (v *Value) Scan(src interface{}) error {
+	s := v.script
+	switch src := src.(type) {
+	case nil:
+		*v = *s.NewValue("")
+	case int64, float64, bool:
+		*v = *s.NewValue(src)
+	case string:
+		*v = *s.newInputValue(src)
+	case []byte:
+		*v = *s.newInputValue(string(src))
+	case time.Time:
+		*v = *s.NewValue(src.Format(time.RFC3339Nano))
+	default:
+		return fmt.Errorf("Cannot scan a %T into a Value", src)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer, letting a Value be passed as an argument
+// to database/sql queries.  A Value created from an integer is passed as an
+// int64, a Value created from any other number is passed as a float64, and
+// all other Values, including numeric strings, are passed as strings.
+// Arbitrary-precision numbers are passed as strings to avoid losing
+// precision.  Value returns an error for a Value that holds a subarray.
+func (v *Value) Value() (driver.Value, error) {
+	switch {
+	case v.aval != nil:
+		return nil, errors.New("An array Value cannot be passed to a database")
+	case v.isBig():
+		return v.String(), nil
+	case v.isInt():
+		return int64(v.ival), nil
+	case v.number:
+		return v.Float64(), nil
+	}
+	return v.String(), nil
+}
@@ -0,0 +1,78 @@
+// This file tests reading and writing Values via database/sql.
+
+package awk
+
+import (
+	"database/sql/driver"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// TestSQLScan tests scanning database columns into Values.
+func TestSQLScan(t *testing.T) {
+	scr := NewScript()
+	when := time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		src    interface{}
+		want   string
+		strnum bool
+	}{
+		{int64(42), "42", false},
+		{2.5, "2.5", false},
+		{true, "1", false},
+		{"hello", "hello", false},
+		{[]byte(" 17 "), " 17 ", true},
+		{when, "2024-02-29T12:30:00Z", false},
+		{nil, "", false},
+	}
+	for _, tc := range tests {
+		v := scr.NewValue(0)
+		if err := v.Scan(tc.src); err != nil {
+			t.Fatal(err)
+		}
+		if got := v.String(); got != tc.want {
+			t.Fatalf("Expected %q but received %q", tc.want, got)
+		}
+		if v.IsStrNum() != tc.strnum {
+			t.Fatalf("Expected IsStrNum() to be %v for %q", tc.strnum, tc.want)
+		}
+		if v.script != scr {
+			t.Fatalf("Expected Scan to retain the Value's script")
+		}
+	}
+	if err := new(Value).Scan(struct{}{}); err == nil {
+		t.Fatal("Expected an error when scanning an unsupported type")
+	}
+}
+
+// TestSQLValue tests converting Values to database arguments.
+func TestSQLValue(t *testing.T) {
+	scr := NewScript()
+	big1, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	converted := scr.NewValue(1<<60 + 1)
+	converted.Float64()
+	tests := []struct {
+		v    *Value
+		want driver.Value
+	}{
+		{scr.NewValue(42), int64(42)},
+		{scr.NewValue(1.5), 1.5},
+		{scr.NewValue("text"), "text"},
+		{scr.newInputValue("7"), "7"},
+		{scr.NewValue(big1), "123456789012345678901234567890"},
+		{converted, int64(1<<60 + 1)},
+	}
+	for _, tc := range tests {
+		got, err := tc.v.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !driver.IsValue(got) || got != tc.want {
+			t.Fatalf("Expected %#v but received %#v", tc.want, got)
+		}
+	}
+	if _, err := scr.NewValue(scr.NewValueArray()).Value(); err == nil {
+		t.Fatal("Expected an error when passing an array to a database")
+	}
+}
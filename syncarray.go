@@ -0,0 +1,116 @@
+// This file defines a variant of ValueArray that is safe for concurrent use.
+
+package awk
+
+import "sync"
+
+// A SyncValueArray is a ValueArray guarded by a lock so that it can be shared
+// by multiple goroutines, such as goroutines started by actions or concurrent
+// executions of a Program that maintain common counters or sets of keys seen.
+// Values are copied on the way into and out of the array so that no Value it
+// holds is ever visible to more than one goroutine.  Use Update to perform a
+// compound operation, such as incrementing a counter, atomically.
+type SyncValueArray struct {
+	mu sync.Mutex  // Lock that guards va
+	va *ValueArray // Underlying array
+}
+
+// NewSyncValueArray creates and returns a standalone SyncValueArray, not
+// associated with any Script.
+func NewSyncValueArray() *SyncValueArray {
+	var s *Script
+	return s.NewSyncValueArray()
+}
+
+// NewSyncValueArray creates and returns a SyncValueArray associated with a
+// Script.  Because a Script is not itself safe for concurrent use, the
+// script's settings (e.g., SubSep) should not be modified while other
+// goroutines are accessing the array.
+func (s *Script) NewSyncValueArray() *SyncValueArray {
+	return &SyncValueArray{va: s.NewValueArray()}
+}
+
+// copyArgs returns a copy of a list of arguments in which every Value is
+// replaced by a copy of itself.
+func (sa *SyncValueArray) copyArgs(args []interface{}) []interface{} {
+	cp := make([]interface{}, len(args))
+	for i, a := range args {
+		if v, ok := a.(*Value); ok {
+			a = sa.va.script.NewValue(v)
+		}
+		cp[i] = a
+	}
+	return cp
+}
+
+// Set assigns a Value to an index of a SyncValueArray, as with
+// ValueArray.Set.
+func (sa *SyncValueArray) Set(args ...interface{}) {
+	args = sa.copyArgs(args)
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.va.Set(args...)
+}
+
+// Get returns a copy of the Value associated with a given index into a
+// SyncValueArray, as with ValueArray.Get.
+func (sa *SyncValueArray) Get(args ...interface{}) *Value {
+	args = sa.copyArgs(args)
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	return sa.va.script.NewValue(sa.va.Get(args...))
+}
+
+// GetOK returns a copy of the Value associated with a given index into a
+// SyncValueArray and whether the index was found, as with ValueArray.GetOK.
+func (sa *SyncValueArray) GetOK(args ...interface{}) (*Value, bool) {
+	args = sa.copyArgs(args)
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	v, ok := sa.va.GetOK(args...)
+	return sa.va.script.NewValue(v), ok
+}
+
+// Exists says whether a given index appears in a SyncValueArray, as with
+// ValueArray.Exists.
+func (sa *SyncValueArray) Exists(args ...interface{}) bool {
+	args = sa.copyArgs(args)
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	return sa.va.Exists(args...)
+}
+
+// Delete deletes a key and associated value from a SyncValueArray, as with
+// ValueArray.Delete.
+func (sa *SyncValueArray) Delete(args ...interface{}) {
+	args = sa.copyArgs(args)
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.va.Delete(args...)
+}
+
+// Update calls a function on the underlying ValueArray while holding the
+// SyncValueArray's lock, letting a sequence of operations be performed
+// atomically.  For example, a counter can be incremented safely with
+//
+//	counts.Update(func(va *ValueArray) {
+//		va.Set(key, va.Get(key).Int()+1)
+//	})
+//
+// The function must not retain the ValueArray or any Value obtained from it
+// after returning.
+func (sa *SyncValueArray) Update(f func(va *ValueArray)) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	f(sa.va)
+}
+
+// Snapshot returns an ordinary ValueArray containing a copy of a
+// SyncValueArray's current contents, as with ValueArray.Snapshot.  The
+// snapshot can be used (e.g., iterated over with Keys or Walk) without
+// holding any lock.
+func (sa *SyncValueArray) Snapshot() *ValueArray {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	return sa.va.Snapshot()
+}
@@ -0,0 +1,56 @@
+// This file tests the concurrency-safe variant of ValueArray.
+
+package awk
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestSyncValueArray tests sharing counters and a seen-set among concurrent
+// executions of a Program.
+func TestSyncValueArray(t *testing.T) {
+	counts := NewSyncValueArray()
+	seen := NewSyncValueArray()
+	scr := NewScript()
+	scr.AppendStmt(nil, func(s *Script) {
+		key := s.F(1)
+		counts.Update(func(va *ValueArray) {
+			va.Set(key, va.Get(key).Int()+1)
+		})
+		if !seen.Exists(key) {
+			seen.Set(key, s.F(2))
+		}
+		if seen.Get(key).String() == "" {
+			t.Error("Get returned an empty Value")
+		}
+	})
+	prog := scr.Compile()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := prog.Run(strings.NewReader("a 1\nb 2\na 3\nc 4\n")); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	snap := counts.Snapshot()
+	for k, want := range map[string]int{"a": 16, "b": 8, "c": 8} {
+		if got := snap.Get(k).Int(); got != want {
+			t.Fatalf("Expected %s to be %d but received %d", k, want, got)
+		}
+	}
+	if v, ok := seen.GetOK("b"); !ok || v.Int() != 2 {
+		t.Fatalf("Expected 2 and true but received %q and %v", v, ok)
+	}
+	seen.Delete("b")
+	if seen.Exists("b") {
+		t.Fatal("Delete failed to delete an element")
+	}
+}
@@ -0,0 +1,252 @@
+// This file implements gawk's time functions and the parsing of timestamps.
+
+package awk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTimeLayouts lists the layouts (in the format of the time package)
+// that Value.Time tries, in order, when neither its caller nor the script
+// specifies any.
+var defaultTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+	"02/Jan/2006:15:04:05 -0700", // Common Log Format
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.RFC822Z,
+	time.RFC822,
+	time.UnixDate,
+	time.RubyDate,
+	time.ANSIC,
+	time.Stamp,
+	"Jan _2 2006 15:04:05",
+}
+
+// SetTimeLayouts specifies the layouts (in the format of the time package)
+// that Value.Time tries, in order, when called without any layouts of its
+// own.  Calling SetTimeLayouts with no arguments restores the default list,
+// which includes RFC 3339, "YYYY-MM-DD HH:MM:SS", the Common Log Format, RFC
+// 1123, and the formats output by the date command.
+func (s *Script) SetTimeLayouts(layouts ...string) {
+	if len(layouts) == 0 {
+		s.timeLayouts = nil
+		return
+	}
+	s.timeLayouts = make([]string, len(layouts))
+	copy(s.timeLayouts, layouts)
+}
+
+// SetTimeLocation specifies the time zone in which Mktime, Strftime, and
+// Value.Time interpret times that do not specify a zone of their own.
+// Passing nil restores the default, time.Local.
+func (s *Script) SetTimeLocation(loc *time.Location) {
+	s.timeLoc = loc
+}
+
+// location returns the time zone in which a script interprets times.
+func (s *Script) location() *time.Location {
+	if s == nil || s.timeLoc == nil {
+		return time.Local
+	}
+	return s.timeLoc
+}
+
+// Systime returns the current time as the number of seconds since the Unix
+// epoch, as with gawk's systime function.  The current time is determined by
+// the script's clock (see SetClock).
+func (s *Script) Systime() int {
+	return int(s.now().Unix())
+}
+
+// Mktime converts a date specification of the form "YYYY MM DD HH MM SS" to
+// a number of seconds since the Unix epoch, as with gawk's mktime function.
+// The specification is interpreted in the script's time zone (see
+// SetTimeLocation).  As in gawk, out-of-range values are normalized (e.g.,
+// month 13 is January of the following year), and an optional seventh
+// number, a daylight-saving-time flag, is accepted but ignored.  Mktime
+// returns -1 if the specification is malformed.
+func (s *Script) Mktime(spec string) int {
+	words := strings.Fields(spec)
+	if len(words) < 6 || len(words) > 7 {
+		return -1
+	}
+	var n [6]int
+	for i := range n {
+		v, err := strconv.Atoi(words[i])
+		if err != nil {
+			return -1
+		}
+		n[i] = v
+	}
+	t := time.Date(n[0], time.Month(n[1]), n[2], n[3], n[4], n[5], 0, s.location())
+	return int(t.Unix())
+}
+
+// DefaultTimeFormat is the format Strftime uses when given an empty format
+// string.  It matches the output of the date command.
+const DefaultTimeFormat = "%a %b %e %H:%M:%S %Z %Y"
+
+// Strftime formats a number of seconds since the Unix epoch according to a
+// C-style format string, as with gawk's strftime function, and returns the
+// result as a Value.  The time is formatted in the script's time zone (see
+// SetTimeLocation).  An empty format string implies DefaultTimeFormat.
+// Strftime supports the conversions defined by POSIX (%a, %A, %b, %B, %c, %C,
+// %d, %D, %e, %F, %g, %G, %h, %H, %I, %j, %m, %M, %n, %p, %r, %R, %S, %t, %T,
+// %u, %U, %V, %w, %W, %x, %X, %y, %Y, %z, %Z, and %%) plus %s, the number of
+// seconds since the epoch.  Unrecognized conversions are output verbatim.
+func (s *Script) Strftime(format string, timestamp int) *Value {
+	if format == "" {
+		format = DefaultTimeFormat
+	}
+	t := time.Unix(int64(timestamp), 0).In(s.location())
+	return s.NewValue(strftime(format, t))
+}
+
+// strftime formats a time according to a C-style format string.
+func strftime(format string, t time.Time) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			sb.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch c := format[i]; c {
+		case 'a':
+			sb.WriteString(t.Format("Mon"))
+		case 'A':
+			sb.WriteString(t.Format("Monday"))
+		case 'b', 'h':
+			sb.WriteString(t.Format("Jan"))
+		case 'B':
+			sb.WriteString(t.Format("January"))
+		case 'c':
+			sb.WriteString(strftime("%a %b %e %H:%M:%S %Y", t))
+		case 'C':
+			fmt.Fprintf(&sb, "%02d", t.Year()/100)
+		case 'd':
+			fmt.Fprintf(&sb, "%02d", t.Day())
+		case 'D', 'x':
+			sb.WriteString(strftime("%m/%d/%y", t))
+		case 'e':
+			fmt.Fprintf(&sb, "%2d", t.Day())
+		case 'F':
+			sb.WriteString(strftime("%Y-%m-%d", t))
+		case 'g':
+			y, _ := t.ISOWeek()
+			fmt.Fprintf(&sb, "%02d", y%100)
+		case 'G':
+			y, _ := t.ISOWeek()
+			fmt.Fprintf(&sb, "%d", y)
+		case 'H':
+			fmt.Fprintf(&sb, "%02d", t.Hour())
+		case 'I':
+			fmt.Fprintf(&sb, "%02d", (t.Hour()+11)%12+1)
+		case 'j':
+			fmt.Fprintf(&sb, "%03d", t.YearDay())
+		case 'm':
+			fmt.Fprintf(&sb, "%02d", int(t.Month()))
+		case 'M':
+			fmt.Fprintf(&sb, "%02d", t.Minute())
+		case 'n':
+			sb.WriteByte('\n')
+		case 'p':
+			sb.WriteString(t.Format("PM"))
+		case 'r':
+			sb.WriteString(strftime("%I:%M:%S %p", t))
+		case 'R':
+			sb.WriteString(strftime("%H:%M", t))
+		case 's':
+			fmt.Fprintf(&sb, "%d", t.Unix())
+		case 'S':
+			fmt.Fprintf(&sb, "%02d", t.Second())
+		case 't':
+			sb.WriteByte('\t')
+		case 'T', 'X':
+			sb.WriteString(strftime("%H:%M:%S", t))
+		case 'u':
+			fmt.Fprintf(&sb, "%d", (int(t.Weekday())+6)%7+1)
+		case 'U':
+			fmt.Fprintf(&sb, "%02d", (t.YearDay()+6-int(t.Weekday()))/7)
+		case 'V':
+			_, w := t.ISOWeek()
+			fmt.Fprintf(&sb, "%02d", w)
+		case 'w':
+			fmt.Fprintf(&sb, "%d", int(t.Weekday()))
+		case 'W':
+			fmt.Fprintf(&sb, "%02d", (t.YearDay()+6-(int(t.Weekday())+6)%7)/7)
+		case 'y':
+			fmt.Fprintf(&sb, "%02d", t.Year()%100)
+		case 'Y':
+			fmt.Fprintf(&sb, "%d", t.Year())
+		case 'z':
+			sb.WriteString(t.Format("-0700"))
+		case 'Z':
+			sb.WriteString(t.Format("MST"))
+		case '%':
+			sb.WriteByte('%')
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// Time converts a Value to a time.Time.  A number, or a string that looks
+// like a number, is taken to be a number of seconds since the Unix epoch
+// (possibly fractional).  Any other string is parsed with each of the given
+// layouts (in the format of the time package) in turn until one succeeds.  If
+// no layouts are given, Time uses those specified by the associated script's
+// SetTimeLayouts or, failing that, a default list of common layouts.  Times
+// lacking a time zone are interpreted in the script's time zone (see
+// SetTimeLocation).  Like the other Value conversions, Time never fails; it
+// returns the zero time.Time if the Value cannot be parsed.
+func (v *Value) Time(layouts ...string) time.Time {
+	// Handle numeric timestamps.
+	loc := v.script.location()
+	if f, ok := v.looksNumber(); ok {
+		sec := int64(f)
+		nsec := int64((f - float64(sec)) * 1e9)
+		return time.Unix(sec, nsec).In(loc)
+	}
+
+	// Try each layout in turn.
+	if len(layouts) == 0 {
+		layouts = defaultTimeLayouts
+		if v.script != nil && v.script.timeLayouts != nil {
+			layouts = v.script.timeLayouts
+		}
+	}
+	str := strings.TrimSpace(v.String())
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, str, loc); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Duration converts a Value to a time.Duration.  A number, or a string that
+// looks like a number, is taken to be a number of seconds (possibly
+// fractional).  Any other string is parsed as a Go duration such as "1h30m"
+// or "250ms".  Like the other Value conversions, Duration never fails; it
+// returns zero if the Value cannot be parsed.
+func (v *Value) Duration() time.Duration {
+	if f, ok := v.looksNumber(); ok {
+		return time.Duration(f * float64(time.Second))
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v.String()))
+	if err != nil {
+		return 0
+	}
+	return d
+}
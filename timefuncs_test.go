@@ -0,0 +1,111 @@
+// This file tests the time functions.
+
+package awk
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSystime tests that Systime honors the script's clock.
+func TestSystime(t *testing.T) {
+	scr := NewScript()
+	scr.SetClock(func() time.Time { return time.Unix(1234567890, 0) })
+	if now := scr.Systime(); now != 1234567890 {
+		t.Fatalf("Expected 1234567890 but received %d", now)
+	}
+}
+
+// TestMktime tests converting date specifications to timestamps.
+func TestMktime(t *testing.T) {
+	scr := NewScript()
+	scr.SetTimeLocation(time.UTC)
+	for _, c := range []struct {
+		spec string
+		want int
+	}{
+		{"2009 02 13 23 31 30", 1234567890},
+		{"2009 02 13 23 31 30 -1", 1234567890},
+		{"2008 14 13 23 31 30", 1234567890},
+		{"1970 01 01 00 00 00", 0},
+		{"2009 02 13", -1},
+		{"2009 02 13 23 31 xx", -1},
+	} {
+		if got := scr.Mktime(c.spec); got != c.want {
+			t.Fatalf("Expected %d for %q but received %d", c.want, c.spec, got)
+		}
+	}
+}
+
+// TestStrftime tests formatting timestamps.
+func TestStrftime(t *testing.T) {
+	scr := NewScript()
+	scr.SetTimeLocation(time.UTC)
+	for _, c := range []struct {
+		format string
+		want   string
+	}{
+		{"", "Fri Feb 13 23:31:30 UTC 2009"},
+		{"%Y-%m-%dT%H:%M:%S%z", "2009-02-13T23:31:30+0000"},
+		{"%j %U %W %V %u %w", "044 06 06 07 5 5"},
+		{"%I:%M %p on %A, %B %e", "11:31 PM on Friday, February 13"},
+		{"%D %T %s %% %q", "02/13/09 23:31:30 1234567890 % %q"},
+	} {
+		if got := scr.Strftime(c.format, 1234567890).String(); got != c.want {
+			t.Fatalf("Expected %q but received %q", c.want, got)
+		}
+	}
+}
+
+// TestValueTime tests parsing timestamps from Values.
+func TestValueTime(t *testing.T) {
+	scr := NewScript()
+	scr.SetTimeLocation(time.UTC)
+	want := time.Unix(1234567890, 0)
+	for _, str := range []string{
+		"1234567890",
+		"2009-02-13T23:31:30Z",
+		"2009-02-13 23:31:30",
+		"13/Feb/2009:18:31:30 -0500",
+		"Fri Feb 13 23:31:30 UTC 2009",
+	} {
+		if got := scr.NewValue(str).Time(); !got.Equal(want) {
+			t.Fatalf("Expected %v for %q but received %v", want, str, got)
+		}
+	}
+	if got := scr.NewValue(1234567890.5).Time(); !got.Equal(want.Add(500 * time.Millisecond)) {
+		t.Fatalf("Expected a fractional second but received %v", got)
+	}
+	if got := scr.NewValue("13.02.2009").Time(); !got.IsZero() {
+		t.Fatalf("Expected the zero time but received %v", got)
+	}
+	if got := scr.NewValue("13.02.2009").Time("02.01.2006"); !got.Equal(time.Date(2009, 2, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected February 13 but received %v", got)
+	}
+	scr.SetTimeLayouts("02.01.2006")
+	if got := scr.NewValue("13.02.2009").Time(); got.IsZero() {
+		t.Fatalf("Expected SetTimeLayouts to be honored")
+	}
+}
+
+// TestValueDuration tests parsing durations from Values.
+func TestValueDuration(t *testing.T) {
+	scr := NewScript()
+	for _, c := range []struct {
+		v    interface{}
+		want time.Duration
+	}{
+		{"1h30m", 90 * time.Minute},
+		{" 250ms ", 250 * time.Millisecond},
+		{"1.5", 1500 * time.Millisecond},
+		{2, 2 * time.Second},
+		{0.001, time.Millisecond},
+		{"-3s", -3 * time.Second},
+		{"soon", 0},
+		{"", 0},
+	} {
+		if got := scr.NewValue(c.v).Duration(); got != c.want {
+			t.Fatalf("Expected %v for %v but received %v", c.want, c.v, got)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// This file converts human-readable sizes and durations to numbers.
+
+package awk
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// matchSize matches a size with an optional unit suffix.
+var matchSize = regexp.MustCompile(`^([-+]?(?:\d+(?:\.\d*)?|\.\d+)(?:[Ee][-+]?\d+)?)\s*([KkMmGgTtPpEe]?)(i?)[Bb]?$`)
+
+// sizeExps maps a unit prefix to its exponent.
+var sizeExps = map[string]int{
+	"":  0,
+	"k": 1,
+	"m": 2,
+	"g": 3,
+	"t": 4,
+	"p": 5,
+	"e": 6,
+}
+
+// Bytes converts a Value representing a human-readable size, such as "1.5G",
+// "200Mi", "64KiB", or "512", to a number of bytes.  Unit prefixes are
+// case-insensitive and may be followed by "B".  As in Kubernetes resource
+// quantities, the prefixes K, M, G, T, P, and E denote powers of 1000, while
+// Ki, Mi, Gi, Ti, Pi, and Ei denote powers of 1024.  Like the other Value
+// conversions, Bytes never fails; it returns zero if the Value cannot be
+// parsed.
+func (v *Value) Bytes() float64 {
+	if f, ok := v.looksNumber(); ok {
+		return f
+	}
+	strs := matchSize.FindStringSubmatch(strings.TrimSpace(v.String()))
+	if strs == nil || (strs[2] == "" && strs[3] != "") {
+		return 0
+	}
+	f, _ := strconv.ParseFloat(strs[1], 64)
+	base := 1000.0
+	if strs[3] != "" {
+		base = 1024.0
+	}
+	for i := sizeExps[strings.ToLower(strs[2])]; i > 0; i-- {
+		f *= base
+	}
+	return f
+}
+
+// matchDurationPart matches one number and unit within a duration.
+var matchDurationPart = regexp.MustCompile(`^\s*((?:\d+(?:\.\d*)?|\.\d+)(?:[Ee][-+]?\d+)?)\s*(ns|us|µs|μs|ms|s|m|h|d|w)`)
+
+// durationUnits maps a duration unit to its length in seconds.
+var durationUnits = map[string]float64{
+	"ns": 1e-9,
+	"us": 1e-6,
+	"µs": 1e-6, // U+00B5 (micro sign)
+	"μs": 1e-6, // U+03BC (Greek small letter mu)
+	"ms": 1e-3,
+	"s":  1,
+	"m":  60,
+	"h":  60 * 60,
+	"d":  24 * 60 * 60,
+	"w":  7 * 24 * 60 * 60,
+}
+
+// DurationUnits converts a Value representing a human-readable duration, such
+// as "250ms", "2h3m", "1.5d", or "3 s", to a number of seconds.  A duration is
+// an optionally signed sequence of numbers, each followed by one of the units
+// ns, us (or µs), ms, s, m, h, d, or w.  A number with no unit is taken to be
+// a number of seconds.  Unlike Duration, which produces a time.Duration,
+// DurationUnits accepts days and weeks and produces a number that can be
+// summed and compared like any other.  Like the other Value conversions,
+// DurationUnits never fails; it returns zero if the Value cannot be parsed.
+func (v *Value) DurationUnits() float64 {
+	if f, ok := v.looksNumber(); ok {
+		return f
+	}
+	str := strings.TrimSpace(v.String())
+	sign := 1.0
+	switch {
+	case strings.HasPrefix(str, "-"):
+		sign = -1.0
+		fallthrough
+	case strings.HasPrefix(str, "+"):
+		str = str[1:]
+	}
+	if str == "" {
+		return 0
+	}
+	secs := 0.0
+	for str != "" {
+		strs := matchDurationPart.FindStringSubmatch(str)
+		if strs == nil {
+			return 0
+		}
+		f, _ := strconv.ParseFloat(strs[1], 64)
+		secs += f * durationUnits[strs[2]]
+		str = strings.TrimSpace(str[len(strs[0]):])
+	}
+	return sign * secs
+}
@@ -0,0 +1,64 @@
+// This file tests conversions of human-readable sizes and durations.
+
+package awk
+
+import (
+	"testing"
+)
+
+// TestBytes tests converting human-readable sizes to bytes.
+func TestBytes(t *testing.T) {
+	scr := NewScript()
+	tests := []struct {
+		in   interface{} // Value to convert
+		want float64     // Expected number of bytes
+	}{
+		{"512", 512},
+		{1024, 1024},
+		{"1.5G", 1.5e9},
+		{"200Mi", 200 * 1024 * 1024},
+		{"64KiB", 64 * 1024},
+		{"3 kb", 3000},
+		{" 2TB ", 2e12},
+		{"1E", 1e18},
+		{"-4k", -4000},
+		{"10B", 10},
+		{"5i", 0},
+		{"7X", 0},
+		{"lots", 0},
+	}
+	for _, tc := range tests {
+		if got := scr.NewValue(tc.in).Bytes(); got != tc.want {
+			t.Fatalf("Expected %v for %q but received %v", tc.want, tc.in, got)
+		}
+	}
+}
+
+// TestDurationUnits tests converting human-readable durations to seconds.
+func TestDurationUnits(t *testing.T) {
+	scr := NewScript()
+	tests := []struct {
+		in   interface{} // Value to convert
+		want float64     // Expected number of seconds
+	}{
+		{"250ms", 0.25},
+		{"2h3m", 2*3600 + 3*60},
+		{"1.5d", 1.5 * 86400},
+		{"1w 2d", 9 * 86400},
+		{"3 s", 3},
+		{"-90s", -90},
+		{"42", 42},
+		{2.5, 2.5},
+		{"500us", 500e-6},
+		{"500µs", 500e-6},
+		{"1h fortnight", 0},
+		{"-", 0},
+		{"soon", 0},
+	}
+	for _, tc := range tests {
+		got := scr.NewValue(tc.in).DurationUnits()
+		if d := got - tc.want; d > 1e-12 || d < -1e-12 {
+			t.Fatalf("Expected %v for %q but received %v", tc.want, tc.in, got)
+		}
+	}
+}
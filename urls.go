@@ -0,0 +1,91 @@
+// This file provides helpers for decomposing URLs, such as those found in
+// web-server access logs.
+
+package awk
+
+import (
+	"net/url"
+	"strings"
+)
+
+// URL parses a Value as a URL, which may be relative, as is the request path
+// in an access log (e.g., "/index.html?lang=en").  Like the other Value
+// conversions, URL never fails; it returns an empty url.URL if the Value
+// cannot be parsed.
+func (v *Value) URL() *url.URL {
+	u, err := url.Parse(strings.TrimSpace(v.String()))
+	if err != nil {
+		return &url.URL{}
+	}
+	return u
+}
+
+// urlPart returns the named component of a parsed URL.  A name beginning
+// with "?" names a query parameter.
+func urlPart(u *url.URL, name string) string {
+	if strings.HasPrefix(name, "?") {
+		return u.Query().Get(name[1:])
+	}
+	switch name {
+	case "scheme":
+		return u.Scheme
+	case "user":
+		return u.User.Username()
+	case "host":
+		return u.Host
+	case "hostname":
+		return u.Hostname()
+	case "port":
+		return u.Port()
+	case "path":
+		return u.Path
+	case "query":
+		return u.RawQuery
+	case "fragment":
+		return u.Fragment
+	}
+	return ""
+}
+
+// URLPart parses a Value as a URL and returns one of its components as a
+// Value.  The component is named "scheme", "user", "host" (including any
+// port), "hostname" (excluding any port), "port", "path" (decoded and without
+// the query string), "query" (the raw query string), or "fragment".  A name
+// beginning with "?" instead returns the first value of the query parameter
+// that follows, so URLPart("?id") returns the value of the id parameter.
+// Components that are absent, and unrecognized names, produce an empty
+// string.
+func (v *Value) URLPart(name string) *Value {
+	return v.script.NewValue(urlPart(v.URL(), name))
+}
+
+// URLQuery parses a Value as a URL and returns the first value of a given
+// query parameter, or an empty string if the parameter is absent.
+func (v *Value) URLQuery(key string) *Value {
+	return v.URLPart("?" + key)
+}
+
+// A parsedURL caches the result of parsing a field as a URL.
+type parsedURL struct {
+	text string   // Field contents that were parsed
+	u    *url.URL // Result of parsing text
+}
+
+// URLField treats field i of the current record as a URL and returns the
+// named component, as with Value.URLPart.  Each field is parsed only once per
+// record no matter how many components are requested, so an access-log
+// script can cheaply explode its request column into named sub-fields, as in
+// s.URLField(7, "path") to group requests by path without the query string
+// and s.URLField(7, "?user") to extract a query parameter.
+func (s *Script) URLField(i int, name string) *Value {
+	text := s.F(i).String()
+	pu, ok := s.urlCache[i]
+	if !ok || pu.text != text {
+		if s.urlCache == nil {
+			s.urlCache = make(map[int]parsedURL)
+		}
+		pu = parsedURL{text: text, u: s.F(i).URL()}
+		s.urlCache[i] = pu
+	}
+	return s.NewValue(urlPart(pu.u, name))
+}
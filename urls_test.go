@@ -0,0 +1,70 @@
+// This file tests decomposing URLs.
+
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestURLPart tests extracting URL components from Values.
+func TestURLPart(t *testing.T) {
+	scr := NewScript()
+	v := scr.NewValue("https://alice@example.com:8443/a%20b/c?id=7&id=8&q=go#top")
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"scheme", "https"},
+		{"user", "alice"},
+		{"host", "example.com:8443"},
+		{"hostname", "example.com"},
+		{"port", "8443"},
+		{"path", "/a b/c"},
+		{"query", "id=7&id=8&q=go"},
+		{"fragment", "top"},
+		{"?id", "7"},
+		{"?missing", ""},
+		{"bogus", ""},
+	}
+	for _, tc := range tests {
+		if got := v.URLPart(tc.name).String(); got != tc.want {
+			t.Fatalf("Expected %q for %q but received %q", tc.want, tc.name, got)
+		}
+	}
+	if got := v.URLQuery("q").String(); got != "go" {
+		t.Fatalf("Expected %q but received %q", "go", got)
+	}
+	if u := scr.NewValue("%zz").URL(); u.String() != "" {
+		t.Fatalf("Expected an empty URL but received %q", u)
+	}
+}
+
+// TestURLField tests grouping access-log requests by path.
+func TestURLField(t *testing.T) {
+	var out bytes.Buffer
+	scr := NewScript()
+	scr.Output = &out
+	hits := scr.NewValueArray()
+	scr.AppendStmt(nil, func(s *Script) {
+		p := s.URLField(2, "path")
+		hits.Set(p, hits.Get(p).Int()+1)
+		if lang := s.URLField(2, "?lang"); lang.String() != "" {
+			s.Println(s.NR, lang)
+		}
+	})
+	scr.End = func(s *Script) {
+		for _, k := range hits.SortedKeys(nil) {
+			s.Println(k, hits.Get(k))
+		}
+	}
+	input := "GET /index.html?lang=en\nGET /about\nGET /index.html\nGET /index.html?lang=fr&x=1\n"
+	if err := scr.Run(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	want := "1 en\n4 fr\n/about 1\n/index.html 3\n"
+	if got := out.String(); got != want {
+		t.Fatalf("Expected %q but received %q", want, got)
+	}
+}
@@ -5,9 +5,12 @@ package awk
 
 import (
 	"fmt"
+	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 const convFmt = "%.6g"
@@ -23,12 +26,97 @@ type Value struct {
 	fvalOk bool // true: fval is valid; false: invalid
 	svalOk bool // true: sval is valid; false: invalid
 
-	script *Script // Pointer to the script that produced this value
+	number bool // true: Value was created from a number
+	float  bool // true: Value was created from a non-integral type of number
+	strnum bool // true: Value is input data that looks like a number
+
+	aval *ValueArray // Subarray (nil if the Value is a scalar)
+
+	bint *big.Int   // Arbitrary-precision integer (nil if none)
+	bflt *big.Float // Arbitrary-precision floating-point number (nil if none)
+
+	script *Script // Pointer to the script that produced this value (nil for a standalone Value)
+
+	caseMode caseOverride // Whether the Value overrides the script's case sensitivity
+}
+
+// A caseOverride indicates whether a Value overrides its script's case
+// sensitivity.
+type caseOverride int8
+
+// These are the possible values for a caseOverride.
+const (
+	caseDefault caseOverride = iota // Use the script's setting
+	caseIgnore                      // Always ignore case
+	caseRespect                     // Never ignore case
+)
+
+// NewValue creates a standalone Value, not associated with any Script, from
+// an arbitrary Go data type.  A standalone Value uses the default number
+// conversion format, "%.6g", and case-sensitive comparisons until it is
+// associated with a Script by calling Bind.
+func NewValue(v interface{}) *Value {
+	var s *Script
+	return s.NewValue(v)
+}
+
+// Bind associates a Value with a Script so that subsequent operations on the
+// Value honor the Script's number conversion format and case sensitivity.
+// Bind returns its receiver to facilitate chaining.
+func (v *Value) Bind(s *Script) *Value {
+	v.script = s
+	return v
+}
+
+// IgnoringCase returns a copy of a Value whose string comparisons and
+// regular-expression matches are case-insensitive (if ign is true) or
+// case-sensitive (if ign is false) regardless of the associated script's
+// IgnoreCase, IgnoreCaseRegex, and IgnoreCaseStrings settings.  This
+// provides a per-call override, as in
+// s.F(1).IgnoringCase(false).StrEqual(key).
+func (v *Value) IgnoringCase(ign bool) *Value {
+	nv := v.script.NewValue(v)
+	nv.caseMode = caseRespect
+	if ign {
+		nv.caseMode = caseIgnore
+	}
+	return nv
+}
+
+// ignoreCase says whether string comparisons involving a Value should be
+// performed in a case-insensitive manner.
+func (v *Value) ignoreCase() bool {
+	switch v.caseMode {
+	case caseIgnore:
+		return true
+	case caseRespect:
+		return false
+	}
+	return v.script != nil && v.script.ignCaseStr
+}
+
+// compileRegexp compiles a regular expression to match against a Value,
+// honoring any case-sensitivity override.
+func (v *Value) compileRegexp(expr string) (*regexp.Regexp, error) {
+	switch v.caseMode {
+	case caseIgnore:
+		return v.script.compileRegexpCase(expr, true)
+	case caseRespect:
+		return v.script.compileRegexpCase(expr, false)
+	}
+	return v.script.compileRegexp(expr)
 }
 
-// NewValue creates a Value from an arbitrary Go data type.  Data types that do
-// not map straightforwardly to one of {int, float64, string} are represented
-// by a zero value.
+// NewValue creates a Value from an arbitrary Go data type.  A *ValueArray
+// produces a Value that holds a subarray (see ValueArray.SubArray).  Other data
+// types that do not map straightforwardly to one of {int, float64, string} are
+// represented by a zero value.  A *big.Int or *big.Float produces a Value
+// that retains the number's full precision (see BigInt and BigFloat).  A
+// time.Time produces a number of seconds since the Unix epoch whose string
+// form is the time formatted with the first of the script's time layouts (see
+// SetTimeLayouts) in the script's time zone.  A time.Duration produces a
+// number of seconds, a []byte produces a string, and any other type with a
+// String method (i.e., a fmt.Stringer) produces the result of that method.
 func (s *Script) NewValue(v interface{}) *Value {
 	val := &Value{}
 	switch v := v.(type) {
@@ -91,16 +179,80 @@ func (s *Script) NewValue(v interface{}) *Value {
 		val.sval = v
 		val.svalOk = true
 
+	case *big.Int:
+		val.bint = new(big.Int).Set(v)
+		val.fval, _ = new(big.Float).SetInt(v).Float64()
+		val.fvalOk = true
+		if v.IsInt64() {
+			val.ival = int(v.Int64())
+			val.ivalOk = true
+		}
+		val.sval = v.String()
+		val.svalOk = true
+	case *big.Float:
+		val.bflt = new(big.Float).Copy(v)
+		val.fval, _ = v.Float64()
+		val.fvalOk = true
+
 	case *Value:
 		*val = *v
 
+	case *ValueArray:
+		val.aval = v
+		val.svalOk = true
+
+	case time.Time:
+		val.ival = int(v.Unix())
+		val.ivalOk = true
+		if v.Nanosecond() != 0 {
+			val.fval = float64(v.UnixNano()) / 1e9
+			val.fvalOk = true
+		}
+		layout := defaultTimeLayouts[0]
+		if s != nil && s.timeLayouts != nil {
+			layout = s.timeLayouts[0]
+		}
+		val.sval = v.In(s.location()).Format(layout)
+		val.svalOk = true
+	case time.Duration:
+		if v%time.Second == 0 {
+			val.ival = int(v / time.Second)
+			val.ivalOk = true
+		} else {
+			val.fval = v.Seconds()
+			val.fvalOk = true
+		}
+
+	case []byte:
+		val.sval = string(v)
+		val.svalOk = true
+	case fmt.Stringer:
+		val.sval = v.String()
+		val.svalOk = true
+
 	default:
 		val.svalOk = true
 	}
+	if _, ok := v.(*Value); !ok {
+		val.number = val.ivalOk || val.fvalOk
+		val.float = val.fvalOk && (!val.ivalOk || val.fval != float64(val.ival))
+	}
 	val.script = s
 	return val
 }
 
+// IsArray says whether a Value holds a subarray rather than a scalar.
+func (v *Value) IsArray() bool {
+	return v.aval != nil
+}
+
+// Array returns the subarray held by a Value and true or, if the Value is a
+// scalar, nil and false.  As in AWK, a subarray converts to a zero value when
+// treated as a scalar.
+func (v *Value) Array() (*ValueArray, bool) {
+	return v.aval, v.aval != nil
+}
+
 // matchInt matches a base-ten integer.
 var matchInt = regexp.MustCompile(`^\s*([-+]?\d+)`)
 
@@ -112,6 +264,12 @@ func (v *Value) Int() int {
 		v.ival = int(v.fval)
 		v.ivalOk = true
 	case v.svalOk:
+		if f, ok := v.lenientFloat64(); ok {
+			v.ival = int(f)
+			v.ivalOk = true
+			break
+		}
+
 		// Perform a best-effort conversion from string to int.
 		strs := matchInt.FindStringSubmatch(v.sval)
 		var i64 int64
@@ -135,6 +293,12 @@ func (v *Value) Float64() float64 {
 		v.fval = float64(v.ival)
 		v.fvalOk = true
 	case v.svalOk:
+		if f, ok := v.lenientFloat64(); ok {
+			v.fval = f
+			v.fvalOk = true
+			break
+		}
+
 		// Perform a best-effort conversion from string to float64.
 		v.fval = 0.0
 		strs := matchFloat.FindStringSubmatch(v.sval)
@@ -146,26 +310,118 @@ func (v *Value) Float64() float64 {
 	return v.fval
 }
 
+// matchOctal matches a base-eight integer written with a leading zero.
+var matchOctal = regexp.MustCompile(`^[-+]?0[0-7]+$`)
+
+// Strtonum converts a Value to a float64 like gawk's strtonum function.  A
+// number is converted as is.  A string is converted as a hexadecimal integer
+// if it begins with "0x" or "0X", as an octal integer if it begins with "0"
+// and contains only octal digits, and as a decimal number otherwise.  Leading
+// whitespace and a sign are allowed in all cases.
+func (v *Value) Strtonum() float64 {
+	if !v.svalOk {
+		return v.Float64()
+	}
+	str := strings.TrimSpace(v.sval)
+	body := strings.TrimLeft(str, "+-")
+	if len(body) > 2 && body[0] == '0' && (body[1] == 'x' || body[1] == 'X') {
+		return float64(v.IntBase(16))
+	}
+	if num := matchFloat.FindStringSubmatch(str); num != nil && matchOctal.MatchString(num[1]) {
+		return float64(v.IntBase(8))
+	}
+	return v.Float64()
+}
+
+// IntBase converts a Value, treated as a string, to an int by interpreting it
+// as an integer in a given base between 2 and 36.  For base 16, an optional
+// "0x" or "0X" prefix is permitted.  A base of 0 selects the base from the
+// string's prefix, as in Strtonum: "0x" or "0X" for hexadecimal, "0" for
+// octal, "0b" or "0B" for binary, and base ten otherwise.  Like Int, IntBase
+// performs a best-effort conversion, ignoring leading whitespace and
+// everything following the longest valid prefix and returning 0 if there are
+// no valid digits.  IntBase returns 0 for an invalid base.
+func (v *Value) IntBase(base int) int {
+	// Strip leading whitespace and the sign.
+	str := strings.TrimLeft(v.String(), " \t\n\r\f\v")
+	neg := false
+	if str != "" && (str[0] == '-' || str[0] == '+') {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+
+	// Determine the base and strip any prefix.
+	hasPrefix := func(p string) bool {
+		return len(str) > len(p) && strings.EqualFold(str[:len(p)], p)
+	}
+	switch {
+	case (base == 0 || base == 16) && hasPrefix("0x"):
+		base = 16
+		str = str[2:]
+	case base == 0 && hasPrefix("0b"):
+		base = 2
+		str = str[2:]
+	case base == 0 && hasPrefix("0"):
+		base = 8
+	case base == 0:
+		base = 10
+	case base < 2 || base > 36:
+		return 0
+	}
+
+	// Parse the longest prefix of valid digits.
+	n := 0
+	for n < len(str) {
+		c := str[n] | 0x20 // Map letters to lowercase.
+		var d int
+		switch {
+		case c >= '0' && c <= '9':
+			d = int(c - '0')
+		case c >= 'a' && c <= 'z':
+			d = int(c-'a') + 10
+		default:
+			d = base
+		}
+		if d >= base {
+			break
+		}
+		n++
+	}
+	i64, _ := strconv.ParseInt(str[:n], base, 0)
+	if neg {
+		i64 = -i64
+	}
+	return int(i64)
+}
+
 // String converts a Value to a string.
 func (v *Value) String() string {
 	switch {
 	case v.svalOk:
+	case v.bflt != nil:
+		v.sval = v.bigString()
+		v.svalOk = true
 	case v.ivalOk:
 		v.sval = strconv.FormatInt(int64(v.ival), 10)
 		v.svalOk = true
 	case v.fvalOk:
-		v.sval = fmt.Sprintf(v.script.ConvFmt, v.fval)
+		cf := convFmt
+		if v.script != nil {
+			cf = v.script.ConvFmt
+		}
+		v.sval = fmt.Sprintf(cf, v.fval)
 		v.svalOk = true
 	}
 	return v.sval
 }
 
 // Match says whether a given regular expression, provided as a string, matches
-// the Value.  If the associated script set IgnoreCase(true), the match is
-// tested in a case-insensitive manner.
+// the Value.  If the associated script set IgnoreCase(true) or
+// IgnoreCaseRegex(true), the match is tested in a case-insensitive manner.
+// RStart and RLength are updated only for Values associated with a Script.
 func (v *Value) Match(expr string) bool {
 	// Compile the regular expression.
-	re, err := v.script.compileRegexp(expr)
+	re, err := v.compileRegexp(expr)
 	if err != nil {
 		return false // Fail silently
 	}
@@ -173,6 +429,9 @@ func (v *Value) Match(expr string) bool {
 	// Return true if the expression matches the value, interpreted as a
 	// string.
 	loc := re.FindStringIndex(v.String())
+	if v.script == nil {
+		return loc != nil
+	}
 	if loc == nil {
 		v.script.RStart = 0
 		v.script.RLength = -1
@@ -183,27 +442,200 @@ func (v *Value) Match(expr string) bool {
 	return true
 }
 
+// MatchGroups is like Match but additionally returns the text and location of
+// the match and of each parenthesized subexpression, much like gawk's
+// three-argument match function.  Element 0 of the returned ValueArray holds
+// the matched text, and element i holds the text matched by the ith
+// subexpression.  Elements (i, "start") and (i, "length") hold the 1-based
+// position and the length of the corresponding match, using the same
+// convention as RStart and RLength.  The text matched by a named
+// subexpression is also stored under its name.  Subexpressions that did not
+// participate in the match are omitted.  If the regular expression does not
+// match, MatchGroups returns an empty ValueArray and false.
+func (v *Value) MatchGroups(expr string) (*ValueArray, bool) {
+	// Compile the regular expression.
+	va := v.script.NewValueArray()
+	re, err := v.compileRegexp(expr)
+	if err != nil {
+		return va, false // Fail silently
+	}
+
+	// Find the match and all submatches.
+	str := v.String()
+	locs := re.FindStringSubmatchIndex(str)
+	if v.script != nil {
+		v.script.RStart = 0
+		v.script.RLength = -1
+	}
+	if locs == nil {
+		return va, false
+	}
+	if v.script != nil {
+		v.script.RStart = locs[0] + 1
+		v.script.RLength = locs[1] - locs[0]
+	}
+
+	// Store each submatch in the array.
+	names := re.SubexpNames()
+	for i := 0; i < len(locs)/2; i++ {
+		b, e := locs[2*i], locs[2*i+1]
+		if b < 0 {
+			continue
+		}
+		va.Set(i, str[b:e])
+		va.Set(i, "start", b+1)
+		va.Set(i, "length", e-b)
+		if names[i] != "" {
+			va.Set(names[i], str[b:e])
+		}
+	}
+	return va, true
+}
+
+// substitute replaces either the first match or all matches of a regular
+// expression within a Value, treated as a string.  It returns the new Value
+// and the number of replacements that were made.
+func (v *Value) substitute(expr, repl string, global bool) (*Value, int) {
+	// Compile the regular expression.
+	re, err := v.compileRegexp(expr)
+	if err != nil {
+		return v, 0 // Fail silently
+	}
+
+	// Find all matches to replace.
+	n := 1
+	if global {
+		n = -1
+	}
+	str := v.String()
+	locs := re.FindAllStringIndex(str, n)
+	if locs == nil {
+		return v, 0
+	}
+
+	// Replace each match with the replacement text.  As in AWK, "&" in the
+	// replacement text stands for the matched text, "\\&" stands for a
+	// literal ampersand, and "\\\\" stands for a literal backslash.
+	var sb strings.Builder
+	prev := 0
+	for _, loc := range locs {
+		sb.WriteString(str[prev:loc[0]])
+		for i := 0; i < len(repl); i++ {
+			switch {
+			case repl[i] == '&':
+				sb.WriteString(str[loc[0]:loc[1]])
+			case repl[i] == '\\' && i+1 < len(repl) && (repl[i+1] == '&' || repl[i+1] == '\\'):
+				i++
+				sb.WriteByte(repl[i])
+			default:
+				sb.WriteByte(repl[i])
+			}
+		}
+		prev = loc[1]
+	}
+	sb.WriteString(str[prev:])
+	return v.script.NewValue(sb.String()), len(locs)
+}
+
+// Sub returns a Value in which the first match of a regular expression,
+// provided as a string, is replaced by a given string, along with the number
+// of replacements made (0 or 1).  As in AWK, an "&" in the replacement string
+// is replaced by the matched text; use "\\&" for a literal ampersand.  If the
+// associated script set IgnoreCase(true) or IgnoreCaseRegex(true), the
+// regular expression is matched in a case-insensitive manner.  Because Values
+// are immutable, Sub returns a new Value rather than modifying its receiver;
+// see Script.SubF for modifying a field of the current record.
+func (v *Value) Sub(expr, repl string) (*Value, int) {
+	return v.substitute(expr, repl, false)
+}
+
+// Gsub is like Sub but replaces all non-overlapping matches of the regular
+// expression, not just the first.
+func (v *Value) Gsub(expr, repl string) (*Value, int) {
+	return v.substitute(expr, repl, true)
+}
+
+// IndexOf returns the 1-based position, in characters, of the first
+// occurrence of a substring within a Value, treated as a string, or 0 if the
+// substring does not occur.  The substring can be provided either as a Value
+// or as any type that can be converted to a Value.  If the associated script
+// called IgnoreCase(true) or IgnoreCaseStrings(true), the search is performed
+// in a case-insensitive manner.  IndexOf mirrors AWK's index function.
+func (v *Value) IndexOf(substr interface{}) int {
+	str := v.String()
+	var sub string
+	switch substr := substr.(type) {
+	case *Value:
+		sub = substr.String()
+	case string:
+		sub = substr
+	default:
+		sub = v.script.NewValue(substr).String()
+	}
+
+	// Handle the case-sensitive case.
+	if !v.ignoreCase() {
+		i := strings.Index(str, sub)
+		if i < 0 {
+			return 0
+		}
+		return utf8.RuneCountInString(str[:i]) + 1
+	}
+
+	// Handle the case-insensitive case by comparing the substring to each
+	// equally long (in characters) slice of the string.
+	nsub := utf8.RuneCountInString(sub)
+	pos := 1
+	for i := range str {
+		j := i
+		for n := 0; n < nsub && j < len(str); n++ {
+			_, w := utf8.DecodeRuneInString(str[j:])
+			j += w
+		}
+		if strings.EqualFold(str[i:j], sub) {
+			return pos
+		}
+		pos++
+	}
+	if sub == "" {
+		return 1
+	}
+	return 0
+}
+
+// Len returns the length of a Value, treated as a string, in characters (not
+// bytes).  Len mirrors AWK's length function.
+func (v *Value) Len() int {
+	return utf8.RuneCountInString(v.String())
+}
+
 // StrEqual says whether a Value, treated as a string, has the same contents as
 // a given Value, which can be provided either as a Value or as any type that
 // can be converted to a Value.  If the associated script called
-// IgnoreCase(true), the comparison is performed in a case-insensitive manner.
+// IgnoreCase(true) or IgnoreCaseStrings(true), the comparison is performed in
+// a case-insensitive manner.  At CompatPOSIX and above (see SetCompat), if
+// one Value is a numeric string read from the input (see IsStrNum) and the
+// other is a number or another numeric string, they are instead compared
+// numerically, as in AWK, so that, for example, a field containing " 10 " is
+// equal to 10.  If the associated script called SetCollator, strings are
+// equal if the collator considers them equal.
 func (v *Value) StrEqual(v2 interface{}) bool {
+	if v.script != nil && v.script.compat >= CompatPOSIX {
+		if x, ok := v2.(*Value); ok || v.strnum {
+			if !ok {
+				x = v.script.NewValue(v2)
+			}
+			if (v.strnum && (x.number || x.strnum)) || (v.number && x.strnum) {
+				return v.Cmp(x) == 0
+			}
+		}
+	}
 	switch v2 := v2.(type) {
 	case *Value:
-		if v.script.ignCase {
-			return strings.EqualFold(v.String(), v2.String())
-		}
-		return v.String() == v2.String()
+		return v.equalStrings(v.String(), v2.String())
 	case string:
-		if v.script.ignCase {
-			return strings.EqualFold(v.String(), v2)
-		}
-		return v.String() == v2
+		return v.equalStrings(v.String(), v2)
 	default:
-		v2Val := v.script.NewValue(v2)
-		if v.script.ignCase {
-			return strings.EqualFold(v.String(), v2Val.String())
-		}
-		return v.String() == v2Val.String()
+		return v.equalStrings(v.String(), v.script.NewValue(v2).String())
 	}
 }
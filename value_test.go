@@ -4,7 +4,9 @@ package awk
 
 import (
 	"math"
+	"net"
 	"testing"
+	"time"
 )
 
 // TestIntToInt converts various ints to Values then back to ints.
@@ -126,6 +128,58 @@ func TestStringToString(t *testing.T) {
 	}
 }
 
+// TestStrtonum tests converting strings with base prefixes to numbers.
+func TestStrtonum(t *testing.T) {
+	scr := NewScript()
+	tests := []struct {
+		in   interface{} // Input value
+		want float64     // Expected output
+	}{
+		{"0x1A", 26},
+		{"  -0XfF", -255},
+		{"0755", 493},
+		{"0789", 789},
+		{"017.5", 17.5},
+		{"12abc", 12},
+		{"1e3", 1000},
+		{"zzz", 0},
+		{42, 42},
+		{2.5, 2.5},
+	}
+	for _, tc := range tests {
+		if got := scr.NewValue(tc.in).Strtonum(); got != tc.want {
+			t.Fatalf("Expected %v for %v but received %v", tc.want, tc.in, got)
+		}
+	}
+}
+
+// TestIntBase tests converting strings to integers in a given base.
+func TestIntBase(t *testing.T) {
+	scr := NewScript()
+	tests := []struct {
+		in   string // Input string
+		base int    // Base
+		want int    // Expected output
+	}{
+		{"ff", 16, 255},
+		{"0xff", 16, 255},
+		{"0x10", 0, 16},
+		{"010", 0, 8},
+		{"0b101", 0, 5},
+		{"10", 0, 10},
+		{" -777 ", 8, -511},
+		{"z1", 36, 1261},
+		{"1012", 2, 5},
+		{"xyz", 10, 0},
+		{"10", 1, 0},
+	}
+	for _, tc := range tests {
+		if got := scr.NewValue(tc.in).IntBase(tc.base); got != tc.want {
+			t.Fatalf("Expected %d for %q in base %d but received %d", tc.want, tc.in, tc.base, got)
+		}
+	}
+}
+
 // TestMatch tests if regular-expression matching works.
 func TestMatch(t *testing.T) {
 	// We run the test twice to confirm that regexp caching works.
@@ -157,6 +211,130 @@ func TestMatch(t *testing.T) {
 	}
 }
 
+// TestMatchGroups tests extracting subexpression matches into an array.
+func TestMatchGroups(t *testing.T) {
+	scr := NewScript()
+	v := scr.NewValue("GET /index.html 200 1234")
+	va, ok := v.MatchGroups(`(GET|POST) (\S+) (?P<status>\d+)( -)?`)
+	if !ok {
+		t.Fatalf("Failed to match %q", v)
+	}
+	tests := []struct {
+		key  []interface{} // Array index
+		want string        // Expected value
+	}{
+		{[]interface{}{0}, "GET /index.html 200"},
+		{[]interface{}{1}, "GET"},
+		{[]interface{}{2}, "/index.html"},
+		{[]interface{}{2, "start"}, "5"},
+		{[]interface{}{2, "length"}, "11"},
+		{[]interface{}{3}, "200"},
+		{[]interface{}{"status"}, "200"},
+	}
+	for _, tc := range tests {
+		if got := va.Get(tc.key...).String(); got != tc.want {
+			t.Fatalf("Expected %q for %v but received %q", tc.want, tc.key, got)
+		}
+	}
+	if _, found := va.data[va.key([]interface{}{4})]; found {
+		t.Fatal("Non-participating subexpression was stored")
+	}
+	if scr.RStart != 1 || scr.RLength != 19 {
+		t.Fatalf("Expected {1, 19} but received {%d, %d}", scr.RStart, scr.RLength)
+	}
+
+	// A failed match should return an empty array.
+	va, ok = v.MatchGroups(`(\d+)x`)
+	if ok || len(va.Keys()) != 0 || scr.RLength != -1 {
+		t.Fatalf("Incorrectly matched %q", v)
+	}
+}
+
+// TestSubGsub tests regular-expression substitution.
+func TestSubGsub(t *testing.T) {
+	scr := NewScript()
+	v := scr.NewValue("Mississippi")
+	tests := []struct {
+		global bool   // true=Gsub; false=Sub
+		expr   string // Regular expression
+		repl   string // Replacement text
+		want   string // Expected result
+		n      int    // Expected number of replacements
+	}{
+		{false, "ss", "SS", "MiSSissippi", 1},
+		{true, "ss", "SS", "MiSSiSSippi", 2},
+		{true, "[ip]+", "<&>", "M<i>ss<i>ss<ippi>", 3},
+		{true, "i", `\&`, "M&ss&ss&pp&", 4},
+		{false, "x", "y", "Mississippi", 0},
+		{true, "x*", "-", "-M-i-s-s-i-s-s-i-p-p-i-", 12},
+	}
+	for _, tc := range tests {
+		var got *Value
+		var n int
+		if tc.global {
+			got, n = v.Gsub(tc.expr, tc.repl)
+		} else {
+			got, n = v.Sub(tc.expr, tc.repl)
+		}
+		if got.String() != tc.want || n != tc.n {
+			t.Fatalf("Expected (%q, %d) but received (%q, %d)", tc.want, tc.n, got, n)
+		}
+	}
+	if v.String() != "Mississippi" {
+		t.Fatalf("Sub modified its receiver to %q", v)
+	}
+
+	// Ensure that IgnoreCase is honored.
+	scr.IgnoreCase(true)
+	if got, n := v.Gsub("S", "z"); got.String() != "Mizzizzippi" || n != 4 {
+		t.Fatalf("Expected (%q, %d) but received (%q, %d)", "Mizzizzippi", 4, got, n)
+	}
+}
+
+// TestIndexOf tests searching for substrings.
+func TestIndexOf(t *testing.T) {
+	scr := NewScript()
+	v := scr.NewValue("Größe und GRÖSSE")
+	tests := []struct {
+		substr interface{} // Substring to search for
+		ign    bool        // true=ignore case; false=honor case
+		want   int         // Expected position
+	}{
+		{"ö", false, 3},
+		{"und", false, 7},
+		{"GRÖ", false, 11},
+		{"grö", false, 0},
+		{"grö", true, 1},
+		{"SSE", true, 14},
+		{scr.NewValue("e u"), false, 5},
+		{"", false, 1},
+		{"", true, 1},
+		{"xyz", true, 0},
+	}
+	for _, tc := range tests {
+		scr.IgnoreCase(tc.ign)
+		if got := v.IndexOf(tc.substr); got != tc.want {
+			t.Fatalf("Expected %d for %q (ignore case = %v) but received %d", tc.want, tc.substr, tc.ign, got)
+		}
+	}
+	if got := scr.NewValue(12345).IndexOf(34); got != 3 {
+		t.Fatalf("Expected 3 but received %d", got)
+	}
+}
+
+// TestLen tests computing string lengths in characters.
+func TestLen(t *testing.T) {
+	scr := NewScript()
+	for str, want := range map[string]int{"": 0, "abc": 3, "日本語": 3, "naïve": 5} {
+		if got := scr.NewValue(str).Len(); got != want {
+			t.Fatalf("Expected %d for %q but received %d", want, str, got)
+		}
+	}
+	if got := scr.NewValue(3.5).Len(); got != 3 {
+		t.Fatalf("Expected 3 but received %d", got)
+	}
+}
+
 // TestStrEqual tests if string comparisons work.
 func TestStrEqual(t *testing.T) {
 	// Test case-sensitive comparisons.
@@ -185,3 +363,110 @@ func TestStrEqual(t *testing.T) {
 		t.Fatalf("Failed to match %q = %q", "good", "GooD")
 	}
 }
+
+// TestStandaloneValue tests Values that are not associated with a Script.
+func TestStandaloneValue(t *testing.T) {
+	// Test conversions and comparisons with default semantics.
+	v := NewValue(2.0 / 3.0)
+	if s := v.String(); s != "0.666667" {
+		t.Fatalf("Expected %q but received %q", "0.666667", s)
+	}
+	w := NewValue("Hello")
+	if w.StrEqual("hello") {
+		t.Fatalf("Incorrectly matched %q = %q", "Hello", "hello")
+	}
+	if !w.Match("^H") {
+		t.Fatalf("Failed to match %v against %q", w, "^H")
+	}
+
+	// Bind the Values to a script and ensure they honor its settings.
+	scr := NewScript()
+	scr.ConvFmt = "%.2f"
+	scr.IgnoreCase(true)
+	if s := NewValue(2.0 / 3.0).Bind(scr).String(); s != "0.67" {
+		t.Fatalf("Expected %q but received %q", "0.67", s)
+	}
+	if !w.Bind(scr).StrEqual("hello") {
+		t.Fatalf("Failed to match %q = %q", "Hello", "hello")
+	}
+	if !w.Match("ell") || scr.RStart != 2 {
+		t.Fatalf("Expected RStart = 2 but received %d", scr.RStart)
+	}
+}
+
+// TestIgnoreCaseSeparately tests controlling case sensitivity separately for
+// regular expressions and strings and overriding it per Value.
+func TestIgnoreCaseSeparately(t *testing.T) {
+	scr := NewScript()
+	v := scr.NewValue("Key")
+
+	// Ignore case only in regular expressions.
+	scr.IgnoreCaseRegex(true)
+	if !v.Match("^key$") {
+		t.Fatalf("Expected %q to match %q", "^key$", v)
+	}
+	if v.StrEqual("key") {
+		t.Fatalf("Expected %q not to equal %q", v, "key")
+	}
+
+	// Ignore case only in string comparisons.
+	scr.IgnoreCaseRegex(false)
+	scr.IgnoreCaseStrings(true)
+	if v.Match("^key$") {
+		t.Fatalf("Expected %q not to match %q", "^key$", v)
+	}
+	if !v.StrEqual("key") || v.IndexOf("EY") != 2 || v.Cmp("KEY") != 0 {
+		t.Fatalf("Expected case-insensitive string comparisons of %q", v)
+	}
+
+	// Override the script's settings for a single comparison.
+	if v.IgnoringCase(false).StrEqual("key") {
+		t.Fatalf("Expected IgnoringCase(false) to compare case-sensitively")
+	}
+	if !v.IgnoringCase(true).Match("^KEY$") {
+		t.Fatalf("Expected IgnoringCase(true) to match case-insensitively")
+	}
+	scr.IgnoreCase(true)
+	if v.IgnoringCase(false).Match("^key$") {
+		t.Fatalf("Expected IgnoringCase(false) to match case-sensitively")
+	}
+	if !v.Match("^key$") || !v.StrEqual("KEY") {
+		t.Fatalf("Expected IgnoreCase(true) to affect both regular expressions and strings")
+	}
+}
+
+// TestNewValueOtherTypes tests converting times, durations, byte slices, and
+// fmt.Stringers to Values.
+func TestNewValueOtherTypes(t *testing.T) {
+	scr := NewScript()
+	scr.SetTimeLocation(time.UTC)
+	when := time.Date(2001, 9, 9, 1, 46, 40, 0, time.UTC)
+	v := scr.NewValue(when)
+	if v.Int() != 1000000000 || v.String() != "2001-09-09T01:46:40Z" {
+		t.Fatalf("Expected 1000000000 and %q but received %d and %q",
+			"2001-09-09T01:46:40Z", v.Int(), v.String())
+	}
+	if !v.Time().Equal(when) {
+		t.Fatalf("Expected %v but received %v", when, v.Time())
+	}
+	scr.SetTimeLayouts("2006-01-02")
+	if s := scr.NewValue(when).String(); s != "2001-09-09" {
+		t.Fatalf("Expected %q but received %q", "2001-09-09", s)
+	}
+	if f := scr.NewValue(when.Add(time.Second / 2)).Float64(); f != 1000000000.5 {
+		t.Fatalf("Expected 1000000000.5 but received %v", f)
+	}
+
+	if s := scr.NewValue(90 * time.Second).String(); s != "90" {
+		t.Fatalf("Expected %q but received %q", "90", s)
+	}
+	if f := scr.NewValue(1500 * time.Millisecond).Float64(); f != 1.5 {
+		t.Fatalf("Expected 1.5 but received %v", f)
+	}
+	if s := scr.NewValue([]byte("bytes")).String(); s != "bytes" {
+		t.Fatalf("Expected %q but received %q", "bytes", s)
+	}
+	if s := scr.NewValue(net.IPv4(192, 168, 0, 1)).String(); s != "192.168.0.1" {
+		t.Fatalf("Expected %q but received %q", "192.168.0.1", s)
+	}
+}
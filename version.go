@@ -0,0 +1,112 @@
+// This file lets programs determine programmatically what the awk package
+// supports.
+
+package awk
+
+// Version is the version number of the awk package, following the
+// conventions of semantic versioning.
+const Version = "1.2.0"
+
+// features lists the names of optional capabilities the package provides.
+// Names are never removed once added, so programs can test for a feature's
+// presence without regard to the package version.
+var features = []string{
+	"aggregates",         // ValueArray.SumValues, MinValue, MaxValue, MeanValue, and CountIf
+	"arithmetic",         // Value.Add, Value.Subtract, etc.
+	"array-exists",       // ValueArray.Exists and ValueArray.GetOK
+	"array-map",          // ValueArray.ToStringMap, ToFloatMap, and FromMap
+	"array-order",        // ValueArray.SetOrder and Script.SetArrayOrder
+	"array-prefix",       // ValueArray.DeletePrefix and ValueArray.View
+	"array-sets",         // ValueArray.Merge, Union, Intersect, and Difference
+	"array-sort",         // ValueArray.SortedKeys and ValueArray.SortedValues
+	"array-stats",        // ValueArray.Stats and NewValueArraySized
+	"atomic-output",      // Script.AtomicOutput and NewSyncWriter
+	"auto-rs",            // Script.AutoDetectRS
+	"auto-validation",    // AutoE, MustAuto, RangeNR, and RangeRE
+	"backed-array",       // NewValueArrayBacked and ArrayBackend
+	"before-print",       // Script.BeforePrint
+	"big-numbers",        // Script.BigNumbers, Value.BigInt, and Value.BigFloat
+	"bitwise",            // Value.And, Value.Or, Value.Xor, etc.
+	"bracket-splitter",   // BracketSplitter
+	"carry",              // Script.Carry and Script.Carried
+	"case-options",       // Script.IgnoreCaseRegex, Script.IgnoreCaseStrings, and Value.IgnoringCase
+	"chaining",           // Script.When, Script.Always, and Script.Print
+	"checks",             // Script.Check and validation reports
+	"clone",              // Script.Clone
+	"collator",           // Script.SetCollator
+	"compare",            // Value.Cmp and Value.Less
+	"compat",             // Script.SetCompat
+	"dry-run",            // Script.MatchRecord
+	"duplicates",         // Script.TrackDuplicates
+	"duration",           // Value.Duration
+	"escape-fs",          // Script.EscapeFS
+	"field-offsets",      // Script.FOffset
+	"field-splitter",     // FieldSplitter and Script.SetFieldSplitter
+	"first-match",        // Script.FirstMatchOnly and Script.Matched
+	"fnr",                // FNR and Script.RunReaders
+	"formatter",          // Value implements fmt.Formatter
+	"fslice",             // Script.FSlice and Script.FJoin
+	"fuzzy",              // Fuzzy and Value.EditDistance
+	"getline-options",    // GetLineOptions and Script.SetGetLineOptions
+	"group-change",       // Script.OnGroupChange
+	"guard",              // Unless and Script.Guard
+	"hashes",             // Value.MD5, Value.SHA256, etc.
+	"inject",             // Script.Inject
+	"join-continuations", // Script.JoinContinuations
+	"json",               // Value.MarshalJSON and Value.UnmarshalJSON
+	"kind",               // Value.Kind, Value.IsNumeric, and Value.IsInt
+	"last-record",        // Script.IsLastRecord
+	"lines",              // Script.Lines and Script.LinesErr
+	"literal-separators", // Script.SetFSLiteral and Script.SetRSLiteral
+	"match-groups",       // Value.MatchGroups
+	"natural-sort",       // NaturalCompare, Value.NaturalKey, and OrderNatural
+	"new-value-types",    // NewValue of time.Time, time.Duration, []byte, and fmt.Stringer
+	"numeric-locale",     // Script.SetNumericLocale
+	"ordered-output",     // OrderedOutput and Script.SetOrderedOutput
+	"output-safety",      // Script.MaxOutputRecord and Script.EscapeControl
+	"printf",             // Script.Sprintf and Script.Printf
+	"profile",            // Script.Profile
+	"program",            // Script.Compile and Program
+	"provenance",         // RunPipelineProvenance and Script.Provenance
+	"pseudonymize",       // Script.Pseudonymize
+	"quote-aware",        // Script.SetQuoteAware and QuoteSplitter
+	"rand",               // Script.Rand and Script.Srand
+	"ratio",              // Script.Ratio, SetZeroDivPolicy, and Value.Percent
+	"raw-bytes",          // Script.RawBytes
+	"record-reader",      // RecordReader and Script.SetRecordReader
+	"reformat-numeric",   // Script.ReformatNumeric
+	"regexp-cache",       // Script.ExportRegexps and Script.ImportRegexps
+	"regexp-limits",      // Script.SetRegexpLimit and related methods
+	"report",             // Script.NewReport
+	"require-nf",         // Script.RequireNF
+	"rewrite",            // Script.Rewrite, Script.RewriteWhen, and Script.RewriteMap
+	"rule-stats",         // Script.RuleStats
+	"run-on",             // Script.RunOn
+	"run-result",         // Script.RunResult
+	"save-load",          // ValueArray.Save and ValueArray.Load
+	"shell-splitter",     // ShellSplitter
+	"split",              // Script.Split and Script.SplitFS
+	"split-key",          // ValueArray.SplitKey
+	"sql",                // Value.Scan and Value.Value
+	"standalone-values",  // NewValue and NewValueArray
+	"strnum",             // Value.IsStrNum and numeric-string comparisons
+	"strtonum",           // Value.Strtonum and Value.IntBase
+	"sub-gsub",           // Value.Sub, Value.Gsub, etc.
+	"sync-array",         // SyncValueArray
+	"text-marshaling",    // Value.MarshalText and Value.UnmarshalText
+	"time",               // Script.Systime, Script.Mktime, Script.Strftime, and Value.Time
+	"units",              // Value.Bytes and Value.DurationUnits
+	"url",                // Value.URL, Value.URLPart, and Script.URLField
+}
+
+// Features returns the set of names of optional capabilities the package
+// provides (e.g., "field-splitter" or "printf").  Programs that generate
+// scripts can consult this set to adapt to the version of the package they
+// are compiled against.
+func Features() map[string]bool {
+	fs := make(map[string]bool, len(features))
+	for _, f := range features {
+		fs[f] = true
+	}
+	return fs
+}
@@ -0,0 +1,27 @@
+// This file tests feature detection.
+
+package awk
+
+import (
+	"sort"
+	"testing"
+)
+
+// TestFeatures tests that the feature list is sorted, free of duplicates, and
+// reported correctly.
+func TestFeatures(t *testing.T) {
+	if !sort.StringsAreSorted(features) {
+		t.Fatal("The feature list is not sorted")
+	}
+	fs := Features()
+	if len(fs) != len(features) {
+		t.Fatalf("Expected %d features but received %d", len(features), len(fs))
+	}
+	if !fs["field-splitter"] || fs["no-such-feature"] {
+		t.Fatalf("Incorrect feature set %v", fs)
+	}
+	fs["field-splitter"] = false
+	if !Features()["field-splitter"] {
+		t.Fatal("Modifying the feature set affected subsequent calls")
+	}
+}